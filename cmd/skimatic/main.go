@@ -6,17 +6,99 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/nhalm/skimatic/internal/generator"
 )
 
+// options holds the command-line flags
+type options struct {
+	config   string
+	dsn      string
+	tables   bool
+	include  string
+	queries  string
+	output   string
+	verbose  bool
+	printSQL bool
+	dryRun   bool
+	help     bool
+	version  bool
+}
+
+// registerFlags defines the command-line flags on fs
+func registerFlags(fs *flag.FlagSet) *options {
+	opts := &options{}
+	fs.StringVar(&opts.config, "config", "skimatik.yaml", "Path to YAML configuration file")
+	fs.StringVar(&opts.dsn, "dsn", "", "PostgreSQL connection string (overrides database.dsn)")
+	fs.BoolVar(&opts.tables, "tables", false, "Generate repositories for database tables")
+	fs.StringVar(&opts.include, "include", "", "Comma-separated table names or patterns to generate (default: all tables)")
+	fs.StringVar(&opts.queries, "queries", "", "Directory of annotated .sql files to generate query code from")
+	fs.StringVar(&opts.output, "output", "", "Output directory for generated code (overrides output.directory)")
+	fs.BoolVar(&opts.verbose, "verbose", false, "Enable verbose logging output")
+	fs.BoolVar(&opts.printSQL, "print-sql", false, "Print the SQL each generated method runs instead of writing Go files")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "Generate in memory and list the files that would be written, without writing them")
+	fs.BoolVar(&opts.help, "help", false, "Show detailed help and examples")
+	fs.BoolVar(&opts.version, "version", false, "Show version information")
+	return opts
+}
+
+// buildConfig loads the config file and applies the flags given on the command line over it.
+// When the default config file doesn't exist, the flags configure generation on their own
+func buildConfig(fs *flag.FlagSet, opts *options) (*generator.Config, error) {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	var cfg *generator.Config
+	if _, err := os.Stat(opts.config); os.IsNotExist(err) && !set["config"] {
+		cfg = generator.DefaultConfig()
+	} else {
+		cfg, err = generator.LoadConfig(opts.config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if set["dsn"] {
+		cfg.DSN = opts.dsn
+	}
+	if set["tables"] {
+		cfg.Tables = opts.tables
+	}
+	if set["include"] {
+		cfg.Include = nil
+		for _, pattern := range strings.Split(opts.include, ",") {
+			if pattern = strings.TrimSpace(pattern); pattern != "" {
+				cfg.Include = append(cfg.Include, pattern)
+			}
+		}
+	}
+	if set["queries"] {
+		cfg.QueriesDir = opts.queries
+	}
+	if set["output"] {
+		cfg.OutputDir = opts.output
+	}
+	if opts.verbose {
+		cfg.Verbose = true
+	}
+	if opts.printSQL {
+		cfg.PrintSQL = true
+	}
+	if opts.dryRun {
+		cfg.DryRun = true
+	}
+
+	// --tables without a table list generates every table
+	if cfg.Tables && len(cfg.Include) == 0 {
+		cfg.Include = []string{"*"}
+	}
+
+	return cfg, nil
+}
+
 func main() {
-	var (
-		config  = flag.String("config", "skimatik.yaml", "Path to YAML configuration file")
-		verbose = flag.Bool("verbose", false, "Enable verbose logging output")
-		help    = flag.Bool("help", false, "Show detailed help and examples")
-		version = flag.Bool("version", false, "Show version information")
-	)
+	opts := registerFlags(flag.CommandLine)
 
 	// Custom usage function with better formatting
 	flag.Usage = func() {
@@ -71,6 +153,12 @@ EXAMPLES:
     # Verbose output for debugging
     skimatik --dsn="postgres://..." --tables --verbose
 
+    # Review the SQL generated methods would run without writing any files
+    skimatik --print-sql
+
+    # Check that generation succeeds and list the files it would write, e.g. in CI
+    skimatik --dry-run
+
 ENVIRONMENT VARIABLES:
     DATABASE_URL       PostgreSQL connection string (alternative to --dsn)
     POSTGRES_HOST      Database host (default: localhost)
@@ -116,7 +204,7 @@ GENERATED FILES:
 
 PAGINATION:
     All generated repositories include efficient cursor-based pagination:
-    - ListPaginated(ctx, PaginationParams) (*PaginationResult[T], error)
+    - ListPaginated(ctx, PaginationParams) (*Page[T], error)
     - Uses UUID v7 time-ordering for consistent results
     - O(log n) performance regardless of dataset size
 
@@ -131,29 +219,24 @@ MORE INFO:
 	flag.Parse()
 
 	// Handle help and version flags
-	if *help {
+	if opts.help {
 		flag.Usage()
 		os.Exit(0)
 	}
 
-	if *version {
+	if opts.version {
 		fmt.Println("skimatik version 2.0.0")
 		fmt.Println("Database-first code generator for PostgreSQL")
 		fmt.Println("https://github.com/nhalm/skimatik")
 		os.Exit(0)
 	}
 
-	// Load configuration file
-	cfg, err := generator.LoadConfig(*config)
+	// Load the configuration file, with command-line flags taking precedence
+	cfg, err := buildConfig(flag.CommandLine, opts)
 	if err != nil {
 		log.Fatalf("Failed to load config file: %v", err)
 	}
 
-	// Override verbose setting from CLI flag if provided
-	if *verbose {
-		cfg.Verbose = true
-	}
-
 	// Create and run generator
 	gen := generator.New(cfg)
 	ctx := context.Background()
@@ -162,5 +245,10 @@ MORE INFO:
 		log.Fatalf("Generation failed: %v", err)
 	}
 
-	fmt.Printf("Successfully generated code in %s\n", cfg.OutputDir)
+	switch {
+	case cfg.DryRun:
+		fmt.Println("Dry run complete, no files were written")
+	case !cfg.PrintSQL:
+		fmt.Printf("Successfully generated code in %s\n", cfg.GetOutputDir())
+	}
 }
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// parseFlags parses args into a fresh flag set and builds the resulting configuration
+func parseFlags(t *testing.T, args ...string) (*options, *flag.FlagSet) {
+	t.Helper()
+	fs := flag.NewFlagSet("skimatik", flag.ContinueOnError)
+	opts := registerFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Parse(%v) failed: %v", args, err)
+	}
+	return opts, fs
+}
+
+func TestBuildConfig_FlagsOnly(t *testing.T) {
+	// No skimatik.yaml in the working directory
+	t.Chdir(t.TempDir())
+
+	opts, fs := parseFlags(t,
+		"--dsn=postgres://localhost/app",
+		"--tables",
+		"--include=users, posts",
+		"--queries=./sql",
+		"--output=./generated",
+		"--dry-run",
+	)
+	cfg, err := buildConfig(fs, opts)
+	if err != nil {
+		t.Fatalf("buildConfig() failed: %v", err)
+	}
+
+	if cfg.DSN != "postgres://localhost/app" {
+		t.Errorf("DSN = %q, want the --dsn value", cfg.DSN)
+	}
+	if !cfg.Tables {
+		t.Error("Tables = false, want true from --tables")
+	}
+	if !slices.Equal(cfg.Include, []string{"users", "posts"}) {
+		t.Errorf("Include = %v, want [users posts]", cfg.Include)
+	}
+	if cfg.QueriesDir != "./sql" || cfg.OutputDir != "./generated" {
+		t.Errorf("QueriesDir, OutputDir = %q, %q, want the --queries and --output values", cfg.QueriesDir, cfg.OutputDir)
+	}
+	if !cfg.DryRun {
+		t.Error("DryRun = false, want true from --dry-run")
+	}
+	// Settings without a flag keep their defaults
+	if cfg.PackageName != "repositories" || cfg.Schema != "public" {
+		t.Errorf("PackageName, Schema = %q, %q, want the defaults", cfg.PackageName, cfg.Schema)
+	}
+
+	// Without --include every table is generated
+	opts, fs = parseFlags(t, "--tables")
+	cfg, err = buildConfig(fs, opts)
+	if err != nil {
+		t.Fatalf("buildConfig() failed: %v", err)
+	}
+	if !slices.Equal(cfg.Include, []string{"*"}) {
+		t.Errorf("Include = %v, want [*]", cfg.Include)
+	}
+}
+
+func TestBuildConfig_FlagsOverrideFile(t *testing.T) {
+	yamlContent := `database:
+  dsn: "postgres://file/app"
+output:
+  directory: "./from-file"
+  package: "models"
+tables:
+  users:
+`
+	configPath := filepath.Join(t.TempDir(), "skimatik.yaml")
+	if err := os.WriteFile(configPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	opts, fs := parseFlags(t, "--config="+configPath, "--dsn=postgres://flag/app", "--output=./from-flag")
+	cfg, err := buildConfig(fs, opts)
+	if err != nil {
+		t.Fatalf("buildConfig() failed: %v", err)
+	}
+	if cfg.DSN != "postgres://flag/app" || cfg.OutputDir != "./from-flag" {
+		t.Errorf("DSN, OutputDir = %q, %q, want the flag values", cfg.DSN, cfg.OutputDir)
+	}
+	// Values not given on the command line come from the file
+	if cfg.PackageName != "models" || !slices.Equal(cfg.Include, []string{"users"}) {
+		t.Errorf("PackageName, Include = %q, %v, want the file values", cfg.PackageName, cfg.Include)
+	}
+
+	// An explicitly given config file must exist
+	opts, fs = parseFlags(t, "--config="+filepath.Join(t.TempDir(), "missing.yaml"))
+	if _, err := buildConfig(fs, opts); err == nil {
+		t.Error("Expected an error for a missing --config file")
+	}
+}
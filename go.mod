@@ -5,6 +5,7 @@ go 1.24.4
 require (
 	github.com/jackc/pgx/v5 v5.7.5
 	github.com/nhalm/pgxkit v1.1.0
+	golang.org/x/tools v0.35.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -19,5 +20,4 @@ require (
 	golang.org/x/mod v0.26.0 // indirect
 	golang.org/x/sync v0.16.0 // indirect
 	golang.org/x/text v0.24.0 // indirect
-	golang.org/x/tools v0.35.0 // indirect
 )
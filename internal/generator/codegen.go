@@ -2,7 +2,15 @@ package generator
 
 import (
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
 	"os"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"text/template"
 
@@ -14,22 +22,43 @@ type CodeGenerator struct {
 	config      *Config
 	typeMapper  *TypeMapper
 	templateMgr *TemplateManager
+
+	// Collected file bodies and imports when generating into a single file
+	singleFileSections []string
+	singleFileImports  map[string]string
+
+	// writeFile stores a formatted file; a dry run replaces it to only report the file
+	writeFile func(filename string, data []byte) error
+
+	// relatedTables are the tables generated in this run, which relationship loaders may reference
+	relatedTables []Table
 }
 
 // NewCodeGenerator creates a new code generator
 func NewCodeGenerator(config *Config) *CodeGenerator {
-	return &CodeGenerator{
-		config:      config,
-		typeMapper:  NewTypeMapper(config.TypeMappings),
-		templateMgr: NewTemplateManager(templateFS),
-	}
+	cg := &CodeGenerator{
+		config:     config,
+		typeMapper: newConfiguredTypeMapper(config),
+		writeFile: func(filename string, data []byte) error {
+			return os.WriteFile(filename, data, 0644)
+		},
+	}
+	cg.templateMgr = NewTemplateManager(templateFS, template.FuncMap{
+		"plural": cg.pluralize,
+		"quote":  quoteIdentifier,
+	})
+	return cg
+}
+
+// pluralize returns the plural of a Go identifier, honoring configured plurals
+func (cg *CodeGenerator) pluralize(name string) string {
+	return pluralize(name, cg.config.Plurals)
 }
 
 // GenerateTableRepository generates a complete repository file for a table
 func (cg *CodeGenerator) GenerateTableRepository(table Table) error {
-	// Map column types
-	if err := cg.typeMapper.MapTableColumns(&table); err != nil {
-		return fmt.Errorf("failed to map column types: %w", err)
+	if err := cg.mapTableTypes(&table); err != nil {
+		return err
 	}
 
 	// Generate the code
@@ -44,24 +73,435 @@ func (cg *CodeGenerator) GenerateTableRepository(table Table) error {
 		return fmt.Errorf("failed to write code to file: %w", err)
 	}
 
+	if cg.config.GetDomainMapping(table.Name) != "" {
+		if err := cg.scaffoldDomainMapping(table); err != nil {
+			return err
+		}
+	}
+
+	if cg.config.GenerateRoundtripTests {
+		testCode, err := cg.generateRoundtripTestCode(table)
+		if err != nil {
+			return fmt.Errorf("failed to generate round-trip test: %w", err)
+		}
+
+		testFilename := cg.config.GetOutputPath(table.GoTestFileName())
+		if err := cg.writeCodeToFile(testFilename, testCode); err != nil {
+			return fmt.Errorf("failed to write round-trip test to file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// domainImport returns the import path of a domain_mapping type, which must be a qualified
+// pkg.Type whose package is listed in types.custom_imports
+func (cg *CodeGenerator) domainImport(tableName, domainType string) (string, error) {
+	pkg, name, ok := strings.Cut(domainType, ".")
+	if !ok || !token.IsIdentifier(pkg) || !token.IsIdentifier(name) {
+		return "", fmt.Errorf("domain_mapping %q for table %s must be a qualified type such as domain.User", domainType, tableName)
+	}
+	importPath, ok := cg.config.CustomImports[pkg]
+	if !ok {
+		return "", fmt.Errorf("domain_mapping %q for table %s needs package %s in types.custom_imports", domainType, tableName, pkg)
+	}
+	return importPath, nil
+}
+
+// generateDomainMethods generates GetDomain and ListDomain for a table with a domain_mapping,
+// alongside the Get and List methods they wrap
+func (cg *CodeGenerator) generateDomainMethods(table Table) (string, error) {
+	domainType := cg.config.GetDomainMapping(table.Name)
+	if domainType == "" {
+		return "", nil
+	}
+
+	data, err := cg.prepareCRUDTemplateData(table)
+	if err != nil {
+		return "", fmt.Errorf("failed to prepare template data: %w", err)
+	}
+	data["DomainType"] = domainType
+
+	return cg.templateMgr.ExecuteTemplate(TemplateDomainMethods, data)
+}
+
+// relatedTable returns the named table generated in this run, with its columns mapped on a copy
+// exactly as its own generation maps them
+func (cg *CodeGenerator) relatedTable(name string) (Table, bool, error) {
+	index := slices.IndexFunc(cg.relatedTables, func(t Table) bool { return t.Name == name })
+	if index < 0 {
+		return Table{}, false, nil
+	}
+	table := cg.relatedTables[index]
+	table.Columns = slices.Clone(table.Columns)
+	if err := cg.mapTableTypes(&table); err != nil {
+		return Table{}, false, err
+	}
+	return table, true, nil
+}
+
+// relationship is a foreign key generated as a key accessor on the struct and a loader on the repository
+type relationship struct {
+	Name      string // Relation name in Go, e.g. "Author" for author_id
+	Column    string
+	Field     string
+	FieldType string
+	RefTable  string
+	RefStruct string
+	KeyArg    string // Expression passing the key to the referenced repository's Get
+	NullCheck string // Condition under which the key is NULL; empty for a NOT NULL column
+}
+
+// tableRelationships returns the table's single-column foreign keys to the primary key of another
+// table generated in this run with a Get method, named after the column without its _id suffix.
+// Keys whose Go type cannot be passed to the referenced Get, and relations whose loader would
+// collide with a method in declared, are left out
+func (cg *CodeGenerator) tableRelationships(table Table, rowParam string, declared map[string]bool) ([]relationship, error) {
+	var relationships []relationship
+	names := make(map[string]bool)
+	for _, fk := range table.ForeignKeys {
+		if len(fk.Columns) != 1 {
+			continue
+		}
+		ref, ok, err := cg.relatedTable(fk.RefTable)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		refKey := ref.GetPrimaryKeyColumn()
+		if refKey == nil || refKey.Name != fk.RefColumns[0] || !slices.Contains(cg.tableFunctions(ref), "get") {
+			continue
+		}
+
+		col := table.GetColumn(fk.Columns[0])
+		name := toPascalCase(strings.TrimSuffix(col.Name, "_id"))
+		if name == "" || names[name] || declared["Get"+name] {
+			continue
+		}
+
+		field := rowParam + "." + col.GoFieldName()
+		rel := relationship{
+			Name:      name,
+			Column:    col.Name,
+			Field:     col.GoFieldName(),
+			FieldType: col.GoType,
+			RefTable:  ref.Name,
+			RefStruct: ref.GoStructName(),
+		}
+		switch {
+		case col.GoType == refKey.GoType:
+			rel.KeyArg = field
+		case col.GoType == "*"+refKey.GoType:
+			rel.KeyArg, rel.NullCheck = "*"+field, field+" == nil"
+		case col.GoType == "pgtype.UUID" && refKey.GoType == "uuid.UUID":
+			rel.KeyArg, rel.NullCheck = "uuid.UUID("+field+".Bytes)", "!"+field+".Valid"
+		default:
+			continue
+		}
+		names[name] = true
+		relationships = append(relationships, rel)
+	}
+	return relationships, nil
+}
+
+// generateRelationships generates the accessors and loaders of the table's relationships, given
+// the code generated for the table so far to keep loaders from shadowing its repository methods
+func (cg *CodeGenerator) generateRelationships(table Table, code string) (string, error) {
+	if !cg.config.GenerateRelationships {
+		return "", nil
+	}
+
+	file, err := parser.ParseFile(token.NewFileSet(), "", code, parser.SkipObjectResolution)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse generated code: %w", err)
+	}
+	declared := make(map[string]bool)
+	for _, fn := range repositoryMethods(file, table.GoStructName()+"Repository") {
+		declared[fn.Name.Name] = true
+	}
+
+	data, err := cg.prepareCRUDTemplateData(table)
+	if err != nil {
+		return "", fmt.Errorf("failed to prepare template data: %w", err)
+	}
+
+	// The row parameter is named like the struct's receiver unless that shadows r or q
+	rowParam := data["ReceiverName"].(string)
+	if rowParam == "r" || rowParam == "q" {
+		rowParam = "row"
+	}
+	relationships, err := cg.tableRelationships(table, rowParam, declared)
+	if err != nil || len(relationships) == 0 {
+		return "", err
+	}
+	data["RowParam"] = rowParam
+	data["Relationships"] = relationships
+	data["QuerierPerCall"] = cg.config.QuerierPerCall
+
+	return cg.templateMgr.ExecuteTemplate(TemplateRelationships, data)
+}
+
+// generateEagerLoads generates a ListWith<Child> method per eager_load child table, listing the
+// table's rows with the child rows whose single foreign key column references the primary key
+func (cg *CodeGenerator) generateEagerLoads(table Table) (string, error) {
+	children := cg.config.TableConfigs[table.Name].EagerLoad
+	if len(children) == 0 {
+		return "", nil
+	}
+	idColumn := table.GetPrimaryKeyColumn()
+	if idColumn == nil {
+		return "", fmt.Errorf("eager_load requires a single-column primary key on table %s", table.Name)
+	}
+
+	var parentColumns, parentScanArgs []string
+	for _, col := range table.Columns {
+		parentColumns = append(parentColumns, "parent."+quoteIdentifier(col.Name))
+		parentScanArgs = append(parentScanArgs, "&parent."+col.GoFieldName())
+	}
+
+	var sections []string
+	for _, childName := range children {
+		if childName == table.Name {
+			return "", fmt.Errorf("eager_load table %s cannot load itself", table.Name)
+		}
+		child, ok, err := cg.relatedTable(childName)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "", fmt.Errorf("eager_load table %s for table %s is not generated", childName, table.Name)
+		}
+
+		var joinColumns []string
+		for _, fk := range child.ForeignKeys {
+			if fk.RefTable == table.Name && slices.Equal(fk.RefColumns, []string{idColumn.Name}) {
+				joinColumns = append(joinColumns, fk.Columns[0])
+			}
+		}
+		if len(joinColumns) != 1 {
+			return "", fmt.Errorf("eager_load table %s needs exactly one foreign key to %s.%s, found %d", childName, table.Name, idColumn.Name, len(joinColumns))
+		}
+
+		// A parent without children leaves every child column NULL, which the Go types of NOT NULL
+		// columns cannot hold, so those are scanned through pointers
+		type pointerScan struct {
+			Var   string
+			Type  string
+			Field string
+		}
+		var childColumns, childScanArgs []string
+		var pointerScans []pointerScan
+		for _, col := range child.Columns {
+			childColumns = append(childColumns, "child."+quoteIdentifier(col.Name))
+			if col.IsNullable || isSliceGoType(col.GoType) {
+				childScanArgs = append(childScanArgs, "&child."+col.GoFieldName())
+				continue
+			}
+			scan := pointerScan{Var: "child" + col.GoFieldName(), Type: col.GoType, Field: col.GoFieldName()}
+			pointerScans = append(pointerScans, scan)
+			childScanArgs = append(childScanArgs, "&"+scan.Var)
+		}
+
+		orderBy := []string{"parent." + quoteIdentifier(idColumn.Name)}
+		for _, key := range child.PrimaryKey {
+			orderBy = append(orderBy, "child."+quoteIdentifier(key))
+		}
+
+		data, err := cg.prepareCRUDTemplateData(table)
+		if err != nil {
+			return "", fmt.Errorf("failed to prepare template data: %w", err)
+		}
+		data["IDType"] = idColumn.GoType
+		data["IDField"] = idColumn.GoFieldName()
+		data["ParentSelectColumns"] = strings.Join(parentColumns, ", ")
+		data["ParentScanArgs"] = strings.Join(parentScanArgs, ", ")
+		data["ChildTable"] = child.Name
+		data["ChildStruct"] = child.GoStructName()
+		data["ChildSoftDeleteColumn"] = cg.config.GetSoftDeleteColumn(child.Name)
+		data["JoinColumn"] = joinColumns[0]
+		data["ChildSelectColumns"] = strings.Join(childColumns, ", ")
+		data["ChildScanArgs"] = strings.Join(childScanArgs, ", ")
+		data["ChildPointerScans"] = pointerScans
+		data["JoinOrderBy"] = strings.Join(orderBy, ", ")
+
+		section, err := cg.templateMgr.ExecuteTemplate(TemplateListWithChildren, data)
+		if err != nil {
+			return "", err
+		}
+		sections = append(sections, section)
+	}
+	return strings.Join(sections, "\n\n"), nil
+}
+
+// scaffoldDomainMapping writes the table's <Struct>ToDomain conversion with a commented-out
+// assignment per field. The file belongs to the user once written, so an existing one is kept
+func (cg *CodeGenerator) scaffoldDomainMapping(table Table) error {
+	filename := cg.config.GetOutputPath(table.GoDomainFileName())
+	if _, err := os.Stat(filename); err == nil {
+		return nil
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to check domain mapping file %s: %w", filename, err)
+	}
+
+	domainType := cg.config.GetDomainMapping(table.Name)
+	importPath, err := cg.domainImport(table.Name, domainType)
+	if err != nil {
+		return err
+	}
+
+	type domainField struct {
+		Name string
+		Type string
+	}
+	data := struct {
+		StructName string
+		DomainType string
+		Fields     []domainField
+	}{
+		StructName: table.GoStructName(),
+		DomainType: domainType,
+	}
+	for _, col := range table.Columns {
+		data.Fields = append(data.Fields, domainField{Name: col.GoFieldName(), Type: col.GoType})
+	}
+
+	var code strings.Builder
+	code.WriteString(fmt.Sprintf("// Scaffolded by skimatik for the %s domain_mapping. Unlike the generated files,\n", table.Name))
+	code.WriteString("// this one is yours to edit: it is written once and never overwritten.\n\n")
+	code.WriteString(fmt.Sprintf("package %s\n\n", cg.config.GetPackageName()))
+	code.WriteString(fmt.Sprintf("import %s\n\n", cg.importSpec(importPath)))
+
+	result, err := cg.templateMgr.ExecuteTemplate(TemplateDomainMapping, data)
+	if err != nil {
+		return fmt.Errorf("failed to execute domain mapping template: %w", err)
+	}
+	code.WriteString(result)
+
+	// Written on its own even with single_file, as it is not regenerated with the rest
+	return cg.formatAndWriteFile(filename, code.String())
+}
+
+// mapTableTypes sets the Go type of each column, applying the table's column_types and int_enums
+func (cg *CodeGenerator) mapTableTypes(table *Table) error {
+	if err := cg.typeMapper.MapTableColumns(table); err != nil {
+		return fmt.Errorf("failed to map column types: %w", err)
+	}
+	if err := cg.applyColumnTypes(table); err != nil {
+		return err
+	}
+	return cg.applyIntEnums(table)
+}
+
+// applyColumnTypes replaces the mapped Go types of columns overridden by the table's column_types
+func (cg *CodeGenerator) applyColumnTypes(table *Table) error {
+	for name, goType := range cg.config.GetColumnTypes(table.Name) {
+		col := table.GetColumn(name)
+		if col == nil {
+			return fmt.Errorf("column_types references unknown column %s in table %s", name, table.Name)
+		}
+		col.GoType = cg.typeMapper.applyNullableAndArray(goType, col.IsNullable, col.IsArray)
+	}
+	return nil
+}
+
+// applyIntEnums maps the table's int_enums columns to their named Go types
+func (cg *CodeGenerator) applyIntEnums(table *Table) error {
+	for name, enum := range cg.config.GetIntEnums(table.Name) {
+		col := table.GetColumn(name)
+		if col == nil {
+			return fmt.Errorf("int_enums references unknown column %s in table %s", name, table.Name)
+		}
+		if _, overridden := cg.config.GetColumnTypes(table.Name)[name]; overridden {
+			return fmt.Errorf("column %s in table %s is configured in both column_types and int_enums", name, table.Name)
+		}
+		if pgType := strings.ToLower(col.Type); (pgType != "smallint" && pgType != "int2") || col.IsArray {
+			return fmt.Errorf("int_enums column %s in table %s must be a smallint, got %s", name, table.Name, col.Type)
+		}
+		if !token.IsIdentifier(enum.Type) {
+			return fmt.Errorf("int_enums column %s in table %s needs a valid Go type name, got %q", name, table.Name, enum.Type)
+		}
+		col.GoType = cg.typeMapper.applyNullableAndArray(enum.Type, col.IsNullable, false)
+	}
 	return nil
 }
 
+// generateIntEnums generates the named int types and constants of the table's int_enums columns
+func (cg *CodeGenerator) generateIntEnums(table Table) (string, error) {
+	type constant struct {
+		Name  string
+		Value int16
+	}
+	type intEnum struct {
+		Type      string
+		Column    string
+		Constants []constant
+	}
+
+	var enums []intEnum
+	for column, config := range cg.config.GetIntEnums(table.Name) {
+		enum := intEnum{Type: config.Type, Column: column}
+		for name, value := range config.Values {
+			enum.Constants = append(enum.Constants, constant{Name: config.Type + toPascalCase(name), Value: value})
+		}
+		sort.Slice(enum.Constants, func(i, j int) bool {
+			if enum.Constants[i].Value != enum.Constants[j].Value {
+				return enum.Constants[i].Value < enum.Constants[j].Value
+			}
+			return enum.Constants[i].Name < enum.Constants[j].Name
+		})
+		enums = append(enums, enum)
+	}
+	if len(enums) == 0 {
+		return "", nil
+	}
+	sort.Slice(enums, func(i, j int) bool { return enums[i].Type < enums[j].Type })
+
+	return cg.templateMgr.ExecuteTemplate(TemplateIntEnum, struct {
+		TableName string
+		Enums     []intEnum
+	}{table.Name, enums})
+}
+
+// importSpec formats an import for a generated import block, naming custom imports whose
+// path doesn't end in the package name the mapped types use
+func (cg *CodeGenerator) importSpec(importPath string) string {
+	for name, customPath := range cg.config.CustomImports {
+		if customPath == importPath && path.Base(importPath) != name {
+			return fmt.Sprintf("%s %q", name, importPath)
+		}
+	}
+	return strconv.Quote(importPath)
+}
+
 // generateTableCode generates the complete Go code for a table
 func (cg *CodeGenerator) generateTableCode(table Table) (string, error) {
 	// Get required imports from column types
 	typeImports := cg.typeMapper.GetRequiredImports(table.Columns)
 
 	// Add minimal imports required for table-specific operations
+	_, dbImport := cg.config.GetDBType()
 	coreImports := []string{
 		"context",
 		"fmt",
-		"github.com/nhalm/pgxkit",
+		dbImport,
 		"github.com/google/uuid",
+		"github.com/jackc/pgx/v5",
+	}
+
+	// The domain type's package is not one goimports can resolve on its own
+	var domainImports []string
+	if domainType := cg.config.GetDomainMapping(table.Name); domainType != "" {
+		importPath, err := cg.domainImport(table.Name, domainType)
+		if err != nil {
+			return "", err
+		}
+		domainImports = append(domainImports, importPath)
 	}
 
 	// Combine and deduplicate imports
-	allImports := cg.combineImports(coreImports, typeImports)
+	allImports := cg.combineImports(coreImports, typeImports, domainImports)
 
 	// Generate struct
 	structCode, err := cg.generateStruct(table)
@@ -69,6 +509,12 @@ func (cg *CodeGenerator) generateTableCode(table Table) (string, error) {
 		return "", fmt.Errorf("failed to generate struct: %w", err)
 	}
 
+	// Generate named types for enum-like smallint columns
+	intEnumCode, err := cg.generateIntEnums(table)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate int enums: %w", err)
+	}
+
 	// Generate repository
 	repositoryCode, err := cg.generateRepository(table)
 	if err != nil {
@@ -81,6 +527,18 @@ func (cg *CodeGenerator) generateTableCode(table Table) (string, error) {
 		return "", fmt.Errorf("failed to generate CRUD operations: %w", err)
 	}
 
+	// Generate the domain_mapping conversions
+	domainCode, err := cg.generateDomainMethods(table)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate domain methods: %w", err)
+	}
+
+	// Generate the eager_load listings
+	eagerLoadCode, err := cg.generateEagerLoads(table)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate eager loads: %w", err)
+	}
+
 	// Generate enhanced features
 	enhancedCode, err := cg.generateEnhancedFeatures(table)
 	if err != nil {
@@ -95,13 +553,13 @@ func (cg *CodeGenerator) generateTableCode(table Table) (string, error) {
 	code.WriteString(fmt.Sprintf("// Source: table %s\n\n", table.Name))
 
 	// Package declaration
-	code.WriteString(fmt.Sprintf("package %s\n\n", cg.config.PackageName))
+	code.WriteString(fmt.Sprintf("package %s\n\n", cg.config.GetPackageName()))
 
 	// Imports - include the predicted imports so imports.Process has them to work with
 	if len(allImports) > 0 {
 		code.WriteString("import (\n")
 		for _, imp := range allImports {
-			code.WriteString(fmt.Sprintf("\t\"%s\"\n", imp))
+			code.WriteString("\t" + cg.importSpec(imp) + "\n")
 		}
 		code.WriteString(")\n\n")
 	}
@@ -110,6 +568,11 @@ func (cg *CodeGenerator) generateTableCode(table Table) (string, error) {
 	code.WriteString(structCode)
 	code.WriteString("\n\n")
 
+	if intEnumCode != "" {
+		code.WriteString(intEnumCode)
+		code.WriteString("\n\n")
+	}
+
 	// Repository definition
 	code.WriteString(repositoryCode)
 	code.WriteString("\n\n")
@@ -117,15 +580,209 @@ func (cg *CodeGenerator) generateTableCode(table Table) (string, error) {
 	// CRUD operations
 	code.WriteString(crudCode)
 
+	if domainCode != "" {
+		code.WriteString("\n\n")
+		code.WriteString(domainCode)
+	}
+
+	if eagerLoadCode != "" {
+		code.WriteString("\n\n")
+		code.WriteString(eagerLoadCode)
+	}
+
 	// Enhanced features
 	if enhancedCode != "" {
 		code.WriteString("\n\n")
 		code.WriteString(enhancedCode)
 	}
 
+	// Test helpers are destructive, so they are only emitted on explicit request
+	if cg.config.GenerateTestHelpers {
+		data, err := cg.prepareCRUDTemplateData(table)
+		if err != nil {
+			return "", fmt.Errorf("failed to prepare template data: %w", err)
+		}
+		truncateCode, err := cg.templateMgr.ExecuteTemplate(TemplateTruncate, data)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate test helpers: %w", err)
+		}
+		code.WriteString("\n\n")
+		code.WriteString(truncateCode)
+	}
+
+	// Relationship loaders are added after every other repository method, which they must not shadow
+	relationshipCode, err := cg.generateRelationships(table, code.String())
+	if err != nil {
+		return "", fmt.Errorf("failed to generate relationships: %w", err)
+	}
+	if relationshipCode != "" {
+		code.WriteString("\n\n")
+		code.WriteString(relationshipCode)
+	}
+
+	// The interface is read off the methods generated above, so it always matches the function list
+	if cg.config.GenerateInterfaces {
+		interfaceCode, err := cg.generateRepositoryInterface(table, code.String())
+		if err != nil {
+			return "", fmt.Errorf("failed to generate repository interface: %w", err)
+		}
+		code.WriteString("\n\n")
+		code.WriteString(interfaceCode)
+	}
+
 	return code.String(), nil
 }
 
+// generateRepositoryInterface generates an interface over the exported methods the table's
+// generated code declares on its repository, with their signatures exactly as generated
+func (cg *CodeGenerator) generateRepositoryInterface(table Table, code string) (string, error) {
+	repositoryName := table.GoStructName() + "Repository"
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", code, parser.SkipObjectResolution)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse generated code: %w", err)
+	}
+
+	var methods []string
+	for _, fn := range repositoryMethods(file, repositoryName) {
+		var signature strings.Builder
+		if err := printer.Fprint(&signature, fset, fn.Type); err != nil {
+			return "", fmt.Errorf("failed to print method %s: %w", fn.Name.Name, err)
+		}
+		methods = append(methods, fn.Name.Name+strings.TrimPrefix(signature.String(), "func"))
+	}
+
+	data := struct {
+		InterfaceName  string
+		RepositoryName string
+		Methods        []string
+	}{
+		InterfaceName:  repositoryName + "Interface",
+		RepositoryName: repositoryName,
+		Methods:        methods,
+	}
+
+	return cg.templateMgr.ExecuteTemplate(TemplateRepositoryInterface, data)
+}
+
+// repositoryMethods returns the exported methods file declares on the named repository, in source order.
+// WithTx is left out: it returns the concrete repository, which a mock could not stand in for
+func repositoryMethods(file *ast.File, repositoryName string) []*ast.FuncDecl {
+	var methods []*ast.FuncDecl
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv == nil || !fn.Name.IsExported() || fn.Name.Name == "WithTx" {
+			continue
+		}
+		star, ok := fn.Recv.List[0].Type.(*ast.StarExpr)
+		if !ok {
+			continue
+		}
+		if ident, ok := star.X.(*ast.Ident); ok && ident.Name == repositoryName {
+			methods = append(methods, fn)
+		}
+	}
+	return methods
+}
+
+// generateRoundtripTestCode generates a test file verifying the table struct's JSON round-trip
+func (cg *CodeGenerator) generateRoundtripTestCode(table Table) (string, error) {
+	type roundtripField struct {
+		Name      string
+		JSONName  string
+		TestValue string
+	}
+
+	data := struct {
+		StructName string
+		Fields     []roundtripField
+	}{
+		StructName: table.GoStructName(),
+	}
+	for _, col := range table.Columns {
+		data.Fields = append(data.Fields, roundtripField{
+			Name:      col.GoFieldName(),
+			JSONName:  col.Name,
+			TestValue: roundtripTestValue(col.GoType),
+		})
+	}
+
+	testCode, err := cg.templateMgr.ExecuteTemplate(TemplateRoundtripTest, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute round-trip test template: %w", err)
+	}
+
+	allImports := cg.combineImports(
+		[]string{"bytes", "encoding/json", "testing"},
+		cg.typeMapper.GetRequiredImports(table.Columns),
+	)
+
+	var code strings.Builder
+	code.WriteString("// Code generated by skimatik. DO NOT EDIT.\n")
+	code.WriteString(fmt.Sprintf("// Source: table %s\n\n", table.Name))
+	code.WriteString(fmt.Sprintf("package %s\n\n", cg.config.GetPackageName()))
+	code.WriteString("import (\n")
+	for _, imp := range allImports {
+		code.WriteString("\t" + cg.importSpec(imp) + "\n")
+	}
+	code.WriteString(")\n\n")
+	code.WriteString(testCode)
+
+	return code.String(), nil
+}
+
+// roundtripTestValue returns a non-zero Go expression for a field of the given type,
+// or an empty string when the type has no known literal and should stay zero-valued
+func roundtripTestValue(goType string) string {
+	if strings.HasPrefix(goType, "[]") && goType != "[]byte" {
+		if element := roundtripTestValue(goType[2:]); element != "" {
+			return goType + "{" + element + "}"
+		}
+		return ""
+	}
+
+	switch goType {
+	case "string":
+		return `"test"`
+	case "int16", "int32", "int64":
+		return "42"
+	case "float32", "float64":
+		return "1.5"
+	case "bool":
+		return "true"
+	case "time.Time":
+		return "time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)"
+	case "uuid.UUID":
+		return `uuid.MustParse("01890a5d-ac96-774b-bcce-b302099a8057")`
+	case "[]byte":
+		return `[]byte("test")`
+	case "json.RawMessage":
+		return `json.RawMessage(` + "`" + `{"key":"value"}` + "`" + `)`
+	case "pgtype.Text":
+		return `pgtype.Text{String: "test", Valid: true}`
+	case "pgtype.Int2":
+		return "pgtype.Int2{Int16: 42, Valid: true}"
+	case "pgtype.Int4":
+		return "pgtype.Int4{Int32: 42, Valid: true}"
+	case "pgtype.Int8":
+		return "pgtype.Int8{Int64: 42, Valid: true}"
+	case "pgtype.Float4":
+		return "pgtype.Float4{Float32: 1.5, Valid: true}"
+	case "pgtype.Float8":
+		return "pgtype.Float8{Float64: 1.5, Valid: true}"
+	case "pgtype.Bool":
+		return "pgtype.Bool{Bool: true, Valid: true}"
+	case "pgtype.Timestamptz":
+		return "pgtype.Timestamptz{Time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Valid: true}"
+	case "pgtype.UUID":
+		return `pgtype.UUID{Bytes: uuid.MustParse("01890a5d-ac96-774b-bcce-b302099a8057"), Valid: true}`
+	}
+
+	// Pointers and custom types are left at their zero value
+	return ""
+}
+
 // combineImports combines and deduplicates import lists
 func (cg *CodeGenerator) combineImports(lists ...[]string) []string {
 	seen := make(map[string]bool)
@@ -154,10 +811,10 @@ func (cg *CodeGenerator) getQueryImports(queries []Query) []string {
 			imports[imp] = true
 		}
 
-		// Get imports for parameters
-		paramImports := cg.typeMapper.GetRequiredImports(convertParametersToColumns(query.Parameters))
-		for _, imp := range paramImports {
-			imports[imp] = true
+		// Get imports for parameters from their mapped Go types, which may differ
+		// from the column mapping (e.g. interval parameters are time.Duration)
+		for _, param := range query.Parameters {
+			cg.typeMapper.addImportsForType(param.GoType, imports)
 		}
 	}
 
@@ -170,23 +827,15 @@ func (cg *CodeGenerator) getQueryImports(queries []Query) []string {
 	return result
 }
 
-// convertParametersToColumns converts parameters to columns for import calculation
-func convertParametersToColumns(params []Parameter) []Column {
-	var columns []Column
-	for _, param := range params {
-		columns = append(columns, Column{
-			Name:   param.Name,
-			Type:   param.Type,
-			GoType: param.GoType,
-		})
-	}
-	return columns
-}
-
 // generateEnhancedFeatures generates enhanced pgxkit features (retry methods)
 func (cg *CodeGenerator) generateEnhancedFeatures(table Table) (string, error) {
 	var code strings.Builder
 
+	// Retry methods call the shared retry helpers, so they are omitted along with that file
+	if cg.config.SharedFiles.Retry.Disabled {
+		return "", nil
+	}
+
 	// Prepare template data
 	data, err := cg.prepareCRUDTemplateData(table)
 	if err != nil {
@@ -213,6 +862,8 @@ func (cg *CodeGenerator) generateStruct(table Table) (string, error) {
 		TableName    string
 		ReceiverName string
 		IDField      string
+		IDValue      string
+		Paginate     bool
 		Fields       []struct {
 			Name string
 			Type string
@@ -222,7 +873,14 @@ func (cg *CodeGenerator) generateStruct(table Table) (string, error) {
 		StructName:   table.GoStructName(),
 		TableName:    table.Name,
 		ReceiverName: strings.ToLower(table.GoStructName()[:1]),
-		IDField:      table.GetPrimaryKeyColumn().GoFieldName(),
+		Paginate:     cg.config.IsPaginationEnabled(table.Name) && !table.HasCompositePrimaryKey(),
+	}
+
+	// Primary keys are never nullable, so the ID always converts to a uuid.UUID
+	if data.Paginate {
+		idColumn := table.GetPrimaryKeyColumn()
+		data.IDField = idColumn.GoFieldName()
+		data.IDValue, _ = uuidValueExpr(idColumn.GoType, data.ReceiverName+"."+data.IDField)
 	}
 
 	// Add fields
@@ -246,12 +904,17 @@ func (cg *CodeGenerator) generateStruct(table Table) (string, error) {
 // generateRepository generates the repository struct and constructor
 func (cg *CodeGenerator) generateRepository(table Table) (string, error) {
 	// Prepare template data
+	dbType, _ := cg.config.GetDBType()
 	data := struct {
 		RepositoryName string
 		TableName      string
+		QuerierPerCall bool
+		DBType         string
 	}{
 		RepositoryName: table.GoStructName() + "Repository",
 		TableName:      table.Name,
+		QuerierPerCall: cg.config.QuerierPerCall,
+		DBType:         dbType,
 	}
 
 	// Execute template using template manager
@@ -269,33 +932,154 @@ func (cg *CodeGenerator) generateCRUDOperations(table Table) (string, error) {
 	}
 
 	// Get the functions to generate for this table
-	functions := cg.config.GetTableFunctions(table.Name)
+	functions := cg.tableFunctions(table)
 
 	// Map function names to templates (using template manager)
 	operationTemplates := map[string]string{
-		"get":      TemplateGetByID,
-		"create":   TemplateCreate,
-		"update":   TemplateUpdate,
-		"delete":   TemplateDelete,
-		"list":     TemplateList,
-		"paginate": TemplatePaginationSharedListPaginated,
+		"get":                      TemplateGetByID,
+		"create":                   TemplateCreate,
+		"update":                   TemplateUpdate,
+		"delete":                   TemplateDelete,
+		"list":                     TemplateList,
+		"paginate":                 TemplatePaginationSharedListPaginated,
+		"clone":                    TemplateClone,
+		"diff":                     TemplateDiff,
+		"head":                     TemplateHead,
+		"random":                   TemplateGetRandom,
+		"modified_since":           TemplateListModifiedSince,
+		"get_by_unique":            TemplateGetByUniqueKeys,
+		"get_by_unique_constraint": TemplateGetByUniqueConstraint,
+		"stream":                   TemplatePaginationStreamPaginated,
+		"export_csv":               TemplateExportCSV,
+		"create_with_conflict":     TemplateCreateConflict,
+		"update_from_map":          TemplateUpdateFromMap,
+		"bulk_create_ids":          TemplateBulkCreateIDs,
+		"create_batch":             TemplateCreateBatch,
+		"get_with_deleted":         TemplateGetWithDeleted,
+		"list_with_deleted":        TemplateListWithDeleted,
+		"create_builder":           TemplateCreateBuilder,
+		"upsert_with_status":       TemplateUpsertWithStatus,
+		"upsert":                   TemplateUpsert,
+		"get_many_ordered":         TemplateGetManyOrdered,
+		"list_descendants":         TemplateListDescendants,
+		"list_ancestors":           TemplateListAncestors,
+		"fetch_and_lock":           TemplateFetchAndLock,
+		"count_by":                 TemplateCountBy,
+		"count_distinct":           TemplateCountDistinct,
+		"list_between":             TemplateListBetween,
+		"list_updated_by":          TemplateListUpdatedBy,
+		"search_by_prefix":         TemplateSearchByPrefix,
+		"list_between_paginated":   TemplateListBetweenPaginated,
+		"bulk_update":              TemplateBulkUpdate,
+		"stats":                    TemplateStats,
+		"get_neighbors":            TemplateGetNeighbors,
+		"list_filtered":            TemplateListFiltered,
+		"count_filtered":           TemplateCountFiltered,
+		"stream_filtered":          TemplateStreamFiltered,
+		"delete_filtered":          TemplateDeleteFiltered,
+		"count":                    TemplateCount,
+		"exists":                   TemplateExists,
+		"ensure_by_unique":         TemplateEnsureByUnique,
+	}
+
+	// Functions that reuse a params type declared by another function
+	requiredFunctions := map[string]string{
+		"update_from_map":      "update",
+		"bulk_create_ids":      "create",
+		"create_batch":         "create",
+		"bulk_update":          "update",
+		"create_with_conflict": "create",
+		"create_builder":       "create",
+		"upsert_with_status":   "create",
+		"upsert":               "create",
+		"ensure_by_unique":     "create",
+	}
+
+	softDeleteColumn := cg.config.GetSoftDeleteColumn(table.Name)
+	if softDeleteColumn != "" {
+		if err := cg.validateSoftDeleteColumn(table); err != nil {
+			return "", err
+		}
 	}
 
 	// Generate each requested CRUD operation
 	first := true
+	filterDeclared := false
 	for _, function := range functions {
 		templateStr, exists := operationTemplates[function]
 		if !exists {
 			return "", fmt.Errorf("unknown function type: %s", function)
 		}
 
-		if !first {
-			code.WriteString("\n\n")
+		if function == "modified_since" {
+			if err := cg.validateModifiedColumn(table); err != nil {
+				return "", err
+			}
 		}
-		first = false
 
-		var result string
-		var err error
+		if function == "paginate" {
+			if column := cg.config.TableConfigs[table.Name].PaginateBy; column != "" {
+				if table.GetColumn(column) == nil {
+					return "", fmt.Errorf("paginate_by column %s does not exist on table %s", column, table.Name)
+				}
+				if err := validateIndexedTimestamp(table, "paginate_by", column, "paginate_by"); err != nil {
+					return "", err
+				}
+				if table.GetColumn(column).IsNullable {
+					return "", fmt.Errorf("paginate_by column %s on table %s must be NOT NULL", column, table.Name)
+				}
+			}
+		}
+
+		if function == "list_between" || function == "list_between_paginated" {
+			if err := validateIndexedTimestamp(table, function, cg.config.GetRangeColumn(table.Name), "range_column"); err != nil {
+				return "", err
+			}
+		}
+
+		if (function == "list_descendants" || function == "list_ancestors") && ltreeColumn(table) == "" {
+			return "", fmt.Errorf("function %s requires an ltree column on table %s", function, table.Name)
+		}
+
+		if (function == "get_with_deleted" || function == "list_with_deleted") && softDeleteColumn == "" {
+			return "", fmt.Errorf("function %s requires soft_delete_column for table %s", function, table.Name)
+		}
+
+		if function == "update" && cg.config.TableConfigs[table.Name].PartialUpdate && !slices.Contains(functions, "get") {
+			return "", fmt.Errorf("partial_update requires get for table %s", table.Name)
+		}
+
+		// Without pointer fields, every column missing from the patch map would be written as its zero value
+		if function == "update_from_map" && !cg.config.TableConfigs[table.Name].PartialUpdate {
+			return "", fmt.Errorf("function update_from_map requires partial_update for table %s", table.Name)
+		}
+
+		// Bulk updates unnest one array per column, so every row must set every field
+		if function == "bulk_update" && cg.config.TableConfigs[table.Name].PartialUpdate {
+			return "", fmt.Errorf("function bulk_update cannot be combined with partial_update for table %s", table.Name)
+		}
+
+		if required, ok := requiredFunctions[function]; ok && !slices.Contains(functions, required) {
+			return "", fmt.Errorf("function %s requires %s for table %s", function, required, table.Name)
+		}
+
+		if !first {
+			code.WriteString("\n\n")
+		}
+		first = false
+
+		// The filter struct is declared once, ahead of the first method taking it
+		if isFilteredFunction(function) && !filterDeclared {
+			filter, err := cg.templateMgr.ExecuteTemplate(TemplateFilter, data)
+			if err != nil {
+				return "", fmt.Errorf("failed to execute filter template: %w", err)
+			}
+			code.WriteString(filter + "\n\n")
+			filterDeclared = true
+		}
+
+		var result string
+		var err error
 
 		// Check if this is a template manager template (starts with "templates/")
 		if strings.HasPrefix(templateStr, "templates/") {
@@ -324,6 +1108,30 @@ func (cg *CodeGenerator) generateCRUDOperations(table Table) (string, error) {
 	return code.String(), nil
 }
 
+// tableFunctions returns the functions to generate for a table, keeping only those its primary key supports
+func (cg *CodeGenerator) tableFunctions(table Table) []string {
+	functions := cg.config.GetTableFunctions(table.Name)
+	supported := keyFunctions(table)
+	if supported == nil {
+		return functions
+	}
+	return slices.DeleteFunc(slices.Clone(functions), func(function string) bool {
+		return !slices.Contains(supported, function)
+	})
+}
+
+// keyFunctions returns the functions a table's primary key limits it to, or nil for a single UUID
+// key, which supports them all
+func keyFunctions(table Table) []string {
+	switch {
+	case table.HasCompositePrimaryKey():
+		return compositeKeyFunctions
+	case !table.HasUUIDPrimaryKey():
+		return nonUUIDKeyFunctions
+	}
+	return nil
+}
+
 // prepareCRUDTemplateData prepares the data structure for CRUD templates
 func (cg *CodeGenerator) prepareCRUDTemplateData(table Table) (map[string]interface{}, error) {
 	structName := table.GoStructName()
@@ -332,8 +1140,10 @@ func (cg *CodeGenerator) prepareCRUDTemplateData(table Table) (map[string]interf
 	idColumn := table.GetPrimaryKeyColumn()
 	createParamIndex := 1
 	updateParamIndex := 1
+	partialUpdate := cg.config.TableConfigs[table.Name].PartialUpdate
 
 	// Build column lists
+	var columns []map[string]string
 	var selectColumns []string
 	var scanArgs []string
 	var createFields []map[string]string
@@ -343,64 +1153,1126 @@ func (cg *CodeGenerator) prepareCRUDTemplateData(table Table) (map[string]interf
 	var insertArgs []string
 	var updateAssignments []string
 	var updateArgs []string
+	var cloneSliceFields []map[string]string
+	var clonePointerFields []map[string]string
+	var cloneSlicePointerFields []map[string]string
+	var cloneMapFields []map[string]string
+	var diffFields []map[string]string
 
 	for _, col := range table.Columns {
 		// Select columns and scan args (for all operations)
+		// ExportCSV writes encrypted columns as their plaintext rather than re-encrypting them through Value
+		csvValue := receiverName + "." + col.GoFieldName()
+		switch {
+		case col.GoType == "EncryptedString":
+			csvValue = "string(" + csvValue + ")"
+		case col.GoType == "*EncryptedString":
+			csvValue = "encryptedPlaintext(" + csvValue + ")"
+		}
+		columns = append(columns, map[string]string{
+			"Name":     col.Name,
+			"Field":    col.GoFieldName(),
+			"CSVValue": csvValue,
+		})
 		selectColumns = append(selectColumns, col.Name)
 		scanArgs = append(scanArgs, "&"+receiverName+"."+col.GoFieldName())
 
-		// Skip ID column for create/update params (it's auto-generated)
-		if col.Name == idColumn.Name {
-			continue
+		// Clone fields (slices, maps and pointers must be copied, not shared)
+		switch {
+		case isMapGoType(col.GoType):
+			// hstore values are *string, so they are copied too rather than shared with the original
+			copyValues := ""
+			if col.GoType == "pgtype.Hstore" {
+				copyValues = "true"
+			}
+			cloneMapFields = append(cloneMapFields, map[string]string{
+				"Name":       col.GoFieldName(),
+				"Type":       col.GoType,
+				"CopyValues": copyValues,
+			})
+		case isSliceGoType(col.GoType):
+			cloneSliceFields = append(cloneSliceFields, map[string]string{
+				"Name": col.GoFieldName(),
+				"Type": col.GoType,
+			})
+		case strings.HasPrefix(col.GoType, "*") && isSliceGoType(col.GoType[1:]):
+			cloneSlicePointerFields = append(cloneSlicePointerFields, map[string]string{
+				"Name": col.GoFieldName(),
+				"Type": col.GoType[1:],
+			})
+		case strings.HasPrefix(col.GoType, "*"):
+			clonePointerFields = append(clonePointerFields, map[string]string{
+				"Name": col.GoFieldName(),
+				"Type": col.GoType[1:],
+			})
 		}
 
-		// Create fields (exclude ID and columns with defaults)
-		if col.DefaultValue == "" {
+		diffFields = append(diffFields, map[string]string{
+			"Name":    col.GoFieldName(),
+			"Column":  col.Name,
+			"Compare": diffComparison(col.GoType),
+		})
+
+		// Create fields (exclude sequence-backed and other defaulted columns); a primary key the
+		// database doesn't generate, such as a natural text key, is set on create like any other column
+		if col.DefaultValue == "" && !col.IsAutoGenerated {
 			createFields = append(createFields, map[string]string{
 				"Name": col.GoFieldName(),
 				"Type": col.GoType,
 				"Tag":  col.GoStructTag(),
 			})
 
-			insertColumns = append(insertColumns, col.Name)
-			insertPlaceholders = append(insertPlaceholders, fmt.Sprintf("$%d", createParamIndex))
-			insertArgs = append(insertArgs, "params."+col.GoFieldName())
-			createParamIndex++
+			insertColumns = append(insertColumns, col.Name)
+			insertPlaceholders = append(insertPlaceholders, fmt.Sprintf("$%d", createParamIndex))
+			insertArgs = append(insertArgs, "params."+col.GoFieldName())
+			createParamIndex++
+		}
+
+		// Skip ID column for update params (rows are addressed by it); the columns of a
+		// composite key are set like any other
+		if idColumn != nil && col.Name == idColumn.Name {
+			continue
+		}
+
+		// Update fields (all non-ID columns); a partial update takes each as a pointer so nil can
+		// mean "unchanged", which leaves nullable columns settable to NULL through a NULL value
+		updateType := col.GoType
+		if partialUpdate {
+			updateType = "*" + updateType
+		}
+		// The zero value of a nullable column's type is NULL, which a patch map sets with a nil value
+		nullType := ""
+		if col.IsNullable {
+			nullType = col.GoType
+		}
+		updateFields = append(updateFields, map[string]string{
+			"Name":     col.GoFieldName(),
+			"Type":     updateType,
+			"Tag":      col.GoStructTag(),
+			"Column":   col.Name,
+			"NullType": nullType,
+		})
+
+		updateAssignments = append(updateAssignments, fmt.Sprintf("%s = $%d", quoteIdentifier(col.Name), updateParamIndex))
+		updateArgs = append(updateArgs, "params."+col.GoFieldName())
+		updateParamIndex++
+	}
+
+	// ID parameter comes last in update
+	updateArgs = append(updateArgs, "id")
+	idParamIndex := updateParamIndex
+
+	// Single-column unique indexes support bulk lookups by natural key
+	uniqueKeys, err := cg.prepareUniqueKeys(table)
+	if err != nil {
+		return nil, err
+	}
+
+	// Composite unique constraints identify a single row by several columns
+	var uniqueLookups []map[string]string
+	if slices.Contains(cg.tableFunctions(table), "get_by_unique_constraint") {
+		uniqueLookups, err = cg.prepareUniqueLookups(table)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Writes return every column unless the table narrows RETURNING
+	returningColumns, returningScanArgs, err := cg.prepareReturningColumns(table, receiverName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Upserts resolve conflicts on a unique key the create params set
+	var upsertConflictColumns, upsertAssignments []string
+	if functions := cg.tableFunctions(table); slices.Contains(functions, "upsert") || slices.Contains(functions, "upsert_with_status") {
+		upsertConflictColumns, upsertAssignments, err = cg.prepareUpsert(table, insertColumns, uniqueKeys)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Get and Delete take one argument per primary key column
+	key, err := cg.preparePrimaryKey(table)
+	if err != nil {
+		return nil, err
+	}
+
+	// With query_options, the core CRUD methods and their retry wrappers take trailing QueryOptions
+	optionsParam, optionsArg := "", ""
+	if cg.config.QueryOptions {
+		optionsParam, optionsArg = ", opts ...QueryOption", ", opts..."
+	}
+
+	// Retry wrappers are only generated for the methods they call
+	generated := make(map[string]bool)
+	for _, function := range cg.tableFunctions(table) {
+		generated[function] = true
+	}
+
+	// Ensure methods look rows up by a unique key the create params set
+	var ensureKeys []map[string]string
+	if slices.Contains(cg.tableFunctions(table), "ensure_by_unique") {
+		ensureKeys, err = cg.prepareEnsureKeys(table, insertColumns, uniqueKeys)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Grouped and distinct counts over configured indexed columns
+	var countByKeys []map[string]interface{}
+	for _, function := range []string{"count_by", "count_distinct"} {
+		if slices.Contains(cg.tableFunctions(table), function) {
+			countByKeys, err = cg.prepareCountBy(table, function)
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	// Stats reports the bounds of the range column when the table has one
+	var statsColumn, statsField string
+	if slices.Contains(cg.tableFunctions(table), "stats") {
+		statsColumn, err = cg.prepareStats(table)
+		if err != nil {
+			return nil, err
+		}
+		if statsColumn != "" {
+			statsField = table.GetColumn(statsColumn).GoFieldName()
+		}
+	}
+
+	// ListUpdatedBy matches the audit column against a user ID of the column's non-null type
+	var updatedByType, updatedByOrderBy string
+	if slices.Contains(cg.tableFunctions(table), "list_updated_by") {
+		updatedByType, err = cg.prepareUpdatedBy(table)
+		if err != nil {
+			return nil, err
+		}
+		// Most recent changes first when the table tracks modification times
+		updatedByOrderBy = strings.Join(key.orderBy, ", ")
+		if col := table.GetColumn(cg.config.GetModifiedColumn(table.Name)); col != nil && (col.GoType == "time.Time" || col.GoType == "pgtype.Timestamptz") {
+			updatedByOrderBy = quoteIdentifier(col.Name) + " DESC, " + updatedByOrderBy
+		}
+	}
+
+	// With context helpers, Create and Update record the acting user from ctx in the audit column
+	createUserID, updateUserID := cg.prepareContextUserID(table, createFields, updateFields)
+
+	// SearchBy<Column>Prefix is named after the column it matches prefixes of
+	var prefixField string
+	if slices.Contains(cg.tableFunctions(table), "search_by_prefix") {
+		if err := validatePrefixColumn(table, cg.config.GetPrefixColumn(table.Name)); err != nil {
+			return nil, err
+		}
+		prefixField = table.GetColumn(cg.config.GetPrefixColumn(table.Name)).GoFieldName()
+	}
+
+	// Bulk updates bind one array per updatable column
+	var bulkUpdateFields []map[string]string
+	if slices.Contains(cg.tableFunctions(table), "bulk_update") {
+		bulkUpdateFields, err = prepareBulkUpdate(table, idColumn)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Each chunk of a multi-row insert binds every create column per row
+	if chunk := cg.config.BatchChunkSize; chunk > 0 && chunk*len(createFields) > 65535 &&
+		(slices.Contains(cg.tableFunctions(table), "create_batch") || slices.Contains(cg.tableFunctions(table), "bulk_create_ids")) {
+		return nil, fmt.Errorf("batch_chunk_size %d exceeds the PostgreSQL limit of 65535 parameters for table %s, which inserts %d columns per row", chunk, table.Name, len(createFields))
+	}
+
+	// Filtered methods share one filter struct with an optional equality match per column
+	var filterFields []map[string]string
+	if slices.ContainsFunc(cg.tableFunctions(table), isFilteredFunction) {
+		filterFields, err = cg.prepareFilter(table)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Soft delete filters reads on a nullable timestamp column
+	softDeleteColumn := cg.config.GetSoftDeleteColumn(table.Name)
+	softDeleteField := ""
+	if col := table.GetColumn(softDeleteColumn); col != nil {
+		softDeleteField = col.GoFieldName()
+	}
+
+	// Simple CHECK constraints are mirrored as Validate methods on the params structs
+	var updateColumns []string
+	for _, field := range updateFields {
+		updateColumns = append(updateColumns, field["Column"])
+	}
+	createChecks := checkValidations(table, insertColumns, false)
+	updateChecks := checkValidations(table, updateColumns, partialUpdate)
+
+	// Modification timestamp used for incremental sync; nullable columns hold a pgtype.Timestamptz
+	modifiedColumn := cg.config.GetModifiedColumn(table.Name)
+	modifiedTimeExpr := timeFieldExpr(table, modifiedColumn)
+
+	// Timestamp column date range listings filter and page on
+	rangeColumn := cg.config.GetRangeColumn(table.Name)
+	rangeTimeExpr := timeFieldExpr(table, rangeColumn)
+
+	// ListPaginated keys its cursor on (paginate_by, id) when a column is configured
+	paginateBy := cg.config.TableConfigs[table.Name].PaginateBy
+
+	data := map[string]interface{}{
+		"StructName":         structName,
+		"RepositoryName":     repositoryName,
+		"ReceiverName":       receiverName,
+		"TableName":          table.Name,
+		"IDColumn":           key.idColumn,
+		"KeyDescription":     key.description,
+		"KeyParams":          strings.Join(key.params, ", "),
+		"KeyArgs":            strings.Join(key.args, ", "),
+		"KeyCondition":       strings.Join(key.conditions, " AND "),
+		"KeyOrderBy":         strings.Join(key.orderBy, ", "),
+		"KeyNilChecks":       key.nilChecks,
+		"Generated":          generated,
+		"IDParamIndex":       idParamIndex,
+		"Columns":            columns,
+		"SelectColumns":      strings.Join(quoteIdentifiers(selectColumns), ", "),
+		"ScanArgs":           strings.Join(scanArgs, ", "),
+		"ReturningColumns":   strings.Join(quoteIdentifiers(returningColumns), ", "),
+		"ReturningScanArgs":  strings.Join(returningScanArgs, ", "),
+		"NarrowReturning":    len(returningColumns) < len(table.Columns),
+		"CreateFields":       createFields,
+		"BuilderFields":      builderFields(createFields),
+		"UpdateFields":       updateFields,
+		"InsertColumns":      strings.Join(quoteIdentifiers(insertColumns), ", "),
+		"InsertPlaceholders": strings.Join(insertPlaceholders, ", "),
+		"InsertArgs":         strings.Join(insertArgs, ", "),
+		"UpdateAssignments":  strings.Join(updateAssignments, ", "),
+		"UpdateArgs":         strings.Join(updateArgs, ", "),
+
+		"UniqueKeys":            uniqueKeys,
+		"UniqueLookups":         uniqueLookups,
+		"EnsureKeys":            ensureKeys,
+		"UpsertConflictColumns": strings.Join(quoteIdentifiers(upsertConflictColumns), ", "),
+		"UpsertAssignments":     strings.Join(upsertAssignments, ", "),
+		"ModifiedColumn":        modifiedColumn,
+		"ModifiedTimeExpr":      modifiedTimeExpr,
+		"ModifiedColumnType":    columnType(table, modifiedColumn),
+		"RangeColumn":           rangeColumn,
+		"RangeTimeExpr":         rangeTimeExpr,
+		"RangeColumnType":       columnType(table, rangeColumn),
+		"PaginateBy":            paginateBy,
+		"PaginateByTimeExpr":    timeFieldExpr(table, paginateBy),
+		"PaginateByType":        columnType(table, paginateBy),
+		"UpdatedByColumn":       cg.config.GetUpdatedByColumn(table.Name),
+		"UpdatedByType":         updatedByType,
+		"UpdatedByOrderBy":      updatedByOrderBy,
+		"CreateUserID":          createUserID,
+		"UpdateUserID":          updateUserID,
+		"PrefixColumn":          cg.config.GetPrefixColumn(table.Name),
+		"PrefixField":           prefixField,
+		"StatsColumn":           statsColumn,
+		"StatsField":            statsField,
+		"SoftDeleteColumn":      softDeleteColumn,
+		"CollectRows":           cg.config.ScanMode == ScanModeCollectRows,
+		"SoftDeleteField":       softDeleteField,
+		"LtreeColumn":           ltreeColumn(table),
+		"CountByKeys":           countByKeys,
+		"BulkUpdateFields":      bulkUpdateFields,
+		"IDArrayType":           key.idArrayType,
+
+		"CloneSliceFields":        cloneSliceFields,
+		"ClonePointerFields":      clonePointerFields,
+		"CloneSlicePointerFields": cloneSlicePointerFields,
+		"CloneMapFields":          cloneMapFields,
+		"DiffFields":              diffFields,
+		"CreateChecks":            createChecks,
+		"UpdateChecks":            updateChecks,
+		"PartialUpdate":           partialUpdate,
+		"AllowFullDelete":         cg.config.TableConfigs[table.Name].AllowFullDelete,
+		"RejectNilIDs":            cg.config.RejectNilIDs,
+		"QueryOptions":            cg.config.QueryOptions,
+		"BatchChunkSize":          cg.config.BatchChunkSize,
+		"OptionsParam":            optionsParam,
+		"OptionsArg":              optionsArg,
+		"FilterFields":            filterFields,
+	}
+	cg.addExecutorData(data)
+	return data, nil
+}
+
+// addExecutorData sets the template values naming what generated methods execute on: the
+// repository's stored r.db, or with querier_per_call a Querier parameter that follows ctx
+func (cg *CodeGenerator) addExecutorData(data map[string]interface{}) {
+	if cg.config.QuerierPerCall {
+		data["DB"] = "q"
+		data["QuerierParam"] = ", q Querier"
+		data["QuerierArg"] = ", q"
+		return
+	}
+	data["DB"] = "r.db"
+	data["QuerierParam"] = ""
+	data["QuerierArg"] = ""
+}
+
+// uuidValueExpr returns the Go expression yielding a uuid.UUID from a UUID column's value,
+// converting custom ID types mapped over uuid, which must have [16]byte as their underlying type.
+// Pointer types are reported as unusable since a nil pointer has no ID to return
+func uuidValueExpr(goType, expr string) (string, bool) {
+	switch {
+	case goType == "uuid.UUID":
+		return expr, true
+	case goType == "pgtype.UUID":
+		return fmt.Sprintf("uuid.UUID(%s.Bytes)", expr), true
+	case strings.HasPrefix(goType, "*") || strings.HasPrefix(goType, "[]"):
+		return "", false
+	default:
+		return fmt.Sprintf("uuid.UUID(%s)", expr), true
+	}
+}
+
+// nullableBuilderTypes maps nullable pgtype fields to the plain Go type a builder setter accepts
+// and the pgtype field that holds it
+var nullableBuilderTypes = map[string][2]string{
+	"pgtype.Text":        {"string", "String"},
+	"pgtype.Int2":        {"int16", "Int16"},
+	"pgtype.Int4":        {"int32", "Int32"},
+	"pgtype.Int8":        {"int64", "Int64"},
+	"pgtype.Float4":      {"float32", "Float32"},
+	"pgtype.Float8":      {"float64", "Float64"},
+	"pgtype.Bool":        {"bool", "Bool"},
+	"pgtype.Timestamptz": {"time.Time", "Time"},
+	"pgtype.UUID":        {"uuid.UUID", "Bytes"},
+}
+
+// builderFields describes the params builder setter for each create field; nullable fields take
+// the plain value and store it as set, since leaving a field out of the builder already means NULL
+func builderFields(createFields []map[string]string) []map[string]interface{} {
+	var fields []map[string]interface{}
+	for _, field := range createFields {
+		paramType, assign, nullable := field["Type"], "value", false
+		if nullableType, ok := nullableBuilderTypes[field["Type"]]; ok {
+			paramType, nullable = nullableType[0], true
+			assign = fmt.Sprintf("%s{%s: value, Valid: true}", field["Type"], nullableType[1])
+		} else if strings.HasPrefix(field["Type"], "*") {
+			paramType, assign, nullable = field["Type"][1:], "&value", true
+		}
+		fields = append(fields, map[string]interface{}{
+			"Name":     field["Name"],
+			"Type":     paramType,
+			"Assign":   assign,
+			"Nullable": nullable,
+		})
+	}
+	return fields
+}
+
+// checkValueAccessors maps the Go types CHECK validation understands to the field holding the value;
+// nullable pgtype values are only checked when Valid, since NULL satisfies a CHECK in PostgreSQL
+var checkValueAccessors = map[string]string{
+	"string":        "",
+	"int16":         "",
+	"int32":         "",
+	"int64":         "",
+	"float32":       "",
+	"float64":       "",
+	"pgtype.Text":   ".String",
+	"pgtype.Int2":   ".Int16",
+	"pgtype.Int4":   ".Int32",
+	"pgtype.Int8":   ".Int64",
+	"pgtype.Float4": ".Float32",
+	"pgtype.Float8": ".Float64",
+}
+
+// checkValidations builds the Validate conditions for the params fields backed by the given columns
+// Each entry holds a Go Condition that is true when the value violates the check, and the error Detail;
+// checks on columns of other types, or whose literals don't fit the column type, are left to the database.
+// Optional fields are pointers, and a nil one is not checked
+func checkValidations(table Table, columns []string, optional bool) []map[string]string {
+	var validations []map[string]string
+	for _, check := range table.Checks {
+		if !slices.Contains(columns, check.Column) {
+			continue
+		}
+		col := table.GetColumn(check.Column)
+		if col == nil {
+			continue
+		}
+		accessor, ok := checkValueAccessors[col.GoType]
+		if !ok {
+			continue
+		}
+
+		isString := col.GoType == "string" || col.GoType == "pgtype.Text"
+		isInteger := strings.Contains(col.GoType, "Int") || strings.HasPrefix(col.GoType, "int")
+		var operands []string
+		for _, value := range check.Values {
+			switch {
+			case isString:
+				operands = append(operands, strconv.Quote(value))
+			case checkNumberRegex.MatchString(value) && (!isInteger || !strings.Contains(value, ".")):
+				operands = append(operands, value)
+			}
+		}
+		if len(operands) != len(check.Values) {
+			continue
+		}
+
+		field := "p." + col.GoFieldName()
+		value := field + accessor
+		if optional {
+			value = "(*" + field + ")" + accessor
+		}
+		var condition, detail string
+		if check.Operator == "IN" {
+			matches := make([]string, len(operands))
+			for i, operand := range operands {
+				matches[i] = value + " == " + operand
+			}
+			condition = "!(" + strings.Join(matches, " || ") + ")"
+			detail = fmt.Sprintf("%s must be one of %s", check.Column, strings.Join(operands, ", "))
+		} else {
+			operator := check.Operator
+			switch operator {
+			case "=":
+				operator = "=="
+			case "<>":
+				operator = "!="
+			}
+			condition = fmt.Sprintf("!(%s %s %s)", value, operator, operands[0])
+			detail = fmt.Sprintf("%s must be %s %s", check.Column, check.Operator, operands[0])
+		}
+		if accessor != "" {
+			condition = field + ".Valid && " + condition
+		}
+		if optional {
+			condition = field + " != nil && " + condition
+		}
+
+		validations = append(validations, map[string]string{
+			"Condition": condition,
+			"Detail":    fmt.Sprintf("%s (constraint %s)", detail, check.Name),
+		})
+	}
+	return validations
+}
+
+// prepareCountBy describes the grouped and distinct count methods of each count_by column. The counts
+// are keyed by the column's non-nullable Go type, so the column must hold a comparable value, and it
+// must lead an index to keep the GROUP BY and DISTINCT from sorting the whole table. function names
+// the requested function in errors
+func (cg *CodeGenerator) prepareCountBy(table Table, function string) ([]map[string]interface{}, error) {
+	columns := cg.config.TableConfigs[table.Name].CountByColumns
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("function %s requires count_by_columns for table %s", function, table.Name)
+	}
+
+	var keys []map[string]interface{}
+	for _, name := range columns {
+		col := table.GetColumn(name)
+		if col == nil {
+			return nil, fmt.Errorf("%s column %s does not exist on table %s", function, name, table.Name)
+		}
+
+		keyType := strings.TrimPrefix(col.GoType, "*")
+		if nullableType, ok := nullableBuilderTypes[keyType]; ok {
+			keyType = nullableType[0]
+		}
+		// Slices and maps, such as hstore, can't be map keys
+		if col.IsArray || isSliceGoType(keyType) || isMapGoType(keyType) {
+			return nil, fmt.Errorf("%s column %s on table %s must hold a comparable value, got %s", function, name, table.Name, col.Type)
+		}
+		if isEncryptedColumn(*col) {
+			return nil, fmt.Errorf("%s column %s on table %s is encrypted, so its stored values can't be grouped", function, name, table.Name)
+		}
+
+		indexed := false
+		for _, index := range table.Indexes {
+			if len(index.Columns) > 0 && index.Columns[0] == name {
+				indexed = true
+				break
+			}
+		}
+		if !indexed {
+			return nil, fmt.Errorf("%s requires an index on %s.%s", function, table.Name, name)
+		}
+
+		keys = append(keys, map[string]interface{}{
+			"Column":     col.Name,
+			"Type":       keyType,
+			"Nullable":   col.IsNullable,
+			"MethodName": "CountBy" + col.GoFieldName(),
+			"Operation":  "count_by_" + col.Name,
+
+			"DistinctMethodName": "CountDistinct" + cg.pluralize(col.GoFieldName()),
+			"DistinctOperation":  "count_distinct_" + col.Name,
+		})
+	}
+	return keys, nil
+}
+
+// prepareBulkUpdate describes the per-column arrays BulkUpdate binds and unnests; each array is
+// cast to the column's type so PostgreSQL can resolve unnest's output columns
+func prepareBulkUpdate(table Table, idColumn *Column) ([]map[string]string, error) {
+	var fields []map[string]string
+	for _, col := range table.Columns {
+		if col.Name == idColumn.Name {
+			continue
+		}
+		// unnest flattens nested arrays, so array columns cannot ride along as a single value per row
+		if col.IsArray {
+			return nil, fmt.Errorf("bulk_update cannot update array column %s on table %s", col.Name, table.Name)
+		}
+		fields = append(fields, map[string]string{
+			"Name":   col.GoFieldName(),
+			"Var":    toCamelCase(col.Name) + "Values",
+			"Type":   col.GoType,
+			"Column": col.Name,
+			"Param":  fmt.Sprintf("$%d::%s[]", len(fields)+2, col.Type),
+		})
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("bulk_update requires at least one non-ID column on table %s", table.Name)
+	}
+	return fields, nil
+}
+
+// prepareStats returns the timestamp column Stats reports bounds for, or an empty string when the
+// table lacks the default column; an explicitly configured range_column must be a timestamp
+func (cg *CodeGenerator) prepareStats(table Table) (string, error) {
+	column := cg.config.GetRangeColumn(table.Name)
+	col := table.GetColumn(column)
+	if col != nil && !col.IsArray && slices.Contains([]string{"timestamptz", "timestamp", "date"}, col.Type) {
+		return column, nil
+	}
+	if cg.config.TableConfigs[table.Name].RangeColumn != "" {
+		return "", fmt.Errorf("stats range_column %s on table %s must be a timestamp column", column, table.Name)
+	}
+	return "", nil
+}
+
+// filteredFunctions are the functions taking the table's filter struct
+var filteredFunctions = []string{"list_filtered", "count_filtered", "stream_filtered", "delete_filtered"}
+
+// isFilteredFunction reports whether a function takes the table's filter struct
+func isFilteredFunction(function string) bool {
+	return slices.Contains(filteredFunctions, function)
+}
+
+// prepareFilter returns the fields of the table's filter struct: a pointer per column that can be
+// compared with =, so a nil field leaves its column unfiltered. Arrays and JSON and binary
+// columns are left out, as equality on them is rarely what a filter means (json has none at all),
+// and so are encrypted columns, whose stored ciphertext never equals the plaintext filtered on
+func (cg *CodeGenerator) prepareFilter(table Table) ([]map[string]string, error) {
+	// Soft-deleted rows are always excluded, so the soft delete column isn't filterable
+	softDeleteColumn := cg.config.GetSoftDeleteColumn(table.Name)
+
+	var fields []map[string]string
+	for _, col := range table.Columns {
+		if col.IsArray || col.Name == softDeleteColumn || slices.Contains([]string{"json", "jsonb", "bytea"}, col.Type) {
+			continue
+		}
+		if isEncryptedColumn(col) {
+			continue
+		}
+
+		// Nullable columns filter on their non-null value; pointer types already are one
+		fieldType := col.GoType
+		switch {
+		case !col.IsNullable:
+			fieldType = "*" + fieldType
+		case !strings.HasPrefix(fieldType, "*"):
+			baseType, err := cg.typeMapper.MapType(col.Type, false, false)
+			if err != nil {
+				return nil, fmt.Errorf("failed to map filter type for column %s: %w", col.Name, err)
+			}
+			fieldType = "*" + baseType
+		}
+
+		fields = append(fields, map[string]string{
+			"Name":   col.GoFieldName(),
+			"Type":   fieldType,
+			"Column": quoteIdentifier(col.Name),
+		})
+	}
+	return fields, nil
+}
+
+// prepareUpdatedBy returns the Go type ListUpdatedBy takes the user ID as, the non-null type of the
+// table's updated_by column
+func (cg *CodeGenerator) prepareUpdatedBy(table Table) (string, error) {
+	column := cg.config.GetUpdatedByColumn(table.Name)
+	col := table.GetColumn(column)
+	if col == nil {
+		return "", fmt.Errorf("list_updated_by requires column %s on table %s (set updated_by_column to use another column)", column, table.Name)
+	}
+	if col.IsArray {
+		return "", fmt.Errorf("list_updated_by column %s on table %s cannot be an array", column, table.Name)
+	}
+
+	if !col.IsNullable || strings.HasPrefix(col.GoType, "*") {
+		return strings.TrimPrefix(col.GoType, "*"), nil
+	}
+	baseType, err := cg.typeMapper.MapType(col.Type, false, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to map list_updated_by type for column %s: %w", column, err)
+	}
+	return baseType, nil
+}
+
+// prepareContextUserID returns, for the create and update params, the field that receives the
+// UserIDFromContext user ID and the expression converting the ID to that field's type. Every write
+// method taking those params applies it. A nil result means context helpers are off or the params
+// have no uuid updated_by field, and the params are written as the caller set them
+func (cg *CodeGenerator) prepareContextUserID(table Table, createFields, updateFields []map[string]string) (map[string]string, map[string]string) {
+	if !cg.config.GenerateContextHelpers {
+		return nil, nil
+	}
+	col := table.GetColumn(cg.config.GetUpdatedByColumn(table.Name))
+	if col == nil || !col.IsUUID() || col.IsArray {
+		return nil, nil
+	}
+
+	assignment := func(fields []map[string]string) map[string]string {
+		for _, field := range fields {
+			if field["Name"] != col.GoFieldName() {
+				continue
+			}
+			var value string
+			switch field["Type"] {
+			case "uuid.UUID":
+				value = "userID"
+			case "*uuid.UUID":
+				value = "&userID"
+			case "pgtype.UUID":
+				value = "pgtype.UUID{Bytes: userID, Valid: true}"
+			case "*pgtype.UUID":
+				value = "&pgtype.UUID{Bytes: userID, Valid: true}"
+			default:
+				return nil
+			}
+			return map[string]string{"Field": field["Name"], "Value": value}
+		}
+		return nil
+	}
+	return assignment(createFields), assignment(updateFields)
+}
+
+// ltreeColumn returns the first ltree column of a table, which hierarchy queries run over,
+// or an empty string when the table has none
+func ltreeColumn(table Table) string {
+	for _, col := range table.Columns {
+		if col.Type == "ltree" && !col.IsArray {
+			return col.Name
+		}
+	}
+	return ""
+}
+
+// prepareUpsert returns the conflict target and DO UPDATE assignments for upserts. The target must be
+// set by the create params, since a generated primary key never conflicts: it defaults to a primary
+// key the params do set (the columns of a composite key), then to the first such unique index. The
+// remaining insert columns take the new values, and a target covering every column re-assigns
+// itself so the conflicting row is still returned
+func (cg *CodeGenerator) prepareUpsert(table Table, insertColumns []string, uniqueKeys []map[string]string) ([]string, []string, error) {
+	conflictColumns := cg.config.TableConfigs[table.Name].ConflictColumns
+	if len(conflictColumns) == 0 {
+		if len(table.PrimaryKey) > 0 && !slices.ContainsFunc(table.PrimaryKey, func(name string) bool {
+			return !slices.Contains(insertColumns, name)
+		}) {
+			conflictColumns = table.PrimaryKey
+		}
+	}
+	if len(conflictColumns) == 0 {
+		for _, key := range uniqueKeys {
+			if slices.Contains(insertColumns, key["Column"]) {
+				conflictColumns = []string{key["Column"]}
+				break
+			}
+		}
+		if len(conflictColumns) == 0 {
+			return nil, nil, fmt.Errorf("upserts on table %s need a unique key set on create; configure conflict_columns%s", table.Name, uniqueIndexAlternatives(table))
+		}
+	}
+
+	for _, name := range conflictColumns {
+		if table.GetColumn(name) == nil {
+			return nil, nil, fmt.Errorf("conflict column %s does not exist on table %s", name, table.Name)
+		}
+		if !slices.Contains(insertColumns, name) {
+			return nil, nil, fmt.Errorf("conflict column %s on table %s is not set by Create%sParams", name, table.Name, table.GoStructName())
+		}
+	}
+
+	var assignments []string
+	for _, name := range insertColumns {
+		if !slices.Contains(conflictColumns, name) {
+			assignments = append(assignments, fmt.Sprintf("%s = EXCLUDED.%s", quoteIdentifier(name), quoteIdentifier(name)))
+		}
+	}
+	if len(assignments) == 0 {
+		assignments = append(assignments, fmt.Sprintf("%s = EXCLUDED.%s", quoteIdentifier(conflictColumns[0]), quoteIdentifier(conflictColumns[0])))
+	}
+
+	return conflictColumns, assignments, nil
+}
+
+// uniqueIndexAlternatives lists a table's unique indexes as candidate conflict_columns for an error message
+func uniqueIndexAlternatives(table Table) string {
+	var alternatives []string
+	for _, index := range table.Indexes {
+		if index.IsUnique {
+			alternatives = append(alternatives, "["+strings.Join(index.Columns, ", ")+"]")
+		}
+	}
+	if len(alternatives) == 0 {
+		return ""
+	}
+	return " (unique indexes: " + strings.Join(alternatives, ", ") + ")"
+}
+
+// prepareReturningColumns returns the RETURNING column list and matching scan arguments for writes
+// A configured list must name existing columns and always include the primary key
+func (cg *CodeGenerator) prepareReturningColumns(table Table, receiverName string) ([]string, []string, error) {
+	names := cg.config.TableConfigs[table.Name].ReturningColumns
+	if len(names) == 0 {
+		for _, col := range table.Columns {
+			names = append(names, col.Name)
+		}
+	}
+
+	for _, key := range table.PrimaryKey {
+		if !slices.Contains(names, key) {
+			return nil, nil, fmt.Errorf("returning_columns for table %s must include the primary key %s", table.Name, key)
+		}
+	}
+
+	var columns, scanArgs []string
+	for _, name := range names {
+		col := table.GetColumn(name)
+		if col == nil {
+			return nil, nil, fmt.Errorf("returning_columns for table %s references unknown column %s", table.Name, name)
+		}
+		columns = append(columns, col.Name)
+		scanArgs = append(scanArgs, "&"+receiverName+"."+col.GoFieldName())
+	}
+
+	return columns, scanArgs, nil
+}
+
+// prepareUniqueKeys returns template data for each column backed by a single-column unique index
+func (cg *CodeGenerator) prepareUniqueKeys(table Table) ([]map[string]string, error) {
+	var uniqueKeys []map[string]string
+	seen := make(map[string]bool)
+
+	for _, index := range table.Indexes {
+		if !index.IsUnique || len(index.Columns) != 1 || seen[index.Columns[0]] {
+			continue
+		}
+
+		// Expression indexes such as lower(email) don't name a column
+		// Encrypted columns can't be matched against plaintext keys; the generator logs the skip
+		col := table.GetColumn(index.Columns[0])
+		if col == nil || col.IsArray || isEncryptedColumn(*col) {
+			continue
+		}
+		seen[col.Name] = true
+
+		// Lookup values are never NULL, so use the column's non-nullable Go type
+		goType, overridden := cg.config.GetColumnTypes(table.Name)[col.Name]
+		if !overridden {
+			var err error
+			if goType, err = cg.typeMapper.MapType(col.Type, false, false); err != nil {
+				return nil, fmt.Errorf("failed to map type for unique column %s: %w", col.Name, err)
+			}
+		}
+
+		uniqueKeys = append(uniqueKeys, map[string]string{
+			"Column":     col.Name,
+			"Type":       goType,
+			"MethodName": "GetBy" + cg.pluralize(col.GoFieldName()),
+			"ParamName":  toCamelCase(cg.pluralize(col.GoFieldName())),
+			"Operation":  "get_by_" + toSnakeCase(cg.pluralize(col.GoFieldName())),
+		})
+	}
+
+	return uniqueKeys, nil
+}
+
+// prepareUniqueLookups returns template data for a single-row finder per configured unique lookup,
+// or per UNIQUE constraint when none are configured. A configured column set must match a constraint's
+// columns in any order, and its order becomes the parameter order
+func (cg *CodeGenerator) prepareUniqueLookups(table Table) ([]map[string]string, error) {
+	lookups := cg.config.TableConfigs[table.Name].UniqueLookups
+	for _, columns := range lookups {
+		for _, name := range columns {
+			if col := table.GetColumn(name); col != nil && isEncryptedColumn(*col) {
+				return nil, fmt.Errorf("unique lookup (%s) on table %s cannot use encrypted column %s", strings.Join(columns, ", "), table.Name, name)
+			}
+		}
+	}
+	if len(lookups) == 0 {
+		// Constraints over encrypted columns can't be matched against plaintext; the generator logs the skip
+		for _, constraint := range table.UniqueConstraints {
+			if !slices.ContainsFunc(constraint.Columns, func(name string) bool {
+				col := table.GetColumn(name)
+				return col != nil && isEncryptedColumn(*col)
+			}) {
+				lookups = append(lookups, constraint.Columns)
+			}
+		}
+	}
+	if len(lookups) == 0 {
+		return nil, fmt.Errorf("function get_by_unique_constraint requires a unique constraint without encrypted columns on table %s", table.Name)
+	}
+
+	var uniqueLookups []map[string]string
+	for _, columns := range lookups {
+		matches := func(constraint UniqueConstraint) bool {
+			return len(constraint.Columns) == len(columns) && !slices.ContainsFunc(columns, func(column string) bool {
+				return !slices.Contains(constraint.Columns, column)
+			})
+		}
+		if !slices.ContainsFunc(table.UniqueConstraints, matches) {
+			return nil, fmt.Errorf("unique lookup (%s) does not match a unique constraint on table %s", strings.Join(columns, ", "), table.Name)
+		}
+
+		var fieldNames, params, args, conditions []string
+		for i, name := range columns {
+			col := table.GetColumn(name)
+			if col == nil {
+				return nil, fmt.Errorf("unique lookup column %s not found on table %s", name, table.Name)
+			}
+
+			// Lookup values are never NULL, so use the column's non-nullable Go type
+			goType, overridden := cg.config.GetColumnTypes(table.Name)[col.Name]
+			if !overridden {
+				var err error
+				if goType, err = cg.typeMapper.MapType(col.Type, false, col.IsArray); err != nil {
+					return nil, fmt.Errorf("failed to map type for unique lookup column %s: %w", col.Name, err)
+				}
+			}
+
+			param := keyParamName(col)
+			fieldNames = append(fieldNames, col.GoFieldName())
+			params = append(params, param+" "+goType)
+			args = append(args, param)
+			conditions = append(conditions, fmt.Sprintf("%s = $%d", quoteIdentifier(col.Name), i+1))
+		}
+
+		uniqueLookups = append(uniqueLookups, map[string]string{
+			"MethodName":  "GetBy" + strings.Join(fieldNames, "And"),
+			"Operation":   "get_by_" + toSnakeCase(strings.Join(fieldNames, "And")),
+			"Description": strings.Join(columns, " and "),
+			"Params":      strings.Join(params, ", "),
+			"Args":        strings.Join(args, ", "),
+			"Conditions":  strings.Join(conditions, " AND "),
+		})
+	}
+
+	return uniqueLookups, nil
+}
+
+// primaryKeyData describes how generated methods identify a row by its primary key
+type primaryKeyData struct {
+	idColumn    string   // the single UUID primary key column; empty for composite keys
+	idArrayType string   // PostgreSQL array type of the ID column
+	description string   // how doc comments name the key
+	params      []string // Go parameters, one per key column
+	args        []string // arguments passing those parameters to a query
+	conditions  []string // WHERE conditions matching the parameters
+	orderBy     []string // ORDER BY terms in key order
+	nilChecks   []string // uuid.UUID parameters rejected when reject_nil_ids is enabled
+}
+
+// preparePrimaryKey returns the template data identifying a row by its primary key. A single key
+// is the id parameter, a uuid.UUID for a UUID key and otherwise the column's own Go type; a
+// composite key takes one parameter per column, named after the column
+func (cg *CodeGenerator) preparePrimaryKey(table Table) (primaryKeyData, error) {
+	if idColumn := table.GetPrimaryKeyColumn(); idColumn != nil {
+		key := primaryKeyData{
+			idColumn:    idColumn.Name,
+			idArrayType: idColumn.Type + "[]",
+			description: "ID",
+			params:      []string{"id uuid.UUID"},
+			args:        []string{"id"},
+			conditions:  []string{quoteIdentifier(idColumn.Name) + " = $1"},
+			orderBy:     []string{quoteIdentifier(idColumn.Name) + " ASC"},
+			nilChecks:   []string{"id"},
+		}
+		if !idColumn.IsUUID() {
+			key.params = []string{"id " + idColumn.GoType}
+			key.nilChecks = nil
+		}
+		return key, nil
+	}
+
+	key := primaryKeyData{description: fmt.Sprintf("primary key (%s)", strings.Join(table.PrimaryKey, ", "))}
+	for i, name := range table.PrimaryKey {
+		col := table.GetColumn(name)
+		if col == nil {
+			return primaryKeyData{}, fmt.Errorf("primary key column %s not found on table %s", name, table.Name)
+		}
+		param := keyParamName(col)
+		key.params = append(key.params, param+" "+col.GoType)
+		key.args = append(key.args, param)
+		key.conditions = append(key.conditions, fmt.Sprintf("%s = $%d", quoteIdentifier(col.Name), i+1))
+		key.orderBy = append(key.orderBy, quoteIdentifier(col.Name)+" ASC")
+		if col.GoType == "uuid.UUID" {
+			key.nilChecks = append(key.nilChecks, param)
+		}
+	}
+	return key, nil
+}
+
+// keyParamName returns the Go parameter name of a composite key column, avoiding keywords and the
+// names generated methods already use
+func keyParamName(col *Column) string {
+	name := toCamelCase(col.GoFieldName())
+	switch name {
+	case "ctx", "r", "q", "query", "row", "err", "rowsAffected":
+		return name + "Key"
+	}
+	if token.IsKeyword(name) {
+		return name + "Key"
+	}
+	return name
+}
+
+// prepareEnsureKeys returns template data for an Ensure method per single-column unique key set by
+// the create params, since only those keys can conflict with the row being inserted
+func (cg *CodeGenerator) prepareEnsureKeys(table Table, insertColumns []string, uniqueKeys []map[string]string) ([]map[string]string, error) {
+	var ensureKeys []map[string]string
+	for _, key := range uniqueKeys {
+		if !slices.Contains(insertColumns, key["Column"]) {
+			continue
+		}
+		fieldName := table.GetColumn(key["Column"]).GoFieldName()
+		ensureKeys = append(ensureKeys, map[string]string{
+			"Column":     key["Column"],
+			"MethodName": "EnsureBy" + fieldName,
+			"Operation":  "ensure_by_" + toSnakeCase(fieldName),
+		})
+	}
+	if len(ensureKeys) == 0 {
+		return nil, fmt.Errorf("function ensure_by_unique requires a unique key set on create for table %s", table.Name)
+	}
+	return ensureKeys, nil
+}
+
+// validateSoftDeleteColumn ensures the table's soft-delete column exists and is a nullable timestamp,
+// since NULL is what marks a row as live
+func (cg *CodeGenerator) validateSoftDeleteColumn(table Table) error {
+	softDeleteColumn := cg.config.GetSoftDeleteColumn(table.Name)
+	col := table.GetColumn(softDeleteColumn)
+	if col == nil {
+		return fmt.Errorf("soft_delete_column %s does not exist on table %s", softDeleteColumn, table.Name)
+	}
+	if (col.Type != "timestamptz" && col.Type != "timestamp") || !col.IsNullable {
+		return fmt.Errorf("soft_delete_column %s on table %s must be a nullable timestamp, got %s", softDeleteColumn, table.Name, col.Type)
+	}
+	return nil
+}
+
+// timeFieldExpr returns the struct expression holding a timestamp column's time.Time value;
+// nullable columns hold a pgtype.Timestamptz
+func timeFieldExpr(table Table, column string) string {
+	col := table.GetColumn(column)
+	if col == nil {
+		return ""
+	}
+	if col.GoType == "pgtype.Timestamptz" {
+		return col.GoFieldName() + ".Time"
+	}
+	return col.GoFieldName()
+}
+
+// columnType returns a column's PostgreSQL type, which keyset cursor parameters are cast to so a
+// timestamp without time zone isn't compared through a session time zone conversion; empty when the
+// table lacks the column
+func columnType(table Table, column string) string {
+	col := table.GetColumn(column)
+	if col == nil {
+		return ""
+	}
+	return col.Type
+}
+
+// validateModifiedColumn ensures the table's modification column is an indexed timestamp
+func (cg *CodeGenerator) validateModifiedColumn(table Table) error {
+	return validateIndexedTimestamp(table, "modified_since", cg.config.GetModifiedColumn(table.Name), "modified_column")
+}
+
+// validateIndexedTimestamp ensures the column a function filters on exists, holds a timestamp,
+// and leads an index so the function's queries don't scan the whole table
+func validateIndexedTimestamp(table Table, function, column, option string) error {
+	col := table.GetColumn(column)
+	if col == nil {
+		return fmt.Errorf("%s requires column %s on table %s (set %s to use another column)", function, column, table.Name, option)
+	}
+	if col.GoType != "time.Time" && col.GoType != "pgtype.Timestamptz" {
+		return fmt.Errorf("%s column %s on table %s must be a timestamp, got %s", function, column, table.Name, col.Type)
+	}
+
+	for _, index := range table.Indexes {
+		if len(index.Columns) > 0 && index.Columns[0] == column {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s requires an index on %s.%s", function, table.Name, column)
+}
+
+// validatePrefixColumn checks that search_by_prefix's column is a text column leading an index,
+// which a LIKE prefix match can use when the index has a C collation or text_pattern_ops
+func validatePrefixColumn(table Table, column string) error {
+	col := table.GetColumn(column)
+	if col == nil {
+		return fmt.Errorf("search_by_prefix requires column %s on table %s (set prefix_column to use another column)", column, table.Name)
+	}
+	if col.IsArray || !slices.Contains([]string{"text", "varchar", "character varying", "char", "character"}, col.Type) {
+		return fmt.Errorf("search_by_prefix column %s on table %s must be a text column, got %s", column, table.Name, col.Type)
+	}
+	if isEncryptedColumn(*col) {
+		return fmt.Errorf("search_by_prefix column %s on table %s is encrypted, so its stored values can't be prefix-matched", column, table.Name)
+	}
+
+	for _, index := range table.Indexes {
+		if len(index.Columns) > 0 && index.Columns[0] == column {
+			return nil
 		}
+	}
+	return fmt.Errorf("search_by_prefix requires an index on %s.%s", table.Name, column)
+}
 
-		// Update fields (all non-ID columns)
-		updateFields = append(updateFields, map[string]string{
-			"Name": col.GoFieldName(),
-			"Type": col.GoType,
-			"Tag":  col.GoStructTag(),
-		})
+// isEncryptedColumn reports whether a column is listed in encrypted_columns. Its stored ciphertext
+// never equals a plaintext value and differs between rows holding the same plaintext, so it can't be
+// looked up, grouped or prefix-matched in SQL
+func isEncryptedColumn(col Column) bool {
+	return strings.TrimPrefix(col.GoType, "*") == "EncryptedString"
+}
 
-		updateAssignments = append(updateAssignments, fmt.Sprintf("%s = $%d", col.Name, updateParamIndex))
-		updateArgs = append(updateArgs, "params."+col.GoFieldName())
-		updateParamIndex++
-	}
+// isMapGoType reports whether a Go type is backed by a map and needs copying to avoid aliasing
+func isMapGoType(goType string) bool {
+	return strings.HasPrefix(goType, "map[") || goType == "pgtype.Hstore"
+}
 
-	// ID parameter comes last in update
-	updateArgs = append(updateArgs, "id")
-	idParamIndex := updateParamIndex
+// isSliceGoType reports whether a Go type is backed by a slice and needs copying to avoid aliasing
+func isSliceGoType(goType string) bool {
+	return strings.HasPrefix(goType, "[]") || goType == "json.RawMessage"
+}
 
-	return map[string]interface{}{
-		"StructName":         structName,
-		"RepositoryName":     repositoryName,
-		"ReceiverName":       receiverName,
-		"TableName":          table.Name,
-		"IDColumn":           idColumn.Name,
-		"IDParamIndex":       idParamIndex,
-		"SelectColumns":      strings.Join(selectColumns, ", "),
-		"ScanArgs":           strings.Join(scanArgs, ", "),
-		"CreateFields":       createFields,
-		"UpdateFields":       updateFields,
-		"InsertColumns":      strings.Join(insertColumns, ", "),
-		"InsertPlaceholders": strings.Join(insertPlaceholders, ", "),
-		"InsertArgs":         strings.Join(insertArgs, ", "),
-		"UpdateAssignments":  strings.Join(updateAssignments, ", "),
-		"UpdateArgs":         strings.Join(updateArgs, ", "),
-	}, nil
+// diffComparison returns how Diff compares a field of the given Go type: "time" for time.Time,
+// whose == also compares locations, "value" for types where == compares contents, and "deep"
+// for slices, pointers and structs holding them (such as pgtype.Numeric), which need reflect.DeepEqual
+func diffComparison(goType string) string {
+	switch goType {
+	case "time.Time":
+		return "time"
+	case "string", "bool", "int16", "int32", "int64", "float32", "float64", "uuid.UUID",
+		"pgtype.Text", "pgtype.Bool", "pgtype.Int2", "pgtype.Int4", "pgtype.Int8",
+		"pgtype.Float4", "pgtype.Float8", "pgtype.UUID":
+		return "value"
+	default:
+		return "deep"
+	}
 }
 
 // GenerateSharedPaginationTypes generates the shared pagination types file
@@ -409,7 +2281,7 @@ func (cg *CodeGenerator) GenerateSharedPaginationTypes() error {
 	data := struct {
 		PackageName string
 	}{
-		PackageName: cg.config.PackageName,
+		PackageName: cg.config.GetPackageName(),
 	}
 
 	// Execute template using template manager
@@ -419,7 +2291,7 @@ func (cg *CodeGenerator) GenerateSharedPaginationTypes() error {
 	}
 
 	// Write to file
-	filename := cg.config.GetOutputPath("pagination.go")
+	filename := cg.config.GetOutputPath(cg.config.SharedFiles.Pagination.FileName("pagination.go"))
 	if err := cg.writeCodeToFile(filename, result); err != nil {
 		return fmt.Errorf("failed to write pagination file: %w", err)
 	}
@@ -437,7 +2309,7 @@ func (cg *CodeGenerator) GenerateSharedErrors() error {
 	code.WriteString("// This file provides shared error handling utilities for all repositories\n\n")
 
 	// Package declaration
-	code.WriteString(fmt.Sprintf("package %s\n\n", cg.config.PackageName))
+	code.WriteString(fmt.Sprintf("package %s\n\n", cg.config.GetPackageName()))
 
 	// Execute template using template manager
 	result, err := cg.templateMgr.ExecuteTemplate(TemplateSharedErrors, nil)
@@ -449,7 +2321,7 @@ func (cg *CodeGenerator) GenerateSharedErrors() error {
 	code.WriteString(result)
 
 	// Write to file
-	filename := cg.config.GetOutputPath("errors.go")
+	filename := cg.config.GetOutputPath(cg.config.SharedFiles.Errors.FileName("errors.go"))
 	if err := cg.writeCodeToFile(filename, code.String()); err != nil {
 		return fmt.Errorf("failed to write errors file: %w", err)
 	}
@@ -466,10 +2338,18 @@ func (cg *CodeGenerator) GenerateSharedDatabaseOperations() error {
 	code.WriteString("// This file provides shared database operation utilities for all repositories\n\n")
 
 	// Package declaration
-	code.WriteString(fmt.Sprintf("package %s\n\n", cg.config.PackageName))
+	code.WriteString(fmt.Sprintf("package %s\n\n", cg.config.GetPackageName()))
 
 	// Execute template using template manager
-	result, err := cg.templateMgr.ExecuteTemplate(TemplateDatabaseOperations, nil)
+	dbType, _ := cg.config.GetDBType()
+	data := struct {
+		Metrics bool
+		DBType  string
+	}{
+		Metrics: cg.config.GenerateMetrics,
+		DBType:  dbType,
+	}
+	result, err := cg.templateMgr.ExecuteTemplate(TemplateDatabaseOperations, data)
 	if err != nil {
 		return fmt.Errorf("failed to execute database operations template: %w", err)
 	}
@@ -478,7 +2358,7 @@ func (cg *CodeGenerator) GenerateSharedDatabaseOperations() error {
 	code.WriteString(result)
 
 	// Write to file
-	filename := cg.config.GetOutputPath("database_operations.go")
+	filename := cg.config.GetOutputPath(cg.config.SharedFiles.DatabaseOperations.FileName("database_operations.go"))
 	if err := cg.writeCodeToFile(filename, code.String()); err != nil {
 		return fmt.Errorf("failed to write database operations file: %w", err)
 	}
@@ -495,7 +2375,7 @@ func (cg *CodeGenerator) GenerateSharedRetryOperations() error {
 	code.WriteString("// This file provides shared retry operation utilities for all repositories\n\n")
 
 	// Package declaration
-	code.WriteString(fmt.Sprintf("package %s\n\n", cg.config.PackageName))
+	code.WriteString(fmt.Sprintf("package %s\n\n", cg.config.GetPackageName()))
 
 	// Execute template using template manager
 	result, err := cg.templateMgr.ExecuteTemplate(TemplateRetryOperations, nil)
@@ -507,7 +2387,7 @@ func (cg *CodeGenerator) GenerateSharedRetryOperations() error {
 	code.WriteString(result)
 
 	// Write to file
-	filename := cg.config.GetOutputPath("retry_operations.go")
+	filename := cg.config.GetOutputPath(cg.config.SharedFiles.Retry.FileName("retry_operations.go"))
 	if err := cg.writeCodeToFile(filename, code.String()); err != nil {
 		return fmt.Errorf("failed to write retry operations file: %w", err)
 	}
@@ -515,8 +2395,242 @@ func (cg *CodeGenerator) GenerateSharedRetryOperations() error {
 	return nil
 }
 
+// GenerateSharedContextHelpers generates the shared context key type and request-scoped value helpers
+func (cg *CodeGenerator) GenerateSharedContextHelpers() error {
+	var code strings.Builder
+
+	// Header
+	code.WriteString("// Code generated by skimatik. DO NOT EDIT.\n")
+	code.WriteString("// This file provides shared context helpers for request-scoped values\n\n")
+
+	// Package declaration
+	code.WriteString(fmt.Sprintf("package %s\n\n", cg.config.GetPackageName()))
+
+	result, err := cg.templateMgr.ExecuteTemplate(TemplateContextHelpers, nil)
+	if err != nil {
+		return fmt.Errorf("failed to execute context helpers template: %w", err)
+	}
+	code.WriteString(result)
+
+	filename := cg.config.GetOutputPath("context_helpers.go")
+	if err := cg.writeCodeToFile(filename, code.String()); err != nil {
+		return fmt.Errorf("failed to write context helpers file: %w", err)
+	}
+
+	return nil
+}
+
+// GenerateSharedQueryOptions generates the query_options.go file holding QueryOption, the per-call
+// settings the generated CRUD methods accept when query_options is enabled
+func (cg *CodeGenerator) GenerateSharedQueryOptions() error {
+	var code strings.Builder
+
+	// Header
+	code.WriteString("// Code generated by skimatik. DO NOT EDIT.\n")
+	code.WriteString("// This file provides shared per-call query options\n\n")
+
+	// Package declaration
+	code.WriteString(fmt.Sprintf("package %s\n\n", cg.config.GetPackageName()))
+
+	result, err := cg.templateMgr.ExecuteTemplate(TemplateQueryOptions, nil)
+	if err != nil {
+		return fmt.Errorf("failed to execute query options template: %w", err)
+	}
+	code.WriteString(result)
+
+	filename := cg.config.GetOutputPath("query_options.go")
+	if err := cg.writeCodeToFile(filename, code.String()); err != nil {
+		return fmt.Errorf("failed to write query options file: %w", err)
+	}
+
+	return nil
+}
+
+// GenerateEnums generates the enums.go file holding a string-based Go type, constants and a
+// Valid method for each enum type registered while mapping the generated tables' columns
+func (cg *CodeGenerator) GenerateEnums() error {
+	enums := cg.typeMapper.Enums()
+	if len(enums) == 0 {
+		return nil
+	}
+
+	var code strings.Builder
+
+	// Header
+	code.WriteString("// Code generated by skimatik. DO NOT EDIT.\n")
+	code.WriteString("// This file provides the PostgreSQL enum types\n\n")
+
+	// Package declaration
+	code.WriteString(fmt.Sprintf("package %s\n\n", cg.config.GetPackageName()))
+
+	type constant struct {
+		Name  string
+		Value string
+	}
+	type enumData struct {
+		Enum
+		Constants []constant
+	}
+
+	data := struct {
+		Enums     []enumData
+		TypeNames []string
+	}{}
+	var arrayTypeNames []string
+	for _, enum := range enums {
+		item := enumData{Enum: enum}
+		for i, value := range enum.Values {
+			item.Constants = append(item.Constants, constant{Name: enumConstantName(enum, i), Value: value})
+		}
+		data.Enums = append(data.Enums, item)
+
+		schema, name := "", enum.PgType
+		if dot := strings.LastIndex(name, "."); dot >= 0 {
+			schema, name = quoteIdentifier(name[:dot])+".", name[dot+1:]
+		}
+		data.TypeNames = append(data.TypeNames, schema+quoteIdentifier(name))
+		arrayTypeNames = append(arrayTypeNames, schema+quoteIdentifier("_"+name))
+	}
+	// Array types can only be loaded once their element type is registered
+	data.TypeNames = append(data.TypeNames, arrayTypeNames...)
+
+	result, err := cg.templateMgr.ExecuteTemplate(TemplateEnums, data)
+	if err != nil {
+		return fmt.Errorf("failed to execute enums template: %w", err)
+	}
+	code.WriteString(result)
+
+	filename := cg.config.GetOutputPath("enums.go")
+	if err := cg.writeCodeToFile(filename, code.String()); err != nil {
+		return fmt.Errorf("failed to write enums file: %w", err)
+	}
+
+	return nil
+}
+
+// GenerateGeometry generates the geometry.go file holding the Point type, when the struct
+// geometry mode mapped any of the generated tables' point columns to it
+func (cg *CodeGenerator) GenerateGeometry() error {
+	if !cg.typeMapper.UsesPoint() {
+		return nil
+	}
+
+	var code strings.Builder
+
+	// Header
+	code.WriteString("// Code generated by skimatik. DO NOT EDIT.\n")
+	code.WriteString("// This file provides the PostgreSQL geometric types\n\n")
+
+	// Package declaration
+	code.WriteString(fmt.Sprintf("package %s\n\n", cg.config.GetPackageName()))
+
+	result, err := cg.templateMgr.ExecuteTemplate(TemplateGeometry, nil)
+	if err != nil {
+		return fmt.Errorf("failed to execute geometry template: %w", err)
+	}
+	code.WriteString(result)
+
+	filename := cg.config.GetOutputPath("geometry.go")
+	if err := cg.writeCodeToFile(filename, code.String()); err != nil {
+		return fmt.Errorf("failed to write geometry file: %w", err)
+	}
+
+	return nil
+}
+
+// GenerateEncryption generates the encryption.go file holding the Cryptor hook and the
+// EncryptedString type, when an encrypted_columns column was mapped to it
+func (cg *CodeGenerator) GenerateEncryption() error {
+	if !cg.typeMapper.UsesEncryption() {
+		return nil
+	}
+
+	var code strings.Builder
+
+	// Header
+	code.WriteString("// Code generated by skimatik. DO NOT EDIT.\n")
+	code.WriteString("// This file provides column-level encryption\n\n")
+
+	// Package declaration
+	code.WriteString(fmt.Sprintf("package %s\n\n", cg.config.GetPackageName()))
+
+	result, err := cg.templateMgr.ExecuteTemplate(TemplateEncryption, nil)
+	if err != nil {
+		return fmt.Errorf("failed to execute encryption template: %w", err)
+	}
+	code.WriteString(result)
+
+	filename := cg.config.GetOutputPath("encryption.go")
+	if err := cg.writeCodeToFile(filename, code.String()); err != nil {
+		return fmt.Errorf("failed to write encryption file: %w", err)
+	}
+
+	return nil
+}
+
+// enumConstantName returns the Go constant name of an enum's i-th label: the type name followed
+// by the label in PascalCase, falling back to the label's position when that is not a usable,
+// unique identifier (e.g. for a label of only punctuation)
+func enumConstantName(enum Enum, i int) string {
+	name := enum.GoType + toPascalCase(enum.Values[i])
+	if !token.IsIdentifier(name) || name == enum.GoType {
+		return fmt.Sprintf("%sValue%d", enum.GoType, i+1)
+	}
+	for j := 0; j < i; j++ {
+		if enum.GoType+toPascalCase(enum.Values[j]) == name {
+			return fmt.Sprintf("%sValue%d", enum.GoType, i+1)
+		}
+	}
+	return name
+}
+
+// GenerateSchemaHash generates the schema_hash.go file holding the generation-time fingerprint
+// of the given tables and VerifySchemaHash, which recomputes it with the same query at runtime
+func (cg *CodeGenerator) GenerateSchemaHash(hash string, tableNames []string) error {
+	var code strings.Builder
+
+	// Header
+	code.WriteString("// Code generated by skimatik. DO NOT EDIT.\n")
+	code.WriteString("// This file provides the schema compatibility check\n\n")
+
+	// Package declaration
+	code.WriteString(fmt.Sprintf("package %s\n\n", cg.config.GetPackageName()))
+
+	dbType, dbImport := cg.config.GetDBType()
+	data := map[string]interface{}{
+		"Schema":   cg.config.Schema,
+		"Hash":     hash,
+		"Tables":   tableNames,
+		"Query":    schemaHashQuery,
+		"DBType":   dbType,
+		"DBImport": dbImport,
+	}
+	result, err := cg.templateMgr.ExecuteTemplate(TemplateSchemaHash, data)
+	if err != nil {
+		return fmt.Errorf("failed to execute schema hash template: %w", err)
+	}
+	code.WriteString(result)
+
+	filename := cg.config.GetOutputPath("schema_hash.go")
+	if err := cg.writeCodeToFile(filename, code.String()); err != nil {
+		return fmt.Errorf("failed to write schema hash file: %w", err)
+	}
+
+	return nil
+}
+
 // writeCodeToFile writes generated code to a file with proper formatting
+// In single file mode non-test code is collected instead and written by WriteSingleFile
 func (cg *CodeGenerator) writeCodeToFile(filename, code string) error {
+	if cg.config.SingleFile != "" && !strings.HasSuffix(filename, "_test.go") {
+		return cg.addSingleFileSection(code)
+	}
+
+	return cg.formatAndWriteFile(filename, code)
+}
+
+// formatAndWriteFile formats generated code, resolving imports, and writes it to disk
+func (cg *CodeGenerator) formatAndWriteFile(filename, code string) error {
 	// Format the code
 	formatted, err := imports.Process("", []byte(code), nil)
 	if err != nil {
@@ -524,7 +2638,7 @@ func (cg *CodeGenerator) writeCodeToFile(filename, code string) error {
 	}
 
 	// Write to file
-	if err := os.WriteFile(filename, formatted, 0644); err != nil {
+	if err := cg.writeFile(filename, formatted); err != nil {
 		return fmt.Errorf("failed to write file %s: %w", filename, err)
 	}
 
@@ -535,6 +2649,86 @@ func (cg *CodeGenerator) writeCodeToFile(filename, code string) error {
 	return nil
 }
 
+// addSingleFileSection splits a generated file into its imports and body for the single file output
+func (cg *CodeGenerator) addSingleFileSection(code string) error {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", code, parser.ParseComments|parser.ImportsOnly)
+	if err != nil {
+		return fmt.Errorf("failed to parse generated code: %w", err)
+	}
+
+	if cg.singleFileImports == nil {
+		cg.singleFileImports = make(map[string]string)
+	}
+	for _, imp := range file.Imports {
+		name := ""
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		cg.singleFileImports[strings.Trim(imp.Path.Value, `"`)] = name
+	}
+
+	// Keep the file's descriptive comments (minus the generated marker) as a section header
+	var section strings.Builder
+	for _, line := range strings.Split(code[:fset.Position(file.Package).Offset], "\n") {
+		if strings.HasPrefix(line, "//") && !strings.Contains(line, "DO NOT EDIT") {
+			section.WriteString(line + "\n")
+		}
+	}
+
+	// The body starts after the import declarations, or after the package clause without any
+	bodyStart := fset.Position(file.Name.End()).Offset
+	for _, decl := range file.Decls {
+		if fset.Position(decl.Pos()).Offset > bodyStart {
+			section.WriteString(strings.TrimSpace(code[bodyStart:fset.Position(decl.Pos()).Offset]))
+		}
+		bodyStart = fset.Position(decl.End()).Offset
+	}
+	section.WriteString("\n\n")
+	section.WriteString(strings.TrimSpace(code[bodyStart:]))
+
+	cg.singleFileSections = append(cg.singleFileSections, section.String())
+	return nil
+}
+
+// WriteSingleFile writes all collected code into the configured single output file
+// with one deduplicated import block
+func (cg *CodeGenerator) WriteSingleFile() error {
+	var paths []string
+	for path := range cg.singleFileImports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var code strings.Builder
+
+	// Header
+	code.WriteString("// Code generated by skimatik. DO NOT EDIT.\n\n")
+
+	// Package declaration
+	code.WriteString(fmt.Sprintf("package %s\n\n", cg.config.GetPackageName()))
+
+	if len(paths) > 0 {
+		code.WriteString("import (\n")
+		for _, path := range paths {
+			if name := cg.singleFileImports[path]; name != "" {
+				code.WriteString(fmt.Sprintf("\t%s \"%s\"\n", name, path))
+			} else {
+				code.WriteString(fmt.Sprintf("\t\"%s\"\n", path))
+			}
+		}
+		code.WriteString(")\n\n")
+	}
+
+	code.WriteString(strings.Join(cg.singleFileSections, "\n\n"))
+	code.WriteString("\n")
+
+	cg.singleFileSections = nil
+	cg.singleFileImports = nil
+
+	return cg.formatAndWriteFile(cg.config.GetOutputPath(cg.config.SingleFile), code.String())
+}
+
 func (cg *CodeGenerator) GenerateQueries(queries []Query) error {
 	if len(queries) == 0 {
 		return nil
@@ -598,9 +2792,10 @@ func (cg *CodeGenerator) generateQueryCode(sourceFile string, queries []Query) (
 	allImports := cg.getQueryImports(queries)
 
 	// Add standard imports
+	_, dbImport := cg.config.GetDBType()
 	standardImports := []string{
 		"context",
-		"github.com/nhalm/pgxkit",
+		dbImport,
 		"github.com/google/uuid",
 	}
 
@@ -614,7 +2809,15 @@ func (cg *CodeGenerator) generateQueryCode(sourceFile string, queries []Query) (
 	}
 
 	if hasPaginatedQueries {
-		standardImports = append(standardImports, "fmt", "encoding/base64")
+		standardImports = append(standardImports, "fmt")
+	}
+
+	// Batch queries build a pgx.Batch
+	for _, query := range queries {
+		if query.Type == QueryTypeBatch {
+			standardImports = append(standardImports, "github.com/jackc/pgx/v5")
+			break
+		}
 	}
 
 	// Combine and deduplicate imports
@@ -627,20 +2830,28 @@ func (cg *CodeGenerator) generateQueryCode(sourceFile string, queries []Query) (
 	code.WriteString(fmt.Sprintf("// Source: %s\n\n", sourceFile))
 
 	// Package declaration
-	code.WriteString(fmt.Sprintf("package %s\n\n", cg.config.PackageName))
+	code.WriteString(fmt.Sprintf("package %s\n\n", cg.config.GetPackageName()))
 
 	// Imports - include the predicted imports so imports.Process has them to work with
 	if len(allImports) > 0 {
 		code.WriteString("import (\n")
 		for _, imp := range allImports {
-			code.WriteString(fmt.Sprintf("\t\"%s\"\n", imp))
+			code.WriteString("\t" + cg.importSpec(imp) + "\n")
 		}
 		code.WriteString(")\n\n")
 	}
 
-	// Generate result structs for queries that need them
+	// Generate params structs for queries with several named parameters, then result structs
 	structsGenerated := make(map[string]bool)
 	for _, query := range queries {
+		if cg.needsParamsStruct(query) {
+			structCode, err := cg.generateQueryParamsStruct(query)
+			if err != nil {
+				return "", fmt.Errorf("failed to generate params struct for query %s: %w", query.Name, err)
+			}
+			code.WriteString(structCode)
+			code.WriteString("\n\n")
+		}
 		if cg.needsResultStruct(query) {
 			structName := cg.getQueryResultStructName(query)
 			if !structsGenerated[structName] {
@@ -758,12 +2969,58 @@ func validatePaginationParams(params PaginationParams) error {
 
 // needsResultStruct determines if a query needs a custom result struct
 func (cg *CodeGenerator) needsResultStruct(query Query) bool {
-	// Only SELECT queries (:one, :many, :paginated) need result structs
-	return query.Type == QueryTypeOne || query.Type == QueryTypeMany || query.Type == QueryTypePaginated
+	// Only queries returning rows (:one, :many, :paginated and :exec with RETURNING) need result structs,
+	// unless they reuse a table struct
+	if query.ResultStruct != "" {
+		return false
+	}
+	return query.Type == QueryTypeOne || query.Type == QueryTypeMany || query.Type == QueryTypePaginated ||
+		isExecReturning(query)
+}
+
+// isExecReturning reports whether an :exec query returns columns through RETURNING
+func isExecReturning(query Query) bool {
+	return query.Type == QueryTypeExec && len(query.Columns) > 0
+}
+
+// needsParamsStruct reports whether a query takes its parameters as a struct, which is done for
+// batch queries and when a query was written with more than one named parameter; other positional
+// queries keep one argument per $n
+func (cg *CodeGenerator) needsParamsStruct(query Query) bool {
+	if query.Type == QueryTypeBatch {
+		return len(query.Parameters) > 0
+	}
+	return query.NamedParameters && len(query.Parameters) > 1
+}
+
+// getQueryParamsStructName returns the struct name for a query's named parameters
+func (cg *CodeGenerator) getQueryParamsStructName(query Query) string {
+	return query.GoFunctionName() + "Params"
+}
+
+// generateQueryParamsStruct generates the struct holding a query's named parameters
+func (cg *CodeGenerator) generateQueryParamsStruct(query Query) (string, error) {
+	var fields []map[string]string
+	for _, param := range query.Parameters {
+		fields = append(fields, map[string]string{
+			"Name": toPascalCase(param.Name),
+			"Type": param.GoType,
+		})
+	}
+
+	data := map[string]interface{}{
+		"StructName": cg.getQueryParamsStructName(query),
+		"QueryName":  query.Name,
+		"Fields":     fields,
+	}
+	return cg.templateMgr.ExecuteTemplate(TemplateQueryParamsStruct, data)
 }
 
 // getQueryResultStructName returns the struct name for a query's result
 func (cg *CodeGenerator) getQueryResultStructName(query Query) string {
+	if query.ResultStruct != "" {
+		return query.ResultStruct
+	}
 	return query.GoFunctionName() + "Result"
 }
 
@@ -775,11 +3032,11 @@ func (cg *CodeGenerator) generateQueryResultStruct(query Query) (string, error)
 
 	// Prepare template data
 	data := struct {
-		StructName      string
-		QueryName       string
-		IDField         string
-		IDFieldIsPgtype bool
-		Fields          []struct {
+		StructName string
+		QueryName  string
+		IDField    string
+		IDValue    string
+		Fields     []struct {
 			Name string
 			Type string
 			Tag  string
@@ -804,8 +3061,10 @@ func (cg *CodeGenerator) generateQueryResultStruct(query Query) (string, error)
 
 		// Use the first UUID field as the ID field for pagination
 		if data.IDField == "" && col.IsUUID() {
-			data.IDField = col.GoFieldName()
-			data.IDFieldIsPgtype = col.GoType == "pgtype.UUID"
+			if value, ok := uuidValueExpr(col.GoType, "r."+col.GoFieldName()); ok {
+				data.IDField = col.GoFieldName()
+				data.IDValue = value
+			}
 		}
 	}
 
@@ -822,12 +3081,17 @@ func (cg *CodeGenerator) generateQueryRepository(sourceFile string, _ []Query) (
 	repositoryName := toPascalCase(baseName) + "Queries"
 
 	// Prepare template data
+	dbType, _ := cg.config.GetDBType()
 	data := struct {
 		RepositoryName string
 		SourceFile     string
+		QuerierPerCall bool
+		DBType         string
 	}{
 		RepositoryName: repositoryName,
 		SourceFile:     sourceFile,
+		QuerierPerCall: cg.config.QuerierPerCall,
+		DBType:         dbType,
 	}
 
 	// Execute template using template manager
@@ -842,7 +3106,15 @@ func (cg *CodeGenerator) generateQueryFunction(query Query) (string, error) {
 	case QueryTypeMany:
 		return cg.generateManyQueryFunction(query)
 	case QueryTypeExec:
+		if isExecReturning(query) {
+			// The statement may change any number of rows, so RETURNING rows come back like a :many query
+			return cg.generateManyQueryFunction(query)
+		}
 		return cg.generateExecQueryFunction(query)
+	case QueryTypeExecRows:
+		return cg.generateExecRowsQueryFunction(query)
+	case QueryTypeBatch:
+		return cg.generateBatchQueryFunction(query)
 	case QueryTypePaginated:
 		return cg.generatePaginatedQueryFunction(query)
 	default:
@@ -883,6 +3155,32 @@ func (cg *CodeGenerator) generateExecQueryFunction(query Query) (string, error)
 	return cg.templateMgr.ExecuteTemplate(TemplateQueryExec, data)
 }
 
+// generateExecRowsQueryFunction generates a function that executes and returns the number of rows affected
+func (cg *CodeGenerator) generateExecRowsQueryFunction(query Query) (string, error) {
+	data, err := cg.prepareQueryTemplateData(query)
+	if err != nil {
+		return "", err
+	}
+
+	// Execute template using template manager
+	return cg.templateMgr.ExecuteTemplate(TemplateQueryExecRows, data)
+}
+
+// generateBatchQueryFunction generates a function that queues the query once per params struct in a pgx.Batch
+func (cg *CodeGenerator) generateBatchQueryFunction(query Query) (string, error) {
+	if len(query.Parameters) == 0 {
+		return "", fmt.Errorf("batch query %s must take parameters", query.Name)
+	}
+
+	data, err := cg.prepareQueryTemplateData(query)
+	if err != nil {
+		return "", err
+	}
+
+	// Execute template using template manager
+	return cg.templateMgr.ExecuteTemplate(TemplateQueryBatch, data)
+}
+
 // generatePaginatedQueryFunction generates a function that returns paginated results
 func (cg *CodeGenerator) generatePaginatedQueryFunction(query Query) (string, error) {
 	data, err := cg.prepareQueryTemplateData(query)
@@ -890,6 +3188,28 @@ func (cg *CodeGenerator) generatePaginatedQueryFunction(query Query) (string, er
 		return "", err
 	}
 
+	// Pages are keyed on the column GetID returns: the first UUID column of the result
+	for _, col := range query.Columns {
+		if _, ok := uuidValueExpr(col.GoType, ""); col.IsUUID() && ok {
+			data["IDColumn"] = col.Name
+			break
+		}
+	}
+	if data["IDColumn"] == nil {
+		return "", fmt.Errorf("paginated query %s must select a UUID column to page by", query.Name)
+	}
+
+	// The query becomes a subquery, where a terminating semicolon is a syntax error
+	data["SQL"] = strings.TrimSuffix(strings.TrimSpace(query.SQL), ";")
+
+	// The cursor and limit placeholders follow the highest placeholder the query uses itself
+	paramCount := len(query.Parameters)
+	for _, param := range query.Parameters {
+		paramCount = max(paramCount, param.Index)
+	}
+	data["CursorParam"] = fmt.Sprintf("$%d", paramCount+1)
+	data["LimitParam"] = fmt.Sprintf("$%d", paramCount+2)
+
 	// Execute template using template manager
 	return cg.templateMgr.ExecuteTemplate(TemplateQueryPaginated, data)
 }
@@ -906,7 +3226,24 @@ func (cg *CodeGenerator) prepareQueryTemplateData(query Query) (map[string]inter
 	var paramDeclarations []string
 	var paramArgs []string
 
+	// The SQL already uses positional placeholders, so arguments are passed in parameter order
+	// whether they come from the function signature or a params struct; batch functions take
+	// a slice of params structs and queue each item
+	switch {
+	case query.Type == QueryTypeBatch:
+		paramDeclarations = append(paramDeclarations, "params []"+cg.getQueryParamsStructName(query))
+	case cg.needsParamsStruct(query):
+		paramDeclarations = append(paramDeclarations, "params "+cg.getQueryParamsStructName(query))
+	}
 	for _, param := range query.Parameters {
+		switch {
+		case query.Type == QueryTypeBatch:
+			paramArgs = append(paramArgs, "item."+toPascalCase(param.Name))
+			continue
+		case cg.needsParamsStruct(query):
+			paramArgs = append(paramArgs, "params."+toPascalCase(param.Name))
+			continue
+		}
 		paramDeclarations = append(paramDeclarations, fmt.Sprintf("%s %s", param.Name, param.GoType))
 		paramArgs = append(paramArgs, param.Name)
 	}
@@ -919,7 +3256,7 @@ func (cg *CodeGenerator) prepareQueryTemplateData(query Query) (map[string]inter
 
 	// Determine result type
 	resultType := cg.getQueryResultStructName(query)
-	if query.Type == QueryTypeExec {
+	if (query.Type == QueryTypeExec && !isExecReturning(query)) || query.Type == QueryTypeExecRows || query.Type == QueryTypeBatch {
 		resultType = "" // Exec queries don't return data
 	}
 
@@ -934,7 +3271,7 @@ func (cg *CodeGenerator) prepareQueryTemplateData(query Query) (map[string]inter
 		paramArgStr = ", " + strings.Join(paramArgs, ", ")
 	}
 
-	return map[string]interface{}{
+	data := map[string]interface{}{
 		"FunctionName":          query.GoFunctionName(),
 		"QueryName":             query.Name,
 		"RepositoryName":        repositoryName,
@@ -943,5 +3280,7 @@ func (cg *CodeGenerator) prepareQueryTemplateData(query Query) (map[string]inter
 		"ParameterDeclarations": paramDeclStr,
 		"ParameterArgs":         paramArgStr,
 		"ScanArgs":              strings.Join(scanArgs, ", "),
-	}, nil
+	}
+	cg.addExecutorData(data)
+	return data, nil
 }
@@ -1,10 +1,25 @@
 package generator
 
 import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
 	"strings"
 	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/nhalm/pgxkit"
 )
 
 // Test data for code generation tests
@@ -67,6 +82,53 @@ func TestCodeGenerator_prepareCRUDTemplateData(t *testing.T) {
 	}
 }
 
+func TestCodeGenerator_IdentityColumnExcludedFromCreate(t *testing.T) {
+	cg := NewCodeGenerator(getTestConfig())
+	table := getTestTable()
+	// An identity column has no column default, so only IsAutoGenerated keeps it out of Create
+	table.Columns = append(table.Columns, Column{
+		Name:            "sequence_number",
+		Type:            "integer",
+		IsAutoGenerated: true,
+	})
+	table = mapColumns(t, cg, table)
+
+	data, err := cg.prepareCRUDTemplateData(table)
+	if err != nil {
+		t.Fatalf("prepareCRUDTemplateData failed: %v", err)
+	}
+
+	for _, field := range data["CreateFields"].([]map[string]string) {
+		if field["Name"] == "SequenceNumber" {
+			t.Error("Sequence-backed column should not be a create field")
+		}
+	}
+	if strings.Contains(data["InsertColumns"].(string), "sequence_number") {
+		t.Errorf("Sequence-backed column should not be inserted: %s", data["InsertColumns"])
+	}
+	if !strings.Contains(data["SelectColumns"].(string), "sequence_number") {
+		t.Errorf("Sequence-backed column should still be selected: %s", data["SelectColumns"])
+	}
+
+	// The same holds for an identity primary key
+	table = Table{
+		Name:   "events",
+		Schema: "public",
+		Columns: []Column{
+			{Name: "id", Type: "integer", IsAutoGenerated: true},
+			{Name: "name", Type: "text"},
+		},
+		PrimaryKey: []string{"id"},
+	}
+	data, err = cg.prepareCRUDTemplateData(mapColumns(t, cg, table))
+	if err != nil {
+		t.Fatalf("prepareCRUDTemplateData failed: %v", err)
+	}
+	if data["InsertColumns"] != "name" {
+		t.Errorf("Identity primary key should not be inserted: %s", data["InsertColumns"])
+	}
+}
+
 func TestCodeGenerator_combineImports(t *testing.T) {
 	cg := NewCodeGenerator(getTestConfig())
 
@@ -129,3 +191,5184 @@ func TestCodeGenerator_GenerateTableRepository_Integration(t *testing.T) {
 		t.Error("Generated file seems too short")
 	}
 }
+
+func TestCodeGenerator_CloneDeepCopiesSlices(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"get", "clone"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	table.Columns = append(table.Columns,
+		Column{Name: "tags", Type: "text", IsArray: true},
+		Column{Name: "avatar", Type: "bytea", IsNullable: true},
+		Column{Name: "nickname", Type: "custom_text", IsNullable: true},
+	)
+	cg.typeMapper = NewTypeMapper(map[string]string{"custom_text": "names.Nickname"})
+	if err := cg.typeMapper.MapTableColumns(&table); err != nil {
+		t.Fatalf("MapTableColumns failed: %v", err)
+	}
+
+	code, err := cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"func (u Users) Clone() Users",
+		"clone := u",
+		// Slice fields get a fresh backing array
+		"clone.Tags = make([]string, len(u.Tags))",
+		"copy(clone.Tags, u.Tags)",
+		// Pointer-to-slice fields copy the pointee's backing array
+		"value := make(json.RawMessage, len(*u.Metadata))",
+		"value := make([]byte, len(*u.Avatar))",
+		// Plain pointer fields get a new pointer
+		"value := *u.Nickname",
+		"clone.Nickname = &value",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("Clone code missing %q\n%s", want, code)
+		}
+	}
+
+	// Clone must not be generated unless requested
+	cg.config.TableConfigs["users"] = TableConfig{Functions: []string{"get"}}
+	code, err = cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	if strings.Contains(code, "Clone()") {
+		t.Error("Clone should only be generated when the clone function is configured")
+	}
+}
+
+func TestCodeGenerator_CloneDeepCopiesMaps(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compile test in short mode")
+	}
+
+	tempDir := t.TempDir()
+	config := &Config{
+		OutputDir:          tempDir,
+		PackageName:        "testgen",
+		ColumnTypeMappings: map[string]string{"users.prefs": "map[string]any"},
+		TableConfigs: map[string]TableConfig{
+			"users": {Functions: []string{"get", "clone"}},
+		},
+	}
+	cg := NewCodeGenerator(config)
+	for _, generate := range []func() error{cg.GenerateSharedErrors, cg.GenerateSharedDatabaseOperations, cg.GenerateSharedRetryOperations} {
+		if err := generate(); err != nil {
+			t.Fatalf("shared file generation failed: %v", err)
+		}
+	}
+	table := getTestTable()
+	table.Columns = append(table.Columns,
+		Column{Name: "attributes", Type: "hstore", IsNullable: true},
+		Column{Name: "prefs", Type: "jsonb"},
+	)
+	if err := cg.GenerateTableRepository(table); err != nil {
+		t.Fatalf("GenerateTableRepository failed: %v", err)
+	}
+	if !compileGeneratedCode(t, tempDir) {
+		return
+	}
+
+	// Changing the clone's maps, including an hstore value behind its pointer, must leave the original alone
+	testContent := `package testgen
+
+import "testing"
+
+func TestCloneMaps(t *testing.T) {
+	color := "red"
+	original := Users{Attributes: map[string]*string{"color": &color}, Prefs: map[string]any{"theme": "dark"}}
+
+	clone := original.Clone()
+	*clone.Attributes["color"] = "blue"
+	clone.Attributes["size"] = nil
+	clone.Prefs["theme"] = "light"
+
+	if *original.Attributes["color"] != "red" || len(original.Attributes) != 1 {
+		t.Errorf("original hstore changed through the clone: %v", original.Attributes)
+	}
+	if original.Prefs["theme"] != "dark" {
+		t.Errorf("original map changed through the clone: %v", original.Prefs)
+	}
+
+	if empty := (Users{}).Clone(); empty.Attributes != nil || empty.Prefs != nil {
+		t.Error("Clone should keep nil maps nil")
+	}
+}
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "clone_test.go"), []byte(testContent), 0644); err != nil {
+		t.Fatalf("Failed to write generated package test: %v", err)
+	}
+
+	testCmd := exec.Command("go", "test", "./...")
+	testCmd.Dir = tempDir
+	testCmd.Env = append(os.Environ(), "GO111MODULE=on")
+	if output, err := testCmd.CombinedOutput(); err != nil {
+		t.Errorf("Generated Clone test failed: %v\nOutput: %s", err, string(output))
+	}
+}
+
+func TestCodeGenerator_Diff(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"diff"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	table.Columns = append(table.Columns,
+		Column{Name: "updated_at", Type: "timestamptz"},
+		Column{Name: "tags", Type: "text", IsArray: true},
+	)
+	code, err := cg.generateCRUDOperations(mapColumns(t, cg, table))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"func (u Users) Diff(other Users) map[string]any",
+		// Keys are column names and values come from the other version
+		"if u.Name != other.Name {\n\t\tchanges[\"name\"] = other.Name\n\t}",
+		"if u.IsActive != other.IsActive {\n\t\tchanges[\"is_active\"] = other.IsActive\n\t}",
+		"if !u.UpdatedAt.Equal(other.UpdatedAt) {\n\t\tchanges[\"updated_at\"] = other.UpdatedAt\n\t}",
+		"if !reflect.DeepEqual(u.Tags, other.Tags) {\n\t\tchanges[\"tags\"] = other.Tags\n\t}",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("Diff code missing %q\n%s", want, code)
+		}
+	}
+}
+
+func TestCodeGenerator_GenerateRoundtripTests(t *testing.T) {
+	config := getTestConfigWithTempDir(t)
+	table := getTestTable()
+
+	// Without the option only the repository file is written
+	if err := NewCodeGenerator(config).GenerateTableRepository(table); err != nil {
+		t.Fatalf("GenerateTableRepository failed: %v", err)
+	}
+	testFilename := filepath.Join(config.OutputDir, "users_generated_test.go")
+	if _, err := os.Stat(testFilename); !os.IsNotExist(err) {
+		t.Fatal("Round-trip test file should not be generated unless enabled")
+	}
+
+	config.GenerateRoundtripTests = true
+	if err := NewCodeGenerator(config).GenerateTableRepository(table); err != nil {
+		t.Fatalf("GenerateTableRepository failed: %v", err)
+	}
+
+	content, err := os.ReadFile(testFilename)
+	if err != nil {
+		t.Fatalf("Failed to read generated round-trip test: %v", err)
+	}
+
+	expected := []string{
+		"package repositories",
+		"func TestUsersJSONRoundtrip(t *testing.T)",
+		"original := Users{",
+		`Name:      "test",`,
+		"IsActive:  pgtype.Bool{Bool: true, Valid: true},",
+		`expectedFields := []string{"id", "name", "email", "is_active", "created_at", "metadata"}`,
+		"var decoded Users",
+		"json.Unmarshal(data, &decoded)",
+	}
+	for _, want := range expected {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("Round-trip test missing %q\n%s", want, content)
+		}
+	}
+}
+
+func TestCodeGenerator_TruncateForTesting(t *testing.T) {
+	config := getTestConfig()
+	cg := NewCodeGenerator(config)
+	table := mapColumns(t, cg, getTestTable())
+
+	code, err := cg.generateTableCode(table)
+	if err != nil {
+		t.Fatalf("generateTableCode failed: %v", err)
+	}
+	if strings.Contains(code, "TruncateForTesting") || strings.Contains(code, "TRUNCATE") {
+		t.Error("TruncateForTesting must not be generated unless test helpers are enabled")
+	}
+
+	config.GenerateTestHelpers = true
+	code, err = cg.generateTableCode(table)
+	if err != nil {
+		t.Fatalf("generateTableCode failed: %v", err)
+	}
+
+	expected := []string{
+		"// TruncateForTesting DELETES EVERY ROW in users and every table referencing it.",
+		"// WARNING: FOR TEST SETUP AND TEARDOWN ONLY.",
+		"func (r *UsersRepository) TruncateForTesting(ctx context.Context) error",
+		"TRUNCATE users RESTART IDENTITY CASCADE",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code missing %q\n%s", want, code)
+		}
+	}
+}
+
+func TestCodeGenerator_HeadOrdersByPrimaryKey(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"head"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	if err := cg.typeMapper.MapTableColumns(&table); err != nil {
+		t.Fatalf("MapTableColumns failed: %v", err)
+	}
+
+	code, err := cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"func (r *UsersRepository) Head(ctx context.Context, n int32) ([]Users, error)",
+		"ORDER BY id ASC\n\t\tLIMIT $1",
+		`ExecuteQuery(ctx, r.db, "head", "Users", query, n)`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("Head code missing %q\n%s", want, code)
+		}
+	}
+}
+
+func TestCodeGenerator_IntervalQueryParameters(t *testing.T) {
+	cg := NewCodeGenerator(getTestConfig())
+
+	var queries []Query
+	for _, queryType := range []QueryType{QueryTypeOne, QueryTypeMany, QueryTypeExec, QueryTypePaginated} {
+		query := Query{
+			Name:       "Users" + toPascalCase(string(queryType)) + "Within",
+			SQL:        "SELECT id FROM users WHERE created_at > NOW() - $1",
+			Type:       queryType,
+			SourceFile: "queries/users.sql",
+			Parameters: []Parameter{{Name: "param1", Type: "interval", Index: 1}},
+		}
+		if queryType != QueryTypeExec {
+			query.Columns = []Column{{Name: "id", Type: "uuid"}}
+		}
+		if err := cg.typeMapper.MapQueryColumns(&query); err != nil {
+			t.Fatalf("MapQueryColumns failed: %v", err)
+		}
+		queries = append(queries, query)
+	}
+
+	code, err := cg.generateQueryCode("queries/users.sql", queries)
+	if err != nil {
+		t.Fatalf("generateQueryCode failed: %v", err)
+	}
+
+	if !strings.Contains(code, "\t\"time\"\n") {
+		t.Error("Expected time import for interval parameters")
+	}
+	for _, query := range queries {
+		want := fmt.Sprintf("%s(ctx context.Context, param1 time.Duration", query.GoFunctionName())
+		if !strings.Contains(code, want) {
+			t.Errorf("Expected %s query to take a time.Duration parameter (%q)\n%s", query.Type, want, code)
+		}
+	}
+}
+
+func TestCodeGenerator_PaginatedQueryParameters(t *testing.T) {
+	cg := NewCodeGenerator(getTestConfig())
+
+	query := Query{
+		Name:       "ListUsersByStatus",
+		SQL:        "SELECT id, name FROM users WHERE status = $1 AND id <> ALL($2);",
+		Type:       QueryTypePaginated,
+		SourceFile: "queries/users.sql",
+		Parameters: []Parameter{{Name: "status", Type: "text", Index: 1}, {Name: "excluded", Type: "uuid[]", Index: 2}},
+		Columns:    []Column{{Name: "id", Type: "uuid"}, {Name: "name", Type: "text"}},
+	}
+	if err := cg.typeMapper.MapQueryColumns(&query); err != nil {
+		t.Fatalf("MapQueryColumns failed: %v", err)
+	}
+
+	code, err := cg.generatePaginatedQueryFunction(query)
+	if err != nil {
+		t.Fatalf("generatePaginatedQueryFunction failed: %v", err)
+	}
+
+	expected := []string{
+		"ListUsersByStatus(ctx context.Context, status string, excluded []uuid.UUID, cursor string, limit int) (*PaginationResult[ListUsersByStatusResult], error)",
+		// The query's own parameters keep $1 and $2; the cursor and limit follow them
+		"SELECT id, name FROM users WHERE status = $1 AND id <> ALL($2)\n\t\t) AS paginated\n",
+		"WHERE ($3::uuid IS NULL OR paginated.id > $3)",
+		"LIMIT $4",
+		// Slices bind as a single argument, in placeholder order
+		`query, status, excluded, afterID, int32(limit+1))`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("Paginated query code missing %q\n%s", want, code)
+		}
+	}
+
+	// Without a UUID column there is nothing to page by
+	query.Columns = []Column{{Name: "name", Type: "text", GoType: "string"}}
+	if _, err := cg.generatePaginatedQueryFunction(query); err == nil || !strings.Contains(err.Error(), "UUID column") {
+		t.Errorf("Expected missing UUID column error, got %v", err)
+	}
+}
+
+func TestCodeGenerator_ArrayQueryResult(t *testing.T) {
+	cg := NewCodeGenerator(getTestConfig())
+
+	columns, err := NewQueryAnalyzer(nil, nil).columnsFromFields([]pgconn.FieldDescription{
+		{Name: "id", DataTypeOID: 2950},
+		{Name: "tags", DataTypeOID: 1009},
+		{Name: "reviewer_ids", DataTypeOID: 2951},
+	}, nil)
+	if err != nil {
+		t.Fatalf("columnsFromFields failed: %v", err)
+	}
+	query := Query{
+		Name:       "GetPostTags",
+		SQL:        "SELECT id, tags, reviewer_ids FROM posts WHERE id = $1",
+		Type:       QueryTypeOne,
+		SourceFile: "queries/posts.sql",
+		Parameters: []Parameter{{Name: "id", Type: "uuid", Index: 1}},
+		Columns:    columns,
+	}
+	if err := cg.typeMapper.MapQueryColumns(&query); err != nil {
+		t.Fatalf("MapQueryColumns failed: %v", err)
+	}
+
+	code, err := cg.generateQueryCode("queries/posts.sql", []Query{query})
+	if err != nil {
+		t.Fatalf("generateQueryCode failed: %v", err)
+	}
+
+	for _, want := range []string{"Tags []string", "ReviewerIds []uuid.UUID"} {
+		if !strings.Contains(strings.Join(strings.Fields(code), " "), want) {
+			t.Errorf("Expected result struct field %q\n%s", want, code)
+		}
+	}
+}
+
+func TestCodeGenerator_ExecRowsQuery(t *testing.T) {
+	cg := NewCodeGenerator(getTestConfig())
+
+	query := Query{
+		Name:       "DeactivateUsers",
+		SQL:        "UPDATE users SET is_active = false WHERE last_login < $1 RETURNING id",
+		Type:       QueryTypeExecRows,
+		SourceFile: "queries/users.sql",
+		Parameters: []Parameter{{Name: "param1", Type: "timestamptz", Index: 1}},
+	}
+	if err := cg.typeMapper.MapQueryColumns(&query); err != nil {
+		t.Fatalf("MapQueryColumns failed: %v", err)
+	}
+
+	code, err := cg.generateQueryCode("queries/users.sql", []Query{query})
+	if err != nil {
+		t.Fatalf("generateQueryCode failed: %v", err)
+	}
+
+	expected := []string{
+		"// DeactivateUsers executes the DeactivateUsers query and returns the number of rows affected",
+		"DeactivateUsers(ctx context.Context, param1 time.Time) (int64, error)",
+		`return ExecuteNonQueryWithRowsAffected(ctx, r.db, "DeactivateUsers", "DeactivateUsers", query, param1)`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("Execrows query code missing %q\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "DeactivateUsersResult") {
+		t.Errorf("Expected no result struct for an :execrows query\n%s", code)
+	}
+}
+
+func TestCodeGenerator_BatchQuery(t *testing.T) {
+	tempDir := t.TempDir()
+	config := &Config{OutputDir: tempDir, PackageName: "testgen"}
+	cg := NewCodeGenerator(config)
+
+	query := Query{
+		Name:       "InsertEvents",
+		SQL:        "INSERT INTO events (kind, weight) VALUES ($1, $2)",
+		Type:       QueryTypeBatch,
+		SourceFile: "queries/events.sql",
+		Parameters: []Parameter{{Name: "param1", Type: "text", Index: 1}, {Name: "param2", Type: "integer", Index: 2}},
+	}
+	if err := cg.typeMapper.MapQueryColumns(&query); err != nil {
+		t.Fatalf("MapQueryColumns failed: %v", err)
+	}
+
+	code, err := cg.generateQueryCode("queries/events.sql", []Query{query})
+	if err != nil {
+		t.Fatalf("generateQueryCode failed: %v", err)
+	}
+	flat := strings.Join(strings.Fields(code), " ")
+	expected := []string{
+		"type InsertEventsParams struct { Param1 string Param2 int32 }",
+		"InsertEvents(ctx context.Context, params []InsertEventsParams) error",
+		"batch.Queue(query, item.Param1, item.Param2)",
+		`return ExecuteBatch(ctx, r.db, "InsertEvents", "InsertEvents", batch)`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(flat, want) {
+			t.Errorf("Batch query code missing %q\n%s", want, code)
+		}
+	}
+
+	// A batch of statements without parameters would queue the same statement over and over
+	if _, err := cg.generateBatchQueryFunction(Query{Name: "TouchEvents", Type: QueryTypeBatch}); err == nil || !strings.Contains(err.Error(), "must take parameters") {
+		t.Errorf("Expected missing parameters error, got %v", err)
+	}
+
+	if testing.Short() {
+		return
+	}
+
+	for _, generate := range []func() error{cg.GenerateSharedErrors, cg.GenerateSharedDatabaseOperations, cg.GenerateSharedRetryOperations} {
+		if err := generate(); err != nil {
+			t.Fatalf("shared file generation failed: %v", err)
+		}
+	}
+	if err := cg.GenerateQueries([]Query{query}); err != nil {
+		t.Fatalf("GenerateQueries failed: %v", err)
+	}
+	if !compileGeneratedCode(t, tempDir) {
+		return
+	}
+
+	// fakeConn sends batches directly like a pgx.Tx; fakeDB can only begin transactions like a *pgxkit.DB
+	testContent := `package testgen
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type fakeResults struct {
+	pgx.BatchResults
+	read   int
+	failAt int
+}
+
+func (r *fakeResults) Exec() (pgconn.CommandTag, error) {
+	defer func() { r.read++ }()
+	if r.read == r.failAt {
+		return pgconn.CommandTag{}, errors.New("duplicate key")
+	}
+	return pgconn.NewCommandTag("INSERT 0 1"), nil
+}
+
+func (r *fakeResults) Close() error { return nil }
+
+type fakeConn struct {
+	pgx.Tx
+	batches    []*pgx.Batch
+	failAt     int
+	committed  bool
+	rolledBack bool
+}
+
+func (c *fakeConn) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	c.batches = append(c.batches, b)
+	return &fakeResults{failAt: c.failAt}
+}
+
+func (c *fakeConn) Commit(ctx context.Context) error {
+	c.committed = true
+	return nil
+}
+
+func (c *fakeConn) Rollback(ctx context.Context) error {
+	c.rolledBack = !c.committed
+	return nil
+}
+
+type fakeDB struct {
+	Querier
+	tx *fakeConn
+}
+
+func (db *fakeDB) BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	return db.tx, nil
+}
+
+func TestInsertEventsBatch(t *testing.T) {
+	ctx := context.Background()
+	conn := &fakeConn{failAt: -1}
+	events := NewEventsQueries(nil).WithTx(conn)
+
+	if err := events.InsertEvents(ctx, nil); err != nil || len(conn.batches) != 0 {
+		t.Fatalf("empty batch sent %d batches, err %v", len(conn.batches), err)
+	}
+
+	params := []InsertEventsParams{{Param1: "signup", Param2: 1}, {Param1: "login", Param2: 2}, {Param1: "logout", Param2: 3}}
+	if err := events.InsertEvents(ctx, params); err != nil {
+		t.Fatalf("InsertEvents failed: %v", err)
+	}
+	if len(conn.batches) != 1 || conn.batches[0].Len() != 3 {
+		t.Fatalf("expected one batch of 3 statements, got %d batches", len(conn.batches))
+	}
+	if args := conn.batches[0].QueuedQueries[1].Arguments; args[0] != "login" || args[1] != int32(2) {
+		t.Errorf("second statement bound %v, want [login 2]", args)
+	}
+
+	conn.failAt = 1
+	err := events.InsertEvents(ctx, params)
+	if err == nil || !strings.Contains(err.Error(), "batch item 1") {
+		t.Errorf("expected the failing item's index in the error, got %v", err)
+	}
+}
+
+func TestExecuteBatchBeginsTransaction(t *testing.T) {
+	ctx := context.Background()
+	batch := &pgx.Batch{}
+	batch.Queue("INSERT INTO events (kind) VALUES ($1)", "signup")
+
+	tx := &fakeConn{failAt: -1}
+	if err := ExecuteBatch(ctx, &fakeDB{tx: tx}, "insert", "events", batch); err != nil {
+		t.Fatalf("ExecuteBatch failed: %v", err)
+	}
+	if len(tx.batches) != 1 || !tx.committed {
+		t.Errorf("expected the batch to run in a committed transaction (batches %d, committed %v)", len(tx.batches), tx.committed)
+	}
+
+	tx = &fakeConn{failAt: 0}
+	if err := ExecuteBatch(ctx, &fakeDB{tx: tx}, "insert", "events", batch); err == nil {
+		t.Fatal("expected ExecuteBatch to fail")
+	}
+	if tx.committed || !tx.rolledBack {
+		t.Errorf("failed batch was not rolled back (committed %v, rolled back %v)", tx.committed, tx.rolledBack)
+	}
+}
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "batch_test.go"), []byte(testContent), 0644); err != nil {
+		t.Fatalf("Failed to write generated package test: %v", err)
+	}
+
+	testCmd := exec.Command("go", "test", "./...")
+	testCmd.Dir = tempDir
+	testCmd.Env = append(os.Environ(), "GO111MODULE=on")
+	if output, err := testCmd.CombinedOutput(); err != nil {
+		t.Errorf("Generated batch test failed: %v\nOutput: %s", err, string(output))
+	}
+}
+
+func TestCodeGenerator_NamedQueryParameters(t *testing.T) {
+	cg := NewCodeGenerator(getTestConfig())
+
+	query := Query{
+		Name:            "GetUsersByStatus",
+		SQL:             "SELECT id, name FROM users WHERE status = $1 AND created_at > $2",
+		Type:            QueryTypeMany,
+		SourceFile:      "queries/users.sql",
+		Parameters:      []Parameter{{Name: "status", Type: "text", Index: 1}, {Name: "createdAfter", Type: "timestamptz", Index: 2}},
+		Columns:         []Column{{Name: "id", Type: "uuid"}, {Name: "name", Type: "text"}},
+		NamedParameters: true,
+	}
+	single := Query{
+		Name:            "GetUserByEmail",
+		SQL:             "SELECT id, name FROM users WHERE email = $1",
+		Type:            QueryTypeOne,
+		SourceFile:      "queries/users.sql",
+		Parameters:      []Parameter{{Name: "email", Type: "text", Index: 1}},
+		Columns:         []Column{{Name: "id", Type: "uuid"}, {Name: "name", Type: "text"}},
+		NamedParameters: true,
+	}
+	paginated := query
+	paginated.Name = "ListUsersByStatus"
+	paginated.Type = QueryTypePaginated
+
+	queries := []Query{query, single, paginated}
+	for i := range queries {
+		if err := cg.typeMapper.MapQueryColumns(&queries[i]); err != nil {
+			t.Fatalf("MapQueryColumns failed: %v", err)
+		}
+	}
+
+	code, err := cg.generateQueryCode("queries/users.sql", queries)
+	if err != nil {
+		t.Fatalf("generateQueryCode failed: %v", err)
+	}
+	code = strings.Join(strings.Fields(code), " ")
+
+	expected := []string{
+		"type GetUsersByStatusParams struct { Status string CreatedAfter time.Time }",
+		"GetUsersByStatus(ctx context.Context, params GetUsersByStatusParams) ([]GetUsersByStatusResult, error)",
+		`query, params.Status, params.CreatedAfter)`,
+		// A single named parameter stays a plain argument
+		"GetUserByEmail(ctx context.Context, email string) (*GetUserByEmailResult, error)",
+		"ListUsersByStatus(ctx context.Context, params ListUsersByStatusParams, cursor string, limit int)",
+		`query, params.Status, params.CreatedAfter, afterID, int32(limit+1))`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("Query code missing %q\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "GetUserByEmailParams") {
+		t.Error("Expected no params struct for a single named parameter")
+	}
+}
+
+func TestCodeGenerator_QueryReusesTableStruct(t *testing.T) {
+	cg := NewCodeGenerator(getTestConfig())
+
+	query := Query{
+		Name:         "GetUserByEmail",
+		SQL:          "SELECT * FROM users WHERE email = $1",
+		Type:         QueryTypeOne,
+		SourceFile:   "queries/users.sql",
+		Parameters:   []Parameter{{Name: "email", Type: "text", GoType: "string", Index: 1}},
+		Columns:      getTestTable().Columns,
+		ResultStruct: "Users",
+	}
+
+	code, err := cg.generateQueryCode("queries/users.sql", []Query{query})
+	if err != nil {
+		t.Fatalf("generateQueryCode failed: %v", err)
+	}
+
+	if strings.Contains(code, "GetUserByEmailResult") {
+		t.Errorf("Expected no dedicated result struct when reusing Users\n%s", code)
+	}
+	if !strings.Contains(code, "GetUserByEmail(ctx context.Context, email string) (*Users, error)") {
+		t.Errorf("Expected GetUserByEmail to return the Users table struct\n%s", code)
+	}
+}
+
+func TestCodeGenerator_QueryRepositorySharesConnection(t *testing.T) {
+	cg := NewCodeGenerator(getTestConfig())
+
+	tableCode, err := cg.generateRepository(mapColumns(t, cg, getTestTable()))
+	if err != nil {
+		t.Fatalf("generateRepository failed: %v", err)
+	}
+	queryCode, err := cg.generateQueryCode("queries/users.sql", []Query{{
+		Name:       "ListActiveUsers",
+		SQL:        "SELECT id, name FROM users WHERE is_active",
+		Type:       QueryTypeMany,
+		SourceFile: "queries/users.sql",
+		Columns:    []Column{{Name: "id", Type: "uuid", GoType: "uuid.UUID"}, {Name: "name", Type: "text", GoType: "string"}},
+	}})
+	if err != nil {
+		t.Fatalf("generateQueryCode failed: %v", err)
+	}
+
+	// Both kinds of repository are built from the same connection and run through the shared helpers
+	if !strings.Contains(tableCode, "func NewUsersRepository(db *pgxkit.DB) *UsersRepository {") {
+		t.Errorf("table repository should take a *pgxkit.DB\n%s", tableCode)
+	}
+	expected := []string{
+		"db Querier\n",
+		"func NewUsersQueries(db *pgxkit.DB) *UsersQueries {",
+		`ExecuteQuery(ctx, r.db, "ListActiveUsers", "ListActiveUsersResult", query)`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(queryCode, want) {
+			t.Errorf("query repository code missing %q\n%s", want, queryCode)
+		}
+	}
+	if strings.Contains(queryCode, "pgxpool") {
+		t.Errorf("query repository should not depend on pgxpool\n%s", queryCode)
+	}
+}
+
+func TestCodeGenerator_PluralizedComments(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"comments":   {Functions: []string{"list", "paginate"}},
+		"categories": {Functions: []string{"list"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	tests := []struct {
+		tableName string
+		want      string
+	}{
+		{"comments", "// List retrieves all Comments\n"},
+		{"categories", "// List retrieves all Categories\n"},
+		{"kpis", "// List retrieves all Kpis\n"},
+		{"apis", "// List retrieves all Apis\n"},
+		{"taxis", "// List retrieves all Taxis\n"},
+		{"emojis", "// List retrieves all Emojis\n"},
+		{"menus", "// List retrieves all Menus\n"},
+	}
+	for _, tt := range tests {
+		table := getTestTable()
+		table.Name = tt.tableName
+		code, err := cg.generateCRUDOperations(mapColumns(t, cg, table))
+		if err != nil {
+			t.Fatalf("generateCRUDOperations failed: %v", err)
+		}
+		if !strings.Contains(code, tt.want) {
+			t.Errorf("Expected %q in generated code\n%s", tt.want, code)
+		}
+		if strings.Contains(code, "Commentss") || strings.Contains(code, "Categoriess") || strings.Contains(code, "Menuses") {
+			t.Errorf("Generated code contains a double plural\n%s", code)
+		}
+		if strings.Contains(code, "Kpes") || strings.Contains(code, "Apes") || strings.Contains(code, "Taxes") || strings.Contains(code, "Emojes") {
+			t.Errorf("Generated code re-pluralizes a plural -is word\n%s", code)
+		}
+	}
+}
+
+func TestCodeGenerator_ReturningQueries(t *testing.T) {
+	cg := NewCodeGenerator(getTestConfig())
+
+	queries := []Query{
+		{
+			Name:       "CreateUser",
+			SQL:        "INSERT INTO users (name) VALUES ($1) RETURNING id",
+			Type:       QueryTypeOne,
+			SourceFile: "queries/users.sql",
+			Parameters: []Parameter{{Name: "name", Type: "text", GoType: "string", Index: 1}},
+			Columns:    []Column{{Name: "id", Type: "uuid", GoType: "pgtype.UUID", IsNullable: true}},
+		},
+		{
+			Name:       "DeactivateUsers",
+			SQL:        "UPDATE users SET is_active = false WHERE last_login < $1 RETURNING id",
+			Type:       QueryTypeMany,
+			SourceFile: "queries/users.sql",
+			Parameters: []Parameter{{Name: "lastLogin", Type: "timestamptz", GoType: "time.Time", Index: 1}},
+			Columns:    []Column{{Name: "id", Type: "uuid", GoType: "pgtype.UUID", IsNullable: true}},
+		},
+		{
+			Name:       "InsertUser",
+			SQL:        "INSERT INTO users (name) VALUES ($1) RETURNING id",
+			Type:       QueryTypeExec,
+			SourceFile: "queries/users.sql",
+			Parameters: []Parameter{{Name: "name", Type: "text", GoType: "string", Index: 1}},
+			Columns:    []Column{{Name: "id", Type: "uuid", GoType: "pgtype.UUID", IsNullable: true}},
+		},
+		{
+			Name:       "DeleteUser",
+			SQL:        "DELETE FROM users WHERE id = $1",
+			Type:       QueryTypeExec,
+			SourceFile: "queries/users.sql",
+			Parameters: []Parameter{{Name: "id", Type: "uuid", GoType: "uuid.UUID", Index: 1}},
+		},
+	}
+
+	code, err := cg.generateQueryCode("queries/users.sql", queries)
+	if err != nil {
+		t.Fatalf("generateQueryCode failed: %v", err)
+	}
+
+	expected := []string{
+		"type CreateUserResult struct",
+		"func (r *UsersQueries) CreateUser(ctx context.Context, name string) (*CreateUserResult, error)",
+		"row.Scan(&result.Id)",
+		// Every row a multi-row statement changes is returned
+		"func (r *UsersQueries) DeactivateUsers(ctx context.Context, lastLogin time.Time) ([]DeactivateUsersResult, error)",
+		// An :exec statement with RETURNING returns its rows without guessing how many there are
+		"type InsertUserResult struct",
+		"func (r *UsersQueries) InsertUser(ctx context.Context, name string) ([]InsertUserResult, error)",
+		"func (r *UsersQueries) DeleteUser(ctx context.Context, id uuid.UUID) error",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code missing %q\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "DeleteUserResult") {
+		t.Error("Exec queries without RETURNING should not get a result struct")
+	}
+}
+
+func TestCodeGenerator_GetRandom(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"random"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	if err := cg.typeMapper.MapTableColumns(&table); err != nil {
+		t.Fatalf("MapTableColumns failed: %v", err)
+	}
+
+	code, err := cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"func (r *UsersRepository) GetRandom(ctx context.Context) (*Users, error)",
+		"FROM users\n\t\tORDER BY random()\n\t\tLIMIT 1",
+		// The cost of sorting the whole table is documented on the method
+		"avoid calling it on large tables",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("GetRandom code missing %q\n%s", want, code)
+		}
+	}
+}
+
+func TestCodeGenerator_SingleFileOutput(t *testing.T) {
+	config := getTestConfigWithTempDir(t)
+	config.SingleFile = "models_generated.go"
+	config.GenerateRoundtripTests = true
+	cg := NewCodeGenerator(config)
+
+	users := getTestTable()
+	posts := getTestTable()
+	posts.Name = "posts"
+
+	for _, generate := range []func() error{
+		cg.GenerateSharedPaginationTypes,
+		cg.GenerateSharedErrors,
+		cg.GenerateSharedDatabaseOperations,
+		cg.GenerateSharedRetryOperations,
+		func() error { return cg.GenerateTableRepository(users) },
+		func() error { return cg.GenerateTableRepository(posts) },
+		cg.WriteSingleFile,
+	} {
+		if err := generate(); err != nil {
+			t.Fatalf("Generation failed: %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(config.OutputDir)
+	if err != nil {
+		t.Fatalf("Failed to read output directory: %v", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		files = append(files, entry.Name())
+	}
+	// Test files cannot be merged into the package file and stay separate
+	expectedFiles := []string{"models_generated.go", "posts_generated_test.go", "users_generated_test.go"}
+	if !stringSlicesEqual(files, expectedFiles) {
+		t.Errorf("Generated files = %v, want %v", files, expectedFiles)
+	}
+
+	content, err := os.ReadFile(filepath.Join(config.OutputDir, "models_generated.go"))
+	if err != nil {
+		t.Fatalf("Failed to read single file: %v", err)
+	}
+	code := string(content)
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "models_generated.go", content, 0); err != nil {
+		t.Fatalf("Single file does not parse: %v", err)
+	}
+	if count := strings.Count(code, "\nimport ("); count != 1 {
+		t.Errorf("Expected exactly one import block, found %d", count)
+	}
+	if count := strings.Count(code, "package repositories"); count != 1 {
+		t.Errorf("Expected exactly one package clause, found %d", count)
+	}
+	for _, want := range []string{
+		"type Users struct",
+		"type Posts struct",
+		"func NewUsersRepository(",
+		"func NewPostsRepository(",
+		"type PaginationParams struct",
+		"func ExecuteQuery(",
+		"func RetryOperation[",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Single file missing %q", want)
+		}
+	}
+}
+
+func TestCodeGenerator_BoolArrayColumns(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"list"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	table.Columns = append(table.Columns,
+		Column{Name: "flags", Type: "bool", IsArray: true},
+		Column{Name: "optional_flags", Type: "bool", IsArray: true, IsNullable: true},
+	)
+
+	code, err := cg.generateTableCode(mapColumns(t, cg, table))
+	if err != nil {
+		t.Fatalf("generateTableCode failed: %v", err)
+	}
+
+	expected := []string{
+		"Flags []bool `json:\"flags\" db:\"flags\"`",
+		"OptionalFlags []pgtype.Bool `json:\"optional_flags\" db:\"optional_flags\"`",
+		// The List loop scans directly into the array fields
+		"&u.Flags, &u.OptionalFlags)",
+		"\"github.com/jackc/pgx/v5/pgtype\"",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code missing %q\n%s", want, code)
+		}
+	}
+}
+
+// mapColumns maps a table's column types for tests that generate code directly
+func mapColumns(t *testing.T, cg *CodeGenerator, table Table) Table {
+	t.Helper()
+	if err := cg.typeMapper.MapTableColumns(&table); err != nil {
+		t.Fatalf("MapTableColumns failed: %v", err)
+	}
+	return table
+}
+
+func TestCodeGenerator_ListModifiedSince(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"modified_since"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	table.Columns = append(table.Columns, Column{Name: "updated_at", Type: "timestamptz", IsNullable: true})
+	table.Indexes = []Index{{Name: "idx_users_updated_at", Columns: []string{"updated_at", "id"}}}
+	table = mapColumns(t, cg, table)
+
+	code, err := cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"func (r *UsersRepository) ListModifiedSince(ctx context.Context, since time.Time, params PaginationParams) (*PaginationResult[Users], error)",
+		"WHERE updated_at >= $1\n\t\t  AND ($2::timestamptz IS NULL OR (updated_at, id) > ($2, $3))",
+		"ORDER BY updated_at ASC, id ASC\n\t\tLIMIT $4",
+		`ExecuteQuery(ctx, r.db, "list_modified_since", "Users", query, since, cursorTime, cursorID, int32(limit+1))`,
+		// Nullable timestamps are read through pgtype.Timestamptz
+		"encodeTimeCursor(lastItem.UpdatedAt.Time, lastItem.GetID())",
+		// A keyset sync feed has no total to report
+		"if params.IncludeTotal {\n\t\treturn nil, fmt.Errorf(\"ListModifiedSince does not support IncludeTotal\")\n\t}",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("ListModifiedSince code missing %q\n%s", want, code)
+		}
+	}
+
+	// The column is configurable per table
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"modified_since"}, ModifiedColumn: "created_at"}
+	table.Indexes = append(table.Indexes, Index{Name: "idx_users_created_at", Columns: []string{"created_at"}})
+	code, err = cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	if !strings.Contains(code, "ORDER BY created_at ASC, id ASC") || !strings.Contains(code, "lastItem.CreatedAt,") {
+		t.Errorf("Expected ListModifiedSince to use created_at\n%s", code)
+	}
+
+	// An unindexed column is rejected
+	table.Indexes = nil
+	if _, err := cg.generateCRUDOperations(table); err == nil || !strings.Contains(err.Error(), "requires an index on users.created_at") {
+		t.Errorf("Expected missing index error, got %v", err)
+	}
+
+	// The cursor is cast to the column's own type, so timestamp without time zone skips the session time zone
+	table.Columns = append(table.Columns, Column{Name: "synced_at", Type: "timestamp", IsNullable: false})
+	table.Indexes = append(table.Indexes, Index{Name: "idx_users_synced_at", Columns: []string{"synced_at"}})
+	table = mapColumns(t, cg, table)
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"modified_since"}, ModifiedColumn: "synced_at"}
+	code, err = cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	if !strings.Contains(code, "($2::timestamp IS NULL OR (synced_at, id) > ($2, $3))") {
+		t.Errorf("Expected the cursor cast to timestamp\n%s", code)
+	}
+}
+
+func TestCodeGenerator_StreamPaginated(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"stream"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	code, err := cg.generateCRUDOperations(mapColumns(t, cg, getTestTable()))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"func (r *UsersRepository) StreamPaginated(ctx context.Context, params PaginationParams) (<-chan Users, <-chan error)",
+		"WHERE ($1::uuid IS NULL OR id > $1)\n\t\t\tORDER BY id ASC\n\t\t\tLIMIT $2",
+		"for {\n\t\t\tpage, err := r.streamPage(ctx, query, cursor, limit)",
+		// The loop stops on a short page and otherwise advances the cursor past the last row
+		"if len(page) < limit {\n\t\t\t\treturn\n\t\t\t}",
+		"lastID := page[len(page)-1].GetID()\n\t\t\tcursor = &lastID",
+		`ExecuteQuery(ctx, r.db, "stream_paginated", "Users", query, cursor, int32(limit))`,
+		"case <-ctx.Done():",
+		"if params.IncludeTotal {\n\t\t\terrs <- fmt.Errorf(\"StreamPaginated does not support IncludeTotal\")\n\t\t\treturn\n\t\t}",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("StreamPaginated code missing %q\n%s", want, code)
+		}
+	}
+}
+
+func TestCodeGenerator_UpdateParamsFromMap(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"update", "update_from_map"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	// Plain params write every field, so a one-key map would zero the rest of the row
+	if _, err := cg.generateCRUDOperations(mapColumns(t, cg, getTestTable())); err == nil || !strings.Contains(err.Error(), "requires partial_update") {
+		t.Errorf("Expected missing partial_update error, got %v", err)
+	}
+
+	// The params type comes from Update
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"update_from_map"}, PartialUpdate: true}
+	if _, err := cg.generateCRUDOperations(mapColumns(t, cg, getTestTable())); err == nil || !strings.Contains(err.Error(), "requires update") {
+		t.Errorf("Expected missing update error, got %v", err)
+	}
+
+	if testing.Short() {
+		t.Skip("skipping compile test in short mode")
+	}
+
+	tempDir := t.TempDir()
+	config = &Config{
+		OutputDir:      tempDir,
+		PackageName:    "testgen",
+		QuerierPerCall: true,
+		TableConfigs: map[string]TableConfig{
+			"users": {Functions: []string{"get", "update", "update_from_map"}, PartialUpdate: true},
+		},
+	}
+	cg = NewCodeGenerator(config)
+	for _, generate := range []func() error{cg.GenerateSharedErrors, cg.GenerateSharedDatabaseOperations, cg.GenerateSharedRetryOperations} {
+		if err := generate(); err != nil {
+			t.Fatalf("shared file generation failed: %v", err)
+		}
+	}
+	table := getTestTable()
+	table.Columns = append(table.Columns, Column{Name: "bio", Type: "text", IsNullable: true})
+	if err := cg.GenerateTableRepository(mapColumns(t, cg, table)); err != nil {
+		t.Fatalf("GenerateTableRepository failed: %v", err)
+	}
+	if !compileGeneratedCode(t, tempDir) {
+		return
+	}
+
+	// A fake Querier records the UPDATE so the test can check which columns it sets
+	testContent := `package testgen
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type recordingQuerier struct {
+	sql  string
+	args []any
+}
+
+func (q *recordingQuerier) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return nil, errors.New("unexpected Query")
+}
+
+func (q *recordingQuerier) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, errors.New("unexpected Exec")
+}
+
+func (q *recordingQuerier) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	q.sql, q.args = sql, args
+	return noRow{}
+}
+
+type noRow struct{}
+
+func (noRow) Scan(dest ...any) error { return nil }
+
+func TestUpdateUsersParamsFromMap(t *testing.T) {
+	params, err := UpdateUsersParamsFromMap(map[string]any{"name": "Ada"})
+	if err != nil {
+		t.Fatalf("UpdateUsersParamsFromMap failed: %v", err)
+	}
+	if params.Name == nil || *params.Name != "Ada" {
+		t.Errorf("Name = %v, want Ada", params.Name)
+	}
+	if params.Email != nil || params.IsActive != nil || params.Bio != nil {
+		t.Errorf("Only Name should be set, got %+v", params)
+	}
+
+	// A nil value sets a nullable column to NULL rather than leaving it unchanged
+	params, err = UpdateUsersParamsFromMap(map[string]any{"bio": nil})
+	if err != nil {
+		t.Fatalf("UpdateUsersParamsFromMap failed: %v", err)
+	}
+	if params.Bio == nil || params.Bio.Valid {
+		t.Errorf("Bio = %+v, want a pointer to a NULL value", params.Bio)
+	}
+
+	if _, err := UpdateUsersParamsFromMap(map[string]any{"name": nil}); err == nil {
+		t.Error("Expected an error for a nil NOT NULL column")
+	}
+	if _, err := UpdateUsersParamsFromMap(map[string]any{"id": uuid.New()}); err == nil {
+		t.Error("The primary key must not be assignable from a patch map")
+	}
+	if _, err := UpdateUsersParamsFromMap(map[string]any{"is_active": "yes"}); err == nil {
+		t.Error("Expected an error for a value of the wrong type")
+	}
+}
+
+func TestUpdateFromMapSetsOnlyMappedColumns(t *testing.T) {
+	q := &recordingQuerier{}
+	id := uuid.New()
+	if _, err := NewUsersRepository().UpdateFromMap(context.Background(), q, id, map[string]any{"name": "Ada"}); err != nil {
+		t.Fatalf("UpdateFromMap failed: %v", err)
+	}
+	if !strings.Contains(q.sql, "SET name = $1\n") {
+		t.Errorf("UPDATE should set only name, got %s", q.sql)
+	}
+	if len(q.args) != 2 || q.args[0] != "Ada" || q.args[1] != id {
+		t.Errorf("args = %v, want [Ada %s]", q.args, id)
+	}
+}
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "from_map_test.go"), []byte(testContent), 0644); err != nil {
+		t.Fatalf("Failed to write generated package test: %v", err)
+	}
+
+	testCmd := exec.Command("go", "test", "./...")
+	testCmd.Dir = tempDir
+	testCmd.Env = append(os.Environ(), "GO111MODULE=on")
+	if output, err := testCmd.CombinedOutput(); err != nil {
+		t.Errorf("Generated UpdateFromMap test failed: %v\nOutput: %s", err, string(output))
+	}
+}
+
+func TestCodeGenerator_PaginationDisabled(t *testing.T) {
+	paginate := false
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Paginate: &paginate},
+	}
+	cg := NewCodeGenerator(config)
+
+	code, err := cg.generateTableCode(mapColumns(t, cg, getTestTable()))
+	if err != nil {
+		t.Fatalf("generateTableCode failed: %v", err)
+	}
+
+	for _, unexpected := range []string{"ListPaginated", "PaginationParams", "GetID"} {
+		if strings.Contains(code, unexpected) {
+			t.Errorf("Generated code should not contain %q when pagination is disabled\n%s", unexpected, code)
+		}
+	}
+	for _, want := range []string{"func (r *UsersRepository) Create(", "func (r *UsersRepository) List("} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code missing plain CRUD %q", want)
+		}
+	}
+
+	// Pagination stays on by default
+	config.TableConfigs = nil
+	code, err = cg.generateTableCode(mapColumns(t, cg, getTestTable()))
+	if err != nil {
+		t.Fatalf("generateTableCode failed: %v", err)
+	}
+	if !strings.Contains(code, "func (u Users) GetID() uuid.UUID") || !strings.Contains(code, "ListPaginated") {
+		t.Error("Expected GetID and ListPaginated when pagination is enabled")
+	}
+}
+
+func TestCodeGenerator_SerialPrimaryKey(t *testing.T) {
+	tempDir := t.TempDir()
+	paginate := false
+	config := &Config{
+		OutputDir:   tempDir,
+		PackageName: "testgen",
+		TableConfigs: map[string]TableConfig{
+			"events": {Paginate: &paginate},
+		},
+	}
+	cg := NewCodeGenerator(config)
+	table := Table{
+		Name:   "events",
+		Schema: "public",
+		Columns: []Column{
+			{Name: "id", Type: "integer", DefaultValue: "nextval('events_id_seq'::regclass)"},
+			{Name: "name", Type: "text"},
+		},
+		PrimaryKey: []string{"id"},
+	}
+
+	code, err := cg.generateTableCode(mapColumns(t, cg, table))
+	if err != nil {
+		t.Fatalf("generateTableCode failed: %v", err)
+	}
+	expected := []string{
+		"Id int32",
+		// The sequence assigns the key, so Create leaves it out
+		"INSERT INTO events (name)",
+		"func (r *EventsRepository) Get(ctx context.Context, id int32) (*Events, error)",
+		"func (r *EventsRepository) Update(ctx context.Context, id int32, params UpdateEventsParams) (*Events, error)",
+		"func (r *EventsRepository) Delete(ctx context.Context, id int32) error",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("Serial key code missing %q\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "uuid.UUID") {
+		t.Errorf("A serial-keyed table should not use uuid.UUID\n%s", code)
+	}
+
+	if testing.Short() {
+		return
+	}
+	for _, generate := range []func() error{cg.GenerateSharedErrors, cg.GenerateSharedDatabaseOperations, cg.GenerateSharedRetryOperations} {
+		if err := generate(); err != nil {
+			t.Fatalf("shared file generation failed: %v", err)
+		}
+	}
+	if err := cg.GenerateTableRepository(table); err != nil {
+		t.Fatalf("GenerateTableRepository failed: %v", err)
+	}
+	compileGeneratedCode(t, tempDir)
+}
+
+func TestCodeGenerator_NaturalPrimaryKey(t *testing.T) {
+	paginate := false
+	config := &Config{
+		OutputDir:   t.TempDir(),
+		PackageName: "testgen",
+		TableConfigs: map[string]TableConfig{
+			"countries": {Paginate: &paginate},
+		},
+	}
+	cg := NewCodeGenerator(config)
+	table := Table{
+		Name:   "countries",
+		Schema: "public",
+		Columns: []Column{
+			{Name: "code", Type: "text"},
+			{Name: "name", Type: "text"},
+		},
+		PrimaryKey: []string{"code"},
+	}
+
+	code, err := cg.generateCRUDOperations(mapColumns(t, cg, table))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	// A key without a default is the caller's to supply on create, and stays out of updates
+	expected := []string{
+		"type CreateCountriesParams struct {\n\tCode string `json:\"code\" db:\"code\"`\n\tName string",
+		"INSERT INTO countries (code, name)\n\t\tVALUES ($1, $2)",
+		"type UpdateCountriesParams struct {\n\tName string",
+		"func (r *CountriesRepository) Get(ctx context.Context, id string) (*Countries, error)",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("Natural key code missing %q\n%s", want, code)
+		}
+	}
+}
+
+func TestCodeGenerator_BulkCreateIDs(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"create", "bulk_create_ids"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	code, err := cg.generateCRUDOperations(mapColumns(t, cg, getTestTable()))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"func (r *UsersRepository) BulkCreateIDs(ctx context.Context, items []CreateUsersParams) ([]uuid.UUID, error)",
+		"query.WriteString(`INSERT INTO users (name, email, metadata) VALUES `)",
+		"const columnsPerRow = 3",
+		"args = append(args, item.Name, item.Email, item.Metadata)",
+		// Only the primary key comes back, scanned straight into the id slice
+		"query.WriteString(` RETURNING id`)",
+		"ids := make([]uuid.UUID, 0, len(items))",
+		"rows.Scan(&id)",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("BulkCreateIDs code missing %q\n%s", want, code)
+		}
+	}
+	bulk := code[strings.Index(code, "func (r *UsersRepository) BulkCreateIDs"):]
+	if strings.Contains(bulk, "RETURNING id, name") {
+		t.Error("BulkCreateIDs should not return full rows")
+	}
+}
+
+func TestCodeGenerator_ExportCSV(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"export_csv"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	code, err := cg.generateCRUDOperations(mapColumns(t, cg, getTestTable()))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"func (r *UsersRepository) ExportCSV(ctx context.Context, w io.Writer) error",
+		// The header row lists every column name in select order
+		`columns := []string{"id", "name", "email", "is_active", "created_at", "metadata"}`,
+		"values := []interface{}{u.Id, u.Name, u.Email, u.IsActive, u.CreatedAt, u.Metadata}",
+		// Rows are written inside the scan loop rather than collected first
+		"for rows.Next() {",
+		"if err := writer.Write(record); err != nil {",
+		"FormatCSVValue(value)",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("ExportCSV code missing %q\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "append(") {
+		t.Error("ExportCSV should stream rows instead of accumulating them")
+	}
+}
+
+func TestCodeGenerator_ReturningColumns(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"create", "update", "get"}, ReturningColumns: []string{"id", "email"}},
+	}
+	cg := NewCodeGenerator(config)
+	table := mapColumns(t, cg, getTestTable())
+
+	code, err := cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	// Both writes return and scan only the configured columns
+	if got := strings.Count(code, "RETURNING id, email\n"); got != 2 {
+		t.Errorf("Expected Create and Update to return id, email, found %d\n%s", got, code)
+	}
+	if got := strings.Count(code, "err := row.Scan(&u.Id, &u.Email)"); got != 2 {
+		t.Errorf("Expected Create and Update to scan id, email, found %d\n%s", got, code)
+	}
+	if !strings.Contains(code, "// Only the configured returning columns (id, email) are populated on the result") {
+		t.Error("Expected narrowed writes to document the partially populated result")
+	}
+	// Reads still select every column
+	if !strings.Contains(code, "SELECT id, name, email, is_active, created_at, metadata") {
+		t.Error("Get should still select all columns")
+	}
+
+	// The primary key must always be returned
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"create"}, ReturningColumns: []string{"email"}}
+	if _, err := cg.generateCRUDOperations(table); err == nil || !strings.Contains(err.Error(), "must include the primary key id") {
+		t.Errorf("Expected missing primary key error, got %v", err)
+	}
+
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"create"}, ReturningColumns: []string{"id", "nickname"}}
+	if _, err := cg.generateCRUDOperations(table); err == nil || !strings.Contains(err.Error(), "unknown column nickname") {
+		t.Errorf("Expected unknown column error, got %v", err)
+	}
+}
+
+func TestCodeGenerator_GetByUniqueKeys(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"get_by_unique"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	table.Columns = append(table.Columns, Column{Name: "external_id", Type: "uuid", IsNullable: true})
+	table.Indexes = []Index{
+		{Name: "users_email_key", Columns: []string{"email"}, IsUnique: true},
+		{Name: "users_external_id_key", Columns: []string{"external_id"}, IsUnique: true},
+		{Name: "idx_users_name", Columns: []string{"name"}},
+		{Name: "users_name_email_key", Columns: []string{"name", "email"}, IsUnique: true},
+		{Name: "users_lower_email_key", Columns: []string{"lower(email)"}, IsUnique: true},
+	}
+	table = mapColumns(t, cg, table)
+
+	code, err := cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"func (r *UsersRepository) GetByEmails(ctx context.Context, emails []string) ([]Users, error)",
+		"WHERE email = ANY($1)",
+		`ExecuteQuery(ctx, r.db, "get_by_emails", "Users", query, emails)`,
+		// Nullable unique columns are looked up by their non-nullable type
+		"func (r *UsersRepository) GetByExternalIds(ctx context.Context, externalIds []uuid.UUID) ([]Users, error)",
+		"WHERE external_id = ANY($1)",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("Unique key lookup code missing %q\n%s", want, code)
+		}
+	}
+
+	// Only single-column unique indexes on plain columns get a lookup method
+	if count := strings.Count(code, "= ANY($1)"); count != 2 {
+		t.Errorf("Expected 2 unique key lookups, found %d\n%s", count, code)
+	}
+}
+
+func TestCodeGenerator_CreateWithConflict(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"create", "create_with_conflict"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	code, err := cg.generateCRUDOperations(mapColumns(t, cg, getTestTable()))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"func (r *UsersRepository) CreateWithConflict(ctx context.Context, params CreateUsersParams, conflictCols []string, updateCols []string) (*Users, error)",
+		// Only the table's own columns may reach the ON CONFLICT clause
+		`columns := map[string]string{"id": "id", "name": "name", "email": "email", "is_active": "is_active", "created_at": "created_at", "metadata": "metadata"}`,
+		"if len(conflictCols) == 0 {",
+		"if _, ok := columns[col]; !ok {",
+		`Detail: fmt.Sprintf("unknown column %q", col)`,
+		`action := "DO NOTHING"`,
+		`assignments[i] = columns[col] + " = EXCLUDED." + columns[col]`,
+		"ON CONFLICT (` + strings.Join(conflictTarget, \", \") + `) ` + action + `",
+		"RETURNING id, name, email, is_active, created_at, metadata",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("CreateWithConflict code missing %q\n%s", want, code)
+		}
+	}
+
+	// Column names must be checked before the statement is assembled
+	method := code[strings.Index(code, "func (r *UsersRepository) CreateWithConflict"):]
+	if strings.Index(method, "if _, ok := columns[col]; !ok {") > strings.Index(method, "query := `") {
+		t.Error("CreateWithConflict should validate column names before building the query")
+	}
+
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"create_with_conflict"}}
+	if _, err := cg.generateCRUDOperations(mapColumns(t, cg, getTestTable())); err == nil || !strings.Contains(err.Error(), "requires create") {
+		t.Errorf("expected missing create dependency error, got %v", err)
+	}
+}
+
+func TestCodeGenerator_CheckValidators(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"create", "update"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	table.Columns = append(table.Columns,
+		Column{Name: "status", Type: "varchar"},
+		Column{Name: "age", Type: "integer", IsNullable: true},
+		Column{Name: "score", Type: "double precision", DefaultValue: "0"},
+	)
+	table.Checks = []CheckConstraint{
+		{Name: "chk_status", Column: "status", Operator: "IN", Values: []string{"draft", "published"}},
+		{Name: "chk_age", Column: "age", Operator: ">=", Values: []string{"0"}},
+		{Name: "chk_age", Column: "age", Operator: "<=", Values: []string{"150"}},
+		{Name: "chk_score", Column: "score", Operator: ">", Values: []string{"-0.5"}},
+		// A fractional bound can't be compared against an integer field, so it stays server-side
+		{Name: "chk_age_fraction", Column: "age", Operator: "<>", Values: []string{"1.5"}},
+		// Metadata is JSON, which the validators don't understand
+		{Name: "chk_metadata", Column: "metadata", Operator: "<>", Values: []string{"{}"}},
+	}
+	table = mapColumns(t, cg, table)
+
+	code, err := cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"func (p CreateUsersParams) Validate() error",
+		"func (p UpdateUsersParams) Validate() error",
+		`if !(p.Status == "draft" || p.Status == "published") {`,
+		`Detail: "status must be one of \"draft\", \"published\" (constraint chk_status)"`,
+		// NULL satisfies a CHECK, so nullable values are only compared when set
+		"if p.Age.Valid && !(p.Age.Int32 >= 0) {",
+		"if p.Age.Valid && !(p.Age.Int32 <= 150) {",
+		"Type: ErrValidationFailed",
+		"if err := params.Validate(); err != nil {",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("check validation code missing %q\n%s", want, code)
+		}
+	}
+
+	// score has a default so it's only validated where it is a param field, i.e. on update
+	create := code[strings.Index(code, "func (p CreateUsersParams) Validate"):strings.Index(code, "func (r *UsersRepository) Create(")]
+	if strings.Contains(create, "p.Score") {
+		t.Error("CreateUsersParams.Validate should not check a column that is not a create param")
+	}
+	if !strings.Contains(code, "if !(p.Score > -0.5) {") {
+		t.Error("UpdateUsersParams.Validate should check score")
+	}
+	for _, unexpected := range []string{"chk_age_fraction", "chk_metadata"} {
+		if strings.Contains(code, unexpected) {
+			t.Errorf("check %s should be left to the database", unexpected)
+		}
+	}
+
+	// Tables without checks get no Validate method
+	table.Checks = nil
+	code, err = cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	if strings.Contains(code, "Validate") {
+		t.Error("Validate should only be generated for tables with check constraints")
+	}
+}
+
+func TestCodeGenerator_ListPaginatedPage(t *testing.T) {
+	config := getTestConfigWithTempDir(t)
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"paginate"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	if err := cg.GenerateSharedPaginationTypes(); err != nil {
+		t.Fatalf("GenerateSharedPaginationTypes failed: %v", err)
+	}
+	content, err := os.ReadFile(cg.config.GetOutputPath("pagination.go"))
+	if err != nil {
+		t.Fatalf("Failed to read pagination file: %v", err)
+	}
+
+	// Page keeps the PaginationResult fields and adds the decoded cursor and item count
+	for _, want := range []string{
+		"type Page[T any] struct",
+		"\tPaginationResult[T]\n",
+		"NextCursorID uuid.UUID `json:\"-\"`",
+		"Count int `json:\"count\"`",
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("pagination types missing %q\n%s", want, content)
+		}
+	}
+
+	code, err := cg.generateCRUDOperations(mapColumns(t, cg, getTestTable()))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	for _, want := range []string{
+		"func (r *UsersRepository) ListPaginated(ctx context.Context, params PaginationParams) (*Page[Users], error)",
+		"nextCursorID = items[len(items)-1].GetID()",
+		"nextCursor = encodeCursor(nextCursorID)",
+		"NextCursorID: nextCursorID,",
+		"Count:        len(items),",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("ListPaginated code missing %q\n%s", want, code)
+		}
+	}
+}
+
+func TestCodeGenerator_ListPaginatedBackward(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compile test in short mode")
+	}
+
+	tempDir := t.TempDir()
+	config := &Config{
+		OutputDir:      tempDir,
+		PackageName:    "testgen",
+		QuerierPerCall: true,
+		TableConfigs: map[string]TableConfig{
+			"items": {Functions: []string{"paginate"}},
+		},
+	}
+	cg := NewCodeGenerator(config)
+	for _, generate := range []func() error{cg.GenerateSharedPaginationTypes, cg.GenerateSharedErrors, cg.GenerateSharedDatabaseOperations, cg.GenerateSharedRetryOperations} {
+		if err := generate(); err != nil {
+			t.Fatalf("shared file generation failed: %v", err)
+		}
+	}
+	table := Table{
+		Name:       "items",
+		Schema:     "public",
+		Columns:    []Column{{Name: "id", Type: "uuid", GoType: "uuid.UUID"}},
+		PrimaryKey: []string{"id"},
+	}
+	if err := cg.GenerateTableRepository(table); err != nil {
+		t.Fatalf("GenerateTableRepository failed: %v", err)
+	}
+	if !compileGeneratedCode(t, tempDir) {
+		return
+	}
+
+	// A fake Querier serves five ordered IDs, reading them in reverse for the descending backward query
+	testContent := `package testgen
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func itemID(n byte) uuid.UUID { return uuid.UUID{15: n} }
+
+type fakeRows struct {
+	pgx.Rows
+	ids []uuid.UUID
+	pos int
+}
+
+func (r *fakeRows) Next() bool { r.pos++; return r.pos <= len(r.ids) }
+func (r *fakeRows) Scan(dest ...any) error {
+	*dest[0].(*uuid.UUID) = r.ids[r.pos-1]
+	return nil
+}
+func (r *fakeRows) Err() error { return nil }
+func (r *fakeRows) Close()     {}
+
+type tableQuerier struct{ ids []uuid.UUID }
+
+func (q tableQuerier) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	cursor, limit := args[0].(*uuid.UUID), int(args[1].(int32))
+	ids := slices.Clone(q.ids)
+	descending := strings.Contains(sql, "DESC")
+	if descending {
+		slices.Reverse(ids)
+	}
+	var rows []uuid.UUID
+	for _, id := range ids {
+		after := cursor == nil || (!descending && strings.Compare(id.String(), cursor.String()) > 0) || (descending && strings.Compare(id.String(), cursor.String()) < 0)
+		if after && len(rows) < limit {
+			rows = append(rows, id)
+		}
+	}
+	return &fakeRows{ids: rows}, nil
+}
+
+func (q tableQuerier) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+
+// QueryRow serves the count(*) query IncludeTotal runs
+func (q tableQuerier) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return countRow(len(q.ids))
+}
+
+type countRow int
+
+func (c countRow) Scan(dest ...any) error {
+	*dest[0].(*int64) = int64(c)
+	return nil
+}
+
+func TestListPaginatedBothDirections(t *testing.T) {
+	q := tableQuerier{ids: []uuid.UUID{itemID(1), itemID(2), itemID(3), itemID(4), itemID(5)}}
+	repo := NewItemsRepository()
+	cursor := func(n byte) string { return encodeCursor(itemID(n)) }
+
+	tests := []struct {
+		name       string
+		params     PaginationParams
+		want       []byte
+		next, prev string
+	}{
+		{"first page", PaginationParams{Limit: 2}, []byte{1, 2}, cursor(2), ""},
+		{"forward from a cursor", PaginationParams{Limit: 2, Cursor: cursor(2)}, []byte{3, 4}, cursor(4), cursor(3)},
+		{"forward to the end", PaginationParams{Limit: 2, Cursor: cursor(4)}, []byte{5}, "", cursor(5)},
+		{"backward to the start", PaginationParams{Limit: 2, Cursor: cursor(3), Direction: PaginationBackward}, []byte{1, 2}, cursor(2), ""},
+		{"backward from a cursor", PaginationParams{Limit: 2, Cursor: cursor(5), Direction: PaginationBackward}, []byte{3, 4}, cursor(4), cursor(3)},
+		{"last page", PaginationParams{Limit: 2, Direction: PaginationBackward}, []byte{4, 5}, "", cursor(4)},
+	}
+	for _, tt := range tests {
+		page, err := repo.ListPaginated(context.Background(), q, tt.params)
+		if err != nil {
+			t.Fatalf("%s: ListPaginated failed: %v", tt.name, err)
+		}
+		var got []byte
+		for _, item := range page.Items {
+			got = append(got, item.Id[15])
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: items = %v, want %v", tt.name, got, tt.want)
+		}
+		if page.NextCursor != tt.next || page.PrevCursor != tt.prev {
+			t.Errorf("%s: NextCursor, PrevCursor = %q, %q, want %q, %q", tt.name, page.NextCursor, page.PrevCursor, tt.next, tt.prev)
+		}
+		if page.Total != nil {
+			t.Errorf("%s: Total = %d, want nil without IncludeTotal", tt.name, *page.Total)
+		}
+	}
+
+	page, err := repo.ListPaginated(context.Background(), q, PaginationParams{Limit: 2, IncludeTotal: true})
+	if err != nil {
+		t.Fatalf("ListPaginated with IncludeTotal failed: %v", err)
+	}
+	if page.Total == nil || *page.Total != 5 || len(page.Items) != 2 {
+		t.Errorf("Total = %v with %d items, want 5 with a page of 2", page.Total, len(page.Items))
+	}
+
+	if _, err := repo.ListPaginated(context.Background(), q, PaginationParams{Direction: "sideways"}); err == nil {
+		t.Error("Expected an error for an unknown direction")
+	}
+}
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "paginated_test.go"), []byte(testContent), 0644); err != nil {
+		t.Fatalf("Failed to write generated package test: %v", err)
+	}
+
+	testCmd := exec.Command("go", "test", "./...")
+	testCmd.Dir = tempDir
+	testCmd.Env = append(os.Environ(), "GO111MODULE=on")
+	if output, err := testCmd.CombinedOutput(); err != nil {
+		t.Errorf("Generated backward pagination test failed: %v\nOutput: %s", err, string(output))
+	}
+}
+
+func TestCodeGenerator_PaginateBy(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"paginate"}, PaginateBy: "created_at"},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	table.Indexes = []Index{{Name: "users_created_at_idx", Columns: []string{"created_at", "id"}}}
+	table = mapColumns(t, cg, table)
+	code, err := cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"validatePaginationOptions(params)",
+		"decodeTimeCursor(params.Cursor)",
+		"WHERE ($1::timestamptz IS NULL OR (created_at, id) > ($1, $2))",
+		"ORDER BY created_at ASC, id ASC",
+		"WHERE ($1::timestamptz IS NULL OR (created_at, id) < ($1, $2))",
+		"ORDER BY created_at DESC, id DESC",
+		"query, cursorTime, cursorID, int32(limit+1))",
+		"nextCursor = encodeTimeCursor(items[len(items)-1].CreatedAt, nextCursorID)",
+		"prevCursor = encodeTimeCursor(items[0].CreatedAt, prevCursorID)",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("ListPaginated by created_at missing %q\n%s", want, code)
+		}
+	}
+
+	tests := []struct {
+		column string
+		want   string
+	}{
+		{"published_at", "does not exist"},
+		{"name", "must be a timestamp"},
+	}
+	for _, tt := range tests {
+		config.TableConfigs["users"] = TableConfig{Functions: []string{"paginate"}, PaginateBy: tt.column}
+		if _, err := cg.generateCRUDOperations(table); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("paginate_by %s: expected an error containing %q, got %v", tt.column, tt.want, err)
+		}
+	}
+	unindexed := table
+	unindexed.Indexes = nil
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"paginate"}, PaginateBy: "created_at"}
+	if _, err := cg.generateCRUDOperations(unindexed); err == nil || !strings.Contains(err.Error(), "requires an index on users.created_at") {
+		t.Errorf("Expected an unindexed paginate_by error, got %v", err)
+	}
+
+	// The cursor is cast to the column's own type, so timestamp without time zone skips the session time zone
+	table.Columns = append(table.Columns, Column{Name: "synced_at", Type: "timestamp", IsNullable: false})
+	table.Indexes = []Index{{Name: "users_synced_at_idx", Columns: []string{"synced_at", "id"}}}
+	table = mapColumns(t, cg, table)
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"paginate"}, PaginateBy: "synced_at"}
+	code, err = cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	for _, want := range []string{
+		"WHERE ($1::timestamp IS NULL OR (synced_at, id) > ($1, $2))",
+		"WHERE ($1::timestamp IS NULL OR (synced_at, id) < ($1, $2))",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("ListPaginated by synced_at missing %q\n%s", want, code)
+		}
+	}
+}
+
+func TestCodeGenerator_PaginateByCursor(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compile test in short mode")
+	}
+
+	tempDir := t.TempDir()
+	config := &Config{
+		OutputDir:      tempDir,
+		PackageName:    "testgen",
+		QuerierPerCall: true,
+		TableConfigs: map[string]TableConfig{
+			"events": {Functions: []string{"paginate"}, PaginateBy: "created_at"},
+		},
+	}
+	cg := NewCodeGenerator(config)
+	for _, generate := range []func() error{cg.GenerateSharedPaginationTypes, cg.GenerateSharedErrors, cg.GenerateSharedDatabaseOperations, cg.GenerateSharedRetryOperations} {
+		if err := generate(); err != nil {
+			t.Fatalf("shared file generation failed: %v", err)
+		}
+	}
+	table := Table{
+		Name:   "events",
+		Schema: "public",
+		Columns: []Column{
+			{Name: "id", Type: "uuid", GoType: "uuid.UUID"},
+			{Name: "created_at", Type: "timestamptz", GoType: "time.Time"},
+		},
+		PrimaryKey: []string{"id"},
+		Indexes:    []Index{{Name: "events_created_at_idx", Columns: []string{"created_at"}}},
+	}
+	if err := cg.GenerateTableRepository(table); err != nil {
+		t.Fatalf("GenerateTableRepository failed: %v", err)
+	}
+	if !compileGeneratedCode(t, tempDir) {
+		return
+	}
+
+	// A fake Querier returns one row and records the keyset arguments each query was bound with
+	testContent := `package testgen
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type eventRows struct {
+	pgx.Rows
+	events []Events
+	pos    int
+}
+
+func (r *eventRows) Next() bool { r.pos++; return r.pos <= len(r.events) }
+func (r *eventRows) Scan(dest ...any) error {
+	*dest[0].(*uuid.UUID) = r.events[r.pos-1].Id
+	*dest[1].(*time.Time) = r.events[r.pos-1].CreatedAt
+	return nil
+}
+func (r *eventRows) Err() error { return nil }
+func (r *eventRows) Close()     {}
+
+type keysetQuerier struct {
+	events []Events
+	args   []interface{}
+}
+
+func (q *keysetQuerier) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	q.args = args
+	return &eventRows{events: q.events}, nil
+}
+
+func (q *keysetQuerier) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+
+func (q *keysetQuerier) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return nil
+}
+
+func TestPaginateByCreatedAt(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 30, 0, 500, time.UTC)
+	event := Events{Id: uuid.New(), CreatedAt: created}
+	q := &keysetQuerier{events: []Events{event, {Id: uuid.New(), CreatedAt: created.Add(time.Second)}}}
+	repo := NewEventsRepository()
+
+	page, err := repo.ListPaginated(context.Background(), q, PaginationParams{Limit: 1})
+	if err != nil {
+		t.Fatalf("ListPaginated failed: %v", err)
+	}
+	if q.args[0].(*time.Time) != nil || q.args[1].(*uuid.UUID) != nil {
+		t.Errorf("first page bound cursor %v, %v, want none", q.args[0], q.args[1])
+	}
+	if page.NextCursorID != event.Id {
+		t.Errorf("NextCursorID = %v, want %v", page.NextCursorID, event.Id)
+	}
+
+	// The next page's query is bound with both halves of the cursor
+	if _, err := repo.ListPaginated(context.Background(), q, PaginationParams{Limit: 1, Cursor: page.NextCursor}); err != nil {
+		t.Fatalf("ListPaginated from NextCursor failed: %v", err)
+	}
+	if cursorTime := q.args[0].(*time.Time); cursorTime == nil || !cursorTime.Equal(created) {
+		t.Errorf("cursor time = %v, want %v", q.args[0], created)
+	}
+	if cursorID := q.args[1].(*uuid.UUID); cursorID == nil || *cursorID != event.Id {
+		t.Errorf("cursor id = %v, want %v", q.args[1], event.Id)
+	}
+
+	if _, err := repo.ListPaginated(context.Background(), q, PaginationParams{Cursor: encodeCursor(event.Id)}); err == nil {
+		t.Error("Expected an ID-only cursor to be rejected")
+	}
+}
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "paginate_by_test.go"), []byte(testContent), 0644); err != nil {
+		t.Fatalf("Failed to write generated package test: %v", err)
+	}
+
+	testCmd := exec.Command("go", "test", "./...")
+	testCmd.Dir = tempDir
+	testCmd.Env = append(os.Environ(), "GO111MODULE=on")
+	if output, err := testCmd.CombinedOutput(); err != nil {
+		t.Errorf("Generated paginate_by test failed: %v\nOutput: %s", err, string(output))
+	}
+}
+
+func TestCodeGenerator_PgxpoolBackend(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compile test in short mode")
+	}
+
+	tempDir := t.TempDir()
+	config := &Config{
+		OutputDir:   tempDir,
+		PackageName: "testgen",
+		Schema:      "public",
+		DBBackend:   DBBackendPgxpool,
+		TableConfigs: map[string]TableConfig{
+			"users": {Functions: []string{"create", "get", "list", "paginate"}},
+		},
+	}
+	cg := NewCodeGenerator(config)
+	for _, generate := range []func() error{cg.GenerateSharedPaginationTypes, cg.GenerateSharedErrors, cg.GenerateSharedDatabaseOperations, cg.GenerateSharedRetryOperations} {
+		if err := generate(); err != nil {
+			t.Fatalf("shared file generation failed: %v", err)
+		}
+	}
+	if err := cg.GenerateSchemaHash("abc123", []string{"users"}); err != nil {
+		t.Fatalf("GenerateSchemaHash failed: %v", err)
+	}
+	if err := cg.GenerateTableRepository(getTestTable()); err != nil {
+		t.Fatalf("GenerateTableRepository failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "users_generated.go"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	if !strings.Contains(string(content), "func NewUsersRepository(db *pgxpool.Pool) *UsersRepository {") {
+		t.Errorf("repository constructor does not take a *pgxpool.Pool\n%s", content)
+	}
+	queryCode, err := cg.generateQueryRepository("queries/users.sql", nil)
+	if err != nil {
+		t.Fatalf("generateQueryRepository failed: %v", err)
+	}
+	if !strings.Contains(queryCode, "func NewUsersQueries(db *pgxpool.Pool) *UsersQueries {") {
+		t.Errorf("query repository constructor does not take a *pgxpool.Pool\n%s", queryCode)
+	}
+
+	if !compileGeneratedCode(t, tempDir) {
+		return
+	}
+	// go mod tidy drops requirements the package doesn't import
+	goMod, err := os.ReadFile(filepath.Join(tempDir, "go.mod"))
+	if err != nil {
+		t.Fatalf("Failed to read go.mod: %v", err)
+	}
+	if strings.Contains(string(goMod), "github.com/nhalm/pgxkit") {
+		t.Errorf("pgxpool backend still depends on pgxkit\n%s", goMod)
+	}
+}
+
+func TestCodeGenerator_ReservedWordIdentifiers(t *testing.T) {
+	config := getTestConfigWithTempDir(t)
+	config.TableConfigs = map[string]TableConfig{
+		"order": {Functions: []string{"create", "get", "update", "delete", "list", "paginate", "create_with_conflict"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := Table{
+		Name:       "order",
+		Schema:     "public",
+		PrimaryKey: []string{"id"},
+		Columns: []Column{
+			{Name: "id", Type: "uuid", DefaultValue: "gen_random_uuid()"},
+			{Name: "user", Type: "text"},
+			{Name: "group", Type: "text", IsNullable: true},
+			{Name: "total", Type: "integer"},
+		},
+	}
+	if err := cg.GenerateTableRepository(mapColumns(t, cg, table)); err != nil {
+		t.Fatalf("GenerateTableRepository failed: %v", err)
+	}
+
+	filename := filepath.Join(config.OutputDir, "order_generated.go")
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	code := string(content)
+
+	// Reserved words are quoted wherever they appear in SQL
+	expected := []string{
+		`FROM "order"`,
+		`INSERT INTO "order" ("user", "group", total)`,
+		`RETURNING id, "user", "group", total`,
+		`SELECT id, "user", "group", total`,
+		`DELETE FROM "order" WHERE id = $1`,
+		// CreateWithConflict maps caller-supplied names to quoted identifiers
+		`"user": "\"user\""`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code missing %q\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "FROM order") {
+		t.Error("table name should never appear unquoted in SQL")
+	}
+
+	// Go identifiers and tags keep the raw names
+	for _, want := range []string{
+		"type Order struct",
+		"`json:\"user\" db:\"user\"`",
+		"`json:\"group\" db:\"group\"`",
+		"func (r *OrderRepository) Get(",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code missing %q", want)
+		}
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), filename, content, parser.AllErrors); err != nil {
+		t.Errorf("generated code is not valid Go: %v", err)
+	}
+}
+
+func TestCodeGenerator_SoftDeleteWithDeleted(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {
+			Functions:        []string{"get", "list", "update", "bulk_update", "delete", "paginate", "get_with_deleted", "list_with_deleted"},
+			SoftDeleteColumn: "deleted_at",
+		},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	table.Columns = append(table.Columns, Column{Name: "deleted_at", Type: "timestamptz", IsNullable: true})
+	table = mapColumns(t, cg, table)
+
+	code, err := cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	method := func(name string) string {
+		start := strings.Index(code, "func (r *UsersRepository) "+name+"(")
+		if start < 0 {
+			t.Fatalf("method %s not generated\n%s", name, code)
+		}
+		end := strings.Index(code[start:], "\n}")
+		return code[start : start+end]
+	}
+
+	// Regular reads and writes skip soft-deleted rows
+	for name, want := range map[string]string{
+		"Get":           "WHERE id = $1 AND deleted_at IS NULL",
+		"List":          "WHERE deleted_at IS NULL",
+		"ListPaginated": "WHERE ($1::uuid IS NULL OR id > $1) AND deleted_at IS NULL",
+		"Update":        "WHERE id = $7 AND deleted_at IS NULL",
+		"BulkUpdate":    "WHERE t.id = u.id AND t.deleted_at IS NULL",
+		"Delete":        "UPDATE users SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL",
+	} {
+		if !strings.Contains(method(name), want) {
+			t.Errorf("%s missing %q\n%s", name, want, method(name))
+		}
+	}
+
+	// The WithDeleted variants omit the filter
+	for _, name := range []string{"GetWithDeleted", "ListWithDeleted"} {
+		if strings.Contains(method(name), "deleted_at IS NULL") {
+			t.Errorf("%s should include soft-deleted rows\n%s", name, method(name))
+		}
+	}
+	if !strings.Contains(method("GetWithDeleted"), "WHERE id = $1\n") {
+		t.Errorf("GetWithDeleted should still look up by ID\n%s", method("GetWithDeleted"))
+	}
+
+	// A partial update skips them too, matching the Get it falls back to when no field is set
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"get", "update"}, PartialUpdate: true, SoftDeleteColumn: "deleted_at"}
+	code, err = cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	if !strings.Contains(method("Update"), "WHERE id = $%d AND deleted_at IS NULL") {
+		t.Errorf("partial Update should skip soft-deleted rows\n%s", method("Update"))
+	}
+
+	// Without soft delete configured, the variants can't be requested and reads are unfiltered
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"get", "list_with_deleted"}}
+	if _, err := cg.generateCRUDOperations(table); err == nil || !strings.Contains(err.Error(), "requires soft_delete_column") {
+		t.Errorf("expected soft_delete_column requirement error, got %v", err)
+	}
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"get", "delete"}}
+	code, err = cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	if strings.Contains(code, "deleted_at IS NULL") || !strings.Contains(code, "DELETE FROM users WHERE id = $1") {
+		t.Errorf("tables without soft delete should hard-delete and read unfiltered\n%s", code)
+	}
+
+	// The soft-delete column must be a nullable timestamp
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"get"}, SoftDeleteColumn: "email"}
+	if _, err := cg.generateCRUDOperations(table); err == nil || !strings.Contains(err.Error(), "must be a nullable timestamp") {
+		t.Errorf("expected nullable timestamp error, got %v", err)
+	}
+}
+
+func TestCodeGenerator_CreateParamsBuilder(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"create", "create_builder"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	table.Columns = append(table.Columns, Column{Name: "bio", Type: "text", IsNullable: true})
+	code, err := cg.generateCRUDOperations(mapColumns(t, cg, table))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"type CreateUsersParamsBuilder struct",
+		"func NewCreateUsersParams() *CreateUsersParamsBuilder",
+		"func (b *CreateUsersParamsBuilder) Build() CreateUsersParams",
+		// Every settable field gets a setter returning the builder for chaining
+		"func (b *CreateUsersParamsBuilder) WithName(value string) *CreateUsersParamsBuilder",
+		"func (b *CreateUsersParamsBuilder) WithEmail(value string) *CreateUsersParamsBuilder",
+		"b.params.Name = value",
+		// Nullable fields take the plain value and mark it set
+		"func (b *CreateUsersParamsBuilder) WithMetadata(value json.RawMessage) *CreateUsersParamsBuilder",
+		"b.params.Metadata = &value",
+		"func (b *CreateUsersParamsBuilder) WithBio(value string) *CreateUsersParamsBuilder",
+		"b.params.Bio = pgtype.Text{String: value, Valid: true}",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("builder code missing %q\n%s", want, code)
+		}
+	}
+
+	// Columns the database fills in are not part of CreateParams, so they get no setter
+	for _, unexpected := range []string{"WithId(", "WithCreatedAt(", "WithIsActive("} {
+		if strings.Contains(code, unexpected) {
+			t.Errorf("builder should not have %s", unexpected)
+		}
+	}
+
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"create_builder"}}
+	if _, err := cg.generateCRUDOperations(mapColumns(t, cg, table)); err == nil || !strings.Contains(err.Error(), "requires create") {
+		t.Errorf("expected missing create dependency error, got %v", err)
+	}
+}
+
+func TestCodeGenerator_CustomUUIDType(t *testing.T) {
+	config := getTestConfigWithTempDir(t)
+	config.TypeMappings = map[string]string{"uuid": "mypkg.ID"}
+	config.CustomImports = map[string]string{"mypkg": "example.com/project/ids"}
+	config.TableConfigs = map[string]TableConfig{
+		"users": {
+			Functions:   []string{"get", "paginate"},
+			ColumnTypes: map[string]string{"owner_id": "mypkg.OwnerID"},
+		},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	table.Columns = append(table.Columns, Column{Name: "owner_id", Type: "uuid", IsNullable: true})
+	if err := cg.GenerateTableRepository(table); err != nil {
+		t.Fatalf("GenerateTableRepository failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(config.OutputDir, "users_generated.go"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	code := string(content)
+
+	expected := []string{
+		// The import path doesn't end in the package name, so it is named explicitly
+		`mypkg "example.com/project/ids"`,
+		"mypkg.ID ",
+		// Per-column overrides win over the uuid mapping and keep the nullable pointer form
+		"*mypkg.OwnerID ",
+		// GetID converts the custom type back to the uuid.UUID cursors are built from
+		"return uuid.UUID(u.Id)",
+		"items[len(items)-1].GetID()",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code missing %q\n%s", want, code)
+		}
+	}
+
+	config.TableConfigs["users"] = TableConfig{ColumnTypes: map[string]string{"missing": "mypkg.ID"}}
+	if err := cg.GenerateTableRepository(table); err == nil || !strings.Contains(err.Error(), "unknown column missing") {
+		t.Errorf("expected unknown column error, got %v", err)
+	}
+}
+
+func TestCodeGenerator_UpsertWithStatus(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"create", "upsert_with_status"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	table.Indexes = []Index{{Name: "idx_users_email", Columns: []string{"email"}, IsUnique: true}}
+	table = mapColumns(t, cg, table)
+
+	code, err := cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"func (r *UsersRepository) UpsertWithStatus(ctx context.Context, params CreateUsersParams) (*Users, bool, error)",
+		// The conflict target defaults to the unique email index
+		"ON CONFLICT (email) DO UPDATE SET name = EXCLUDED.name, metadata = EXCLUDED.metadata",
+		// Only freshly inserted rows have no xmax, which is scanned into the returned bool
+		"RETURNING id, name, email, is_active, created_at, metadata, (xmax = 0) AS inserted",
+		"err := row.Scan(&u.Id, &u.Name, &u.Email, &u.IsActive, &u.CreatedAt, &u.Metadata, &inserted)",
+		"return &u, inserted, nil",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("UpsertWithStatus code missing %q\n%s", want, code)
+		}
+	}
+
+	// A configured target covering every insert column re-assigns itself so the row is still returned
+	config.TableConfigs["users"] = TableConfig{
+		Functions:       []string{"create", "upsert_with_status"},
+		ConflictColumns: []string{"name", "email", "metadata"},
+	}
+	code, err = cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	if want := "ON CONFLICT (name, email, metadata) DO UPDATE SET name = EXCLUDED.name"; !strings.Contains(code, want) {
+		t.Errorf("UpsertWithStatus code missing %q\n%s", want, code)
+	}
+
+	// The generated primary key never conflicts, so it can't be the target
+	config.TableConfigs["users"] = TableConfig{
+		Functions:       []string{"create", "upsert_with_status"},
+		ConflictColumns: []string{"id"},
+	}
+	if _, err := cg.generateCRUDOperations(table); err == nil || !strings.Contains(err.Error(), "is not set by CreateUsersParams") {
+		t.Errorf("expected conflict column error, got %v", err)
+	}
+
+	table.Indexes = nil
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"create", "upsert_with_status"}}
+	if _, err := cg.generateCRUDOperations(table); err == nil || !strings.Contains(err.Error(), "configure conflict_columns") {
+		t.Errorf("expected missing unique key error, got %v", err)
+	}
+}
+
+func TestCodeGenerator_Upsert(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"create", "upsert"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	table.Indexes = []Index{{Name: "idx_users_email", Columns: []string{"email"}, IsUnique: true}}
+	table = mapColumns(t, cg, table)
+
+	code, err := cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"func (r *UsersRepository) Upsert(ctx context.Context, params CreateUsersParams) (*Users, error) {",
+		"INSERT INTO users (name, email, metadata)\n\t\tVALUES ($1, $2, $3)",
+		// The generated id never conflicts, so the target falls back to the unique email index
+		"ON CONFLICT (email) DO UPDATE SET name = EXCLUDED.name, metadata = EXCLUDED.metadata\n\t\tRETURNING id, name, email, is_active, created_at, metadata\n",
+		`ExecuteQueryRow(ctx, r.db, "upsert", "Users", query, params.Name, params.Email, params.Metadata)`,
+		`HandleQueryRowError("upsert", "Users", err)`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("Upsert code missing %q\n%s", want, code)
+		}
+	}
+
+	config.TableConfigs["users"] = TableConfig{
+		Functions:       []string{"create", "upsert"},
+		ConflictColumns: []string{"name", "email"},
+	}
+	code, err = cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	if want := "ON CONFLICT (name, email) DO UPDATE SET metadata = EXCLUDED.metadata"; !strings.Contains(code, want) {
+		t.Errorf("Upsert code missing %q\n%s", want, code)
+	}
+
+	config.TableConfigs["users"] = TableConfig{
+		Functions:       []string{"create", "upsert"},
+		ConflictColumns: []string{"nickname"},
+	}
+	if _, err := cg.generateCRUDOperations(table); err == nil || !strings.Contains(err.Error(), "conflict column nickname does not exist") {
+		t.Errorf("expected unknown conflict column error, got %v", err)
+	}
+
+	// Unique indexes the create params don't set are suggested as conflict_columns
+	table.Indexes = []Index{{Name: "idx_users_created_at_email", Columns: []string{"created_at", "email"}, IsUnique: true}}
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"create", "upsert"}}
+	if _, err := cg.generateCRUDOperations(table); err == nil || !strings.Contains(err.Error(), "(unique indexes: [created_at, email])") {
+		t.Errorf("expected missing unique key error listing alternatives, got %v", err)
+	}
+
+	// A composite primary key is set by the create params, so it is the default target
+	memberships := mapColumns(t, cg, Table{
+		Name: "memberships",
+		Columns: []Column{
+			{Name: "tenant_id", Type: "uuid"},
+			{Name: "type", Type: "text"},
+			{Name: "role", Type: "text"},
+		},
+		PrimaryKey: []string{"tenant_id", "type"},
+	})
+	config.TableConfigs["memberships"] = TableConfig{Functions: []string{"create", "upsert"}}
+	code, err = cg.generateCRUDOperations(memberships)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	if want := `ON CONFLICT (tenant_id, type) DO UPDATE SET role = EXCLUDED.role`; !strings.Contains(code, want) {
+		t.Errorf("Upsert code missing %q\n%s", want, code)
+	}
+}
+
+func TestCodeGenerator_Count(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"count"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	code, err := cg.generateCRUDOperations(mapColumns(t, cg, getTestTable()))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	expected := []string{
+		"func (r *UsersRepository) Count(ctx context.Context) (int64, error) {",
+		"SELECT COUNT(*)\n\t\tFROM users\n\t`",
+		`ExecuteQueryRow(ctx, r.db, "count", "Users", query)`,
+		`HandleQueryRowError("count", "Users", row.Scan(&count))`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("Count code missing %q\n%s", want, code)
+		}
+	}
+
+	// Soft-deleted rows are skipped like they are by List
+	table := getTestTable()
+	table.Columns = append(table.Columns, Column{Name: "deleted_at", Type: "timestamptz", IsNullable: true})
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"count"}, SoftDeleteColumn: "deleted_at"}
+	code, err = cg.generateCRUDOperations(mapColumns(t, cg, table))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	if want := "FROM users\n\t\tWHERE deleted_at IS NULL"; !strings.Contains(code, want) {
+		t.Errorf("Count code missing %q\n%s", want, code)
+	}
+}
+
+func TestCodeGenerator_Exists(t *testing.T) {
+	config := getTestConfig()
+	config.RejectNilIDs = true
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"exists"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	code, err := cg.generateCRUDOperations(mapColumns(t, cg, getTestTable()))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	expected := []string{
+		"func (r *UsersRepository) Exists(ctx context.Context, id uuid.UUID) (bool, error) {",
+		`return false, &DatabaseError{Type: ErrValidationFailed, Operation: "exists", Entity: "Users", Detail: "id must not be uuid.Nil"}`,
+		"SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)\n",
+		`ExecuteQueryRow(ctx, r.db, "exists", "Users", query, id)`,
+		`HandleQueryRowError("exists", "Users", row.Scan(&exists))`,
+		"return exists, nil",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("Exists code missing %q\n%s", want, code)
+		}
+	}
+
+	// Composite keys are matched on every key column
+	memberships := mapColumns(t, cg, Table{
+		Name: "memberships",
+		Columns: []Column{
+			{Name: "tenant_id", Type: "uuid"},
+			{Name: "slug", Type: "text"},
+			{Name: "deleted_at", Type: "timestamptz", IsNullable: true},
+		},
+		PrimaryKey: []string{"tenant_id", "slug"},
+	})
+	config.TableConfigs["memberships"] = TableConfig{Functions: []string{"exists"}, SoftDeleteColumn: "deleted_at"}
+	code, err = cg.generateCRUDOperations(memberships)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	expected = []string{
+		"func (r *MembershipsRepository) Exists(ctx context.Context, tenantId uuid.UUID, slug string) (bool, error) {",
+		"SELECT EXISTS(SELECT 1 FROM memberships WHERE tenant_id = $1 AND slug = $2 AND deleted_at IS NULL)",
+		`query, tenantId, slug)`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("Exists code missing %q\n%s", want, code)
+		}
+	}
+}
+
+func TestCodeGenerator_ListUpdatedBy(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"list_updated_by"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	// The audit column must exist
+	_, err := cg.generateCRUDOperations(mapColumns(t, cg, getTestTable()))
+	if err == nil || !strings.Contains(err.Error(), "list_updated_by requires column updated_by on table users") {
+		t.Fatalf("Expected missing updated_by column error, got %v", err)
+	}
+
+	table := getTestTable()
+	table.Columns = append(table.Columns,
+		Column{Name: "updated_at", Type: "timestamptz"},
+		Column{Name: "updated_by", Type: "uuid", IsNullable: true},
+	)
+	code, err := cg.generateCRUDOperations(mapColumns(t, cg, table))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	// A nullable uuid column is matched with its non-null type
+	expected := []string{
+		"func (r *UsersRepository) ListUpdatedBy(ctx context.Context, userID uuid.UUID) ([]Users, error) {",
+		"WHERE updated_by = $1\n",
+		"ORDER BY updated_at DESC, id ASC",
+		`ExecuteQuery(ctx, r.db, "list_updated_by", "Users", query, userID)`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("ListUpdatedBy code missing %q\n%s", want, code)
+		}
+	}
+
+	// A configured column of another type keeps that type, and reads skip soft-deleted rows
+	table = getTestTable()
+	table.Columns = append(table.Columns,
+		Column{Name: "modified_by", Type: "text"},
+		Column{Name: "deleted_at", Type: "timestamptz", IsNullable: true},
+	)
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"list_updated_by"}, UpdatedByColumn: "modified_by", SoftDeleteColumn: "deleted_at"}
+	code, err = cg.generateCRUDOperations(mapColumns(t, cg, table))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	expected = []string{
+		"ListUpdatedBy(ctx context.Context, userID string) ([]Users, error) {",
+		"WHERE modified_by = $1 AND deleted_at IS NULL",
+		"ORDER BY id ASC",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("ListUpdatedBy code missing %q\n%s", want, code)
+		}
+	}
+}
+
+func TestCodeGenerator_ContextUserID(t *testing.T) {
+	tempDir := t.TempDir()
+	config := &Config{
+		OutputDir:   tempDir,
+		PackageName: "testgen",
+	}
+	cg := NewCodeGenerator(config)
+	table := getTestTable()
+	table.Columns = append(table.Columns, Column{Name: "updated_by", Type: "uuid", IsNullable: true})
+
+	// Without context helpers the params are used as the caller set them
+	code, err := cg.generateTableCode(mapColumns(t, cg, table))
+	if err != nil {
+		t.Fatalf("generateTableCode failed: %v", err)
+	}
+	if strings.Contains(code, "UserIDFromContext") {
+		t.Errorf("Create and Update should not read the context user ID unless enabled\n%s", code)
+	}
+
+	config.GenerateContextHelpers = true
+	code, err = cg.generateTableCode(mapColumns(t, cg, table))
+	if err != nil {
+		t.Fatalf("generateTableCode failed: %v", err)
+	}
+	// Both Create and Update record the acting user in the audit column
+	assignment := "if userID, ok := UserIDFromContext(ctx); ok {\n\t\tparams.UpdatedBy = pgtype.UUID{Bytes: userID, Valid: true}\n\t}"
+	if count := strings.Count(code, assignment); count != 2 {
+		t.Errorf("Expected Create and Update to set UpdatedBy from the context, found %d assignments\n%s", count, code)
+	}
+
+	// The other write paths fill it the same way; batch methods set it on their copy of each item
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"create", "get", "update", "upsert", "upsert_with_status", "create_with_conflict", "ensure_by_unique", "create_batch", "bulk_create_ids", "bulk_update"}},
+	}
+	table.Indexes = []Index{{Name: "idx_users_email", Columns: []string{"email"}, IsUnique: true}}
+	code, err = cg.generateTableCode(mapColumns(t, cg, table))
+	if err != nil {
+		t.Fatalf("generateTableCode failed: %v", err)
+	}
+	if count := strings.Count(code, assignment); count != 6 {
+		t.Errorf("Expected Create, Update, Upsert, UpsertWithStatus, CreateWithConflict and EnsureByEmail to set UpdatedBy, found %d assignments\n%s", count, code)
+	}
+	for _, want := range []string{
+		"if userID, ok := UserIDFromContext(ctx); ok {\n\t\t\titem.UpdatedBy = pgtype.UUID{Bytes: userID, Valid: true}\n\t\t}",
+		"if userID, ok := UserIDFromContext(ctx); ok {\n\t\t\tupdate.Params.UpdatedBy = pgtype.UUID{Bytes: userID, Valid: true}\n\t\t}",
+	} {
+		if count := strings.Count(code, want); count == 0 {
+			t.Errorf("Batch write code missing %q\n%s", want, code)
+		}
+	}
+
+	if testing.Short() {
+		return
+	}
+	for _, generate := range []func() error{cg.GenerateSharedPaginationTypes, cg.GenerateSharedErrors, cg.GenerateSharedDatabaseOperations, cg.GenerateSharedRetryOperations, cg.GenerateSharedContextHelpers} {
+		if err := generate(); err != nil {
+			t.Fatalf("shared file generation failed: %v", err)
+		}
+	}
+	if err := cg.GenerateTableRepository(table); err != nil {
+		t.Fatalf("GenerateTableRepository failed: %v", err)
+	}
+	compileGeneratedCode(t, tempDir)
+}
+
+func TestCodeGenerator_CreateBatch(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"create", "create_batch"}},
+	}
+	cg := NewCodeGenerator(config)
+	table := mapColumns(t, cg, getTestTable())
+
+	code, err := cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	expected := []string{
+		"func (r *UsersRepository) CreateBatch(ctx context.Context, params []CreateUsersParams) ([]Users, error) {",
+		"if len(params) == 0 {\n\t\treturn nil, nil\n\t}",
+		// One batch holds at most 65535 / 3 rows of name, email and metadata
+		"most 65535 / 3 rows; split larger imports into several calls",
+		"const columnsPerRow = 3",
+		"if len(params)*columnsPerRow > 65535 {",
+		"query.WriteString(`INSERT INTO users (name, email, metadata) VALUES `)",
+		// Each row continues the placeholder numbering where the previous one stopped
+		`fmt.Fprintf(&query, "$%d", len(args)+j)`,
+		"args = append(args, item.Name, item.Email, item.Metadata)",
+		"query.WriteString(` RETURNING id, name, email, is_active, created_at, metadata`)",
+		"if err := rows.Scan(&u.Id, &u.Name, &u.Email, &u.IsActive, &u.CreatedAt, &u.Metadata); err != nil {",
+		"results = append(results, u)",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("CreateBatch code missing %q\n%s", want, code)
+		}
+	}
+
+	// The params type comes from Create
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"create_batch"}}
+	if _, err := cg.generateCRUDOperations(table); err == nil || !strings.Contains(err.Error(), "requires create") {
+		t.Errorf("Expected missing create error, got %v", err)
+	}
+}
+
+func TestCodeGenerator_EnsureByUnique(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"create", "ensure_by_unique"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	table.Indexes = []Index{
+		{Name: "idx_users_email", Columns: []string{"email"}, IsUnique: true},
+		// The primary key is generated, so it gets no Ensure method
+		{Name: "users_pkey", Columns: []string{"id"}, IsUnique: true},
+	}
+	table = mapColumns(t, cg, table)
+
+	code, err := cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"func (r *UsersRepository) EnsureByEmail(ctx context.Context, params CreateUsersParams) (*Users, bool, error)",
+		// Re-assigning the key on conflict returns the existing row untouched in one statement
+		"ON CONFLICT (email) DO UPDATE SET email = EXCLUDED.email",
+		"RETURNING id, name, email, is_active, created_at, metadata, (xmax = 0) AS created",
+		`ExecuteQueryRow(ctx, r.db, "ensure_by_email", "Users", query, params.Name, params.Email, params.Metadata)`,
+		"err := row.Scan(&u.Id, &u.Name, &u.Email, &u.IsActive, &u.CreatedAt, &u.Metadata, &created)",
+		"return &u, created, nil",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("EnsureByEmail code missing %q\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "EnsureById") {
+		t.Errorf("the generated primary key should not get an Ensure method\n%s", code)
+	}
+
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"ensure_by_unique"}}
+	if _, err := cg.generateCRUDOperations(table); err == nil || !strings.Contains(err.Error(), "requires create") {
+		t.Errorf("expected missing create error, got %v", err)
+	}
+
+	table.Indexes = nil
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"create", "ensure_by_unique"}}
+	if _, err := cg.generateCRUDOperations(table); err == nil || !strings.Contains(err.Error(), "requires a unique key set on create") {
+		t.Errorf("expected missing unique key error, got %v", err)
+	}
+}
+
+func TestCodeGenerator_GetManyOrdered(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"get_many_ordered"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	code, err := cg.generateCRUDOperations(mapColumns(t, cg, getTestTable()))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"func (r *UsersRepository) GetManyOrdered(ctx context.Context, ids []uuid.UUID) ([]Users, error)",
+		"WHERE id = ANY($1)",
+		// Rows come back in input order without sorting in Go
+		"ORDER BY array_position($1, id)",
+		`ExecuteQuery(ctx, r.db, "get_many_ordered", "Users", query, ids)`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("GetManyOrdered code missing %q\n%s", want, code)
+		}
+	}
+}
+
+func TestCodeGenerator_LtreeHierarchy(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"list_descendants", "list_ancestors"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	table.Columns = append(table.Columns, Column{Name: "path", Type: "ltree"})
+	table = mapColumns(t, cg, table)
+	if got := table.GetColumn("path").GoType; got != "string" {
+		t.Errorf("ltree column GoType = %s, want string", got)
+	}
+
+	code, err := cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"func (r *UsersRepository) ListDescendants(ctx context.Context, path string) ([]Users, error)",
+		"WHERE path <@ $1::ltree",
+		"func (r *UsersRepository) ListAncestors(ctx context.Context, path string) ([]Users, error)",
+		"WHERE path @> $1::ltree",
+		"ORDER BY path",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("hierarchy code missing %q\n%s", want, code)
+		}
+	}
+
+	if _, err := cg.generateCRUDOperations(mapColumns(t, cg, getTestTable())); err == nil || !strings.Contains(err.Error(), "requires an ltree column") {
+		t.Errorf("expected missing ltree column error, got %v", err)
+	}
+}
+
+func TestCodeGenerator_FetchAndLock(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"fetch_and_lock"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	code, err := cg.generateCRUDOperations(mapColumns(t, cg, getTestTable()))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		// Locks only last as long as the transaction, so the caller's transaction is required
+		"func (r *UsersRepository) FetchAndLock(ctx context.Context, tx pgx.Tx, limit int32) ([]Users, error)",
+		"ORDER BY id ASC\n\t\tLIMIT $1\n\t\tFOR UPDATE SKIP LOCKED",
+		`rows, err := ExecuteQuery(ctx, tx, "fetch_and_lock", "Users", query, limit)`,
+		"var results []Users",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("FetchAndLock code missing %q\n%s", want, code)
+		}
+	}
+	// limit comes from the caller, who may pass math.MaxInt32 to drain the queue, so nothing is sized from it
+	if strings.Contains(code, "make([]Users, 0, limit)") {
+		t.Errorf("FetchAndLock must not preallocate from limit\n%s", code)
+	}
+}
+
+func TestCodeGenerator_ListBetween(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"list_between", "list_between_paginated"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	table.Indexes = []Index{{Name: "idx_users_created_at", Columns: []string{"created_at"}}}
+	table = mapColumns(t, cg, table)
+
+	code, err := cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"func (r *UsersRepository) ListBetween(ctx context.Context, from, to time.Time) ([]Users, error)",
+		"WHERE created_at >= $1 AND created_at < $2\n\t\tORDER BY created_at ASC, id ASC",
+		`ExecuteQuery(ctx, r.db, "list_between", "Users", query, from, to)`,
+		"func (r *UsersRepository) ListBetweenPaginated(ctx context.Context, from, to time.Time, params PaginationParams) (*PaginationResult[Users], error)",
+		"if params.IncludeTotal {\n\t\treturn nil, fmt.Errorf(\"ListBetweenPaginated does not support IncludeTotal\")\n\t}",
+		"WHERE created_at >= $1 AND created_at < $2\n\t\t  AND ($3::timestamptz IS NULL OR (created_at, id) > ($3, $4))",
+		`ExecuteQuery(ctx, r.db, "list_between_paginated", "Users", query, from, to, cursorTime, cursorID, int32(limit+1))`,
+		"encodeTimeCursor(lastItem.CreatedAt, lastItem.GetID())",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("ListBetween code missing %q\n%s", want, code)
+		}
+	}
+
+	// The range column must be indexed
+	table.Indexes = nil
+	if _, err := cg.generateCRUDOperations(table); err == nil || !strings.Contains(err.Error(), "list_between requires an index on users.created_at") {
+		t.Errorf("Expected missing index error, got %v", err)
+	}
+
+	// Non-timestamp columns are rejected
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"list_between"}, RangeColumn: "name"}
+	if _, err := cg.generateCRUDOperations(table); err == nil || !strings.Contains(err.Error(), "must be a timestamp") {
+		t.Errorf("Expected timestamp type error, got %v", err)
+	}
+
+	// The cursor is cast to the column's own type, so timestamp without time zone skips the session time zone
+	table.Columns = append(table.Columns, Column{Name: "synced_at", Type: "timestamp", IsNullable: false})
+	table.Indexes = []Index{{Name: "idx_users_synced_at", Columns: []string{"synced_at"}}}
+	table = mapColumns(t, cg, table)
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"list_between_paginated"}, RangeColumn: "synced_at"}
+	code, err = cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	if !strings.Contains(code, "($3::timestamp IS NULL OR (synced_at, id) > ($3, $4))") {
+		t.Errorf("Expected the cursor cast to timestamp\n%s", code)
+	}
+}
+
+func TestCodeGenerator_BulkUpdate(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"update", "bulk_update"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := mapColumns(t, cg, getTestTable())
+	code, err := cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"type UsersUpdate struct {\n\tID     uuid.UUID\n\tParams UpdateUsersParams\n}",
+		"func (r *UsersRepository) BulkUpdate(ctx context.Context, updates []UsersUpdate) (int64, error)",
+		"UPDATE users AS t\n\t\tSET name = u.name, email = u.email, is_active = u.is_active, created_at = u.created_at, metadata = u.metadata",
+		"FROM unnest($1::uuid[], $2::text[], $3::text[], $4::boolean[], $5::timestamptz[], $6::jsonb[])",
+		"AS u(id, name, email, is_active, created_at, metadata)",
+		"WHERE t.id = u.id",
+		// One array per column, bound in the same order as the unnest arguments
+		"nameValues[i] = update.Params.Name",
+		`ExecuteNonQueryWithRowsAffected(ctx, r.db, "bulk_update", "Users", query, ids, nameValues, emailValues, isActiveValues, createdAtValues, metadataValues)`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("BulkUpdate code missing %q\n%s", want, code)
+		}
+	}
+
+	// unnest flattens nested arrays, so array columns are rejected
+	table.Columns = append(table.Columns, Column{Name: "tags", Type: "text", GoType: "[]string", IsArray: true})
+	if _, err := cg.generateCRUDOperations(table); err == nil || !strings.Contains(err.Error(), "cannot update array column tags") {
+		t.Errorf("Expected array column error, got %v", err)
+	}
+}
+
+func TestCodeGenerator_GetByUniqueConstraint(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"post_categories": {Functions: []string{"get", "get_by_unique_constraint"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := mapColumns(t, cg, Table{
+		Name:       "post_categories",
+		Schema:     "public",
+		PrimaryKey: []string{"id"},
+		Columns: []Column{
+			{Name: "id", Type: "uuid"},
+			{Name: "post_id", Type: "uuid"},
+			{Name: "category_id", Type: "uuid"},
+		},
+		UniqueConstraints: []UniqueConstraint{{Name: "post_categories_post_id_category_id_key", Columns: []string{"post_id", "category_id"}}},
+	})
+	code, err := cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"// GetByPostIdAndCategoryId retrieves the PostCategories identified by its unique post_id and category_id",
+		"func (r *PostCategoriesRepository) GetByPostIdAndCategoryId(ctx context.Context, postId uuid.UUID, categoryId uuid.UUID) (*PostCategories, error)",
+		"WHERE post_id = $1 AND category_id = $2",
+		`ExecuteQueryRow(ctx, r.db, "get_by_post_id_and_category_id", "PostCategories", query, postId, categoryId)`,
+		`HandleQueryRowError("get_by_post_id_and_category_id", "PostCategories", err)`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("unique constraint finder missing %q\n%s", want, code)
+		}
+	}
+
+	// A configured lookup sets the parameter order and must match a constraint
+	config.TableConfigs["post_categories"] = TableConfig{
+		Functions:     []string{"get", "get_by_unique_constraint"},
+		UniqueLookups: [][]string{{"category_id", "post_id"}},
+	}
+	code, err = cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	if !strings.Contains(code, "GetByCategoryIdAndPostId(ctx context.Context, categoryId uuid.UUID, postId uuid.UUID)") {
+		t.Errorf("Expected the configured parameter order\n%s", code)
+	}
+
+	config.TableConfigs["post_categories"] = TableConfig{
+		Functions:     []string{"get", "get_by_unique_constraint"},
+		UniqueLookups: [][]string{{"post_id"}},
+	}
+	if _, err := cg.generateCRUDOperations(table); err == nil || !strings.Contains(err.Error(), "does not match a unique constraint") {
+		t.Errorf("Expected an unmatched unique lookup error, got %v", err)
+	}
+}
+
+func TestCodeGenerator_SearchByPrefix(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"get", "search_by_prefix"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	table.Indexes = []Index{{Name: "users_name_idx", Columns: []string{"name"}}}
+	table = mapColumns(t, cg, table)
+	code, err := cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"func (r *UsersRepository) SearchByNamePrefix(ctx context.Context, prefix string, limit int32) ([]Users, error)",
+		`WHERE name LIKE $1 || '%'`,
+		"ORDER BY name, id ASC",
+		"LIMIT $2",
+		`ExecuteQuery(ctx, r.db, "search_by_prefix", "Users", query, EscapeLikePattern(prefix), limit)`,
+		"var results []Users",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("prefix search missing %q\n%s", want, code)
+		}
+	}
+	// limit comes from the caller, who may pass math.MaxInt32 for no limit, so nothing is sized from it
+	if strings.Contains(code, "make([]Users, 0, limit)") {
+		t.Errorf("prefix search must not preallocate from limit\n%s", code)
+	}
+
+	// prefix_column selects another text column, which must be indexed
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"search_by_prefix"}, PrefixColumn: "email"}
+	if _, err := cg.generateCRUDOperations(table); err == nil || !strings.Contains(err.Error(), "requires an index on users.email") {
+		t.Errorf("Expected an unindexed prefix column error, got %v", err)
+	}
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"search_by_prefix"}, PrefixColumn: "created_at"}
+	if _, err := cg.generateCRUDOperations(table); err == nil || !strings.Contains(err.Error(), "must be a text column") {
+		t.Errorf("Expected a non-text prefix column error, got %v", err)
+	}
+}
+
+func TestCodeGenerator_EscapeLikePattern(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compile test in short mode")
+	}
+
+	tempDir := t.TempDir()
+	cg := NewCodeGenerator(&Config{OutputDir: tempDir, PackageName: "testgen"})
+	for _, generate := range []func() error{cg.GenerateSharedErrors, cg.GenerateSharedDatabaseOperations} {
+		if err := generate(); err != nil {
+			t.Fatalf("shared file generation failed: %v", err)
+		}
+	}
+	if !compileGeneratedCode(t, tempDir) {
+		return
+	}
+
+	testContent := `package testgen
+
+import "testing"
+
+func TestEscapeLikePattern(t *testing.T) {
+	for input, want := range map[string]string{
+		"ann":        "ann",
+		"50%":        "50\\%",
+		"snake_case": "snake\\_case",
+		"C:\\dir":    "C:\\\\dir",
+	} {
+		if got := EscapeLikePattern(input); got != want {
+			t.Errorf("EscapeLikePattern(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "escape_test.go"), []byte(testContent), 0644); err != nil {
+		t.Fatalf("Failed to write generated package test: %v", err)
+	}
+
+	testCmd := exec.Command("go", "test", "./...")
+	testCmd.Dir = tempDir
+	testCmd.Env = append(os.Environ(), "GO111MODULE=on")
+	if output, err := testCmd.CombinedOutput(); err != nil {
+		t.Errorf("Generated EscapeLikePattern test failed: %v\nOutput: %s", err, string(output))
+	}
+}
+
+func TestCodeGenerator_BatchChunkSize(t *testing.T) {
+	config := getTestConfig()
+	config.BatchChunkSize = 1000
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"create", "update", "create_batch", "bulk_create_ids", "bulk_update"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := mapColumns(t, cg, getTestTable())
+	code, err := cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		// The public methods walk the input in chunks, checking ctx before each one
+		"const chunkSize = 1000",
+		"for start := 0; start < len(params); start += chunkSize {\n\t\tif err := ctx.Err(); err != nil {",
+		"r.createBatchChunk(ctx, params[start:min(start+chunkSize, len(params))])",
+		"r.bulkCreateIDsChunk(ctx, items[start:min(start+chunkSize, len(items))])",
+		"r.bulkUpdateChunk(ctx, updates[start:min(start+chunkSize, len(updates))])",
+		// Chunks committed before a failure are reported with the error
+		"if err != nil {\n\t\t\treturn results, err\n\t\t}\n\t\tresults = append(results, created...)",
+		"if err != nil {\n\t\t\treturn ids, err\n\t\t}\n\t\tids = append(ids, created...)",
+		"if err != nil {\n\t\t\treturn total, err\n\t\t}\n\t\ttotal += updated",
+		// Each chunk runs as the single statement the unchunked methods execute
+		"func (r *UsersRepository) createBatchChunk(ctx context.Context, params []CreateUsersParams) ([]Users, error)",
+		"func (r *UsersRepository) bulkCreateIDsChunk(ctx context.Context, items []CreateUsersParams) ([]uuid.UUID, error)",
+		"func (r *UsersRepository) bulkUpdateChunk(ctx context.Context, updates []UsersUpdate) (int64, error)",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("chunked batch code missing %q\n%s", want, code)
+		}
+	}
+
+	// Without a chunk size every call stays a single statement
+	config.BatchChunkSize = 0
+	code, err = cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	if strings.Contains(code, "Chunk(") {
+		t.Errorf("Expected no chunked methods without batch_chunk_size\n%s", code)
+	}
+
+	// A chunk of inserts must fit PostgreSQL's bind parameter limit
+	config.BatchChunkSize = 50000
+	if _, err := cg.generateCRUDOperations(table); err == nil || !strings.Contains(err.Error(), "parameter") {
+		t.Errorf("Expected a parameter limit error, got %v", err)
+	}
+}
+
+func TestCodeGenerator_BatchChunkSizeCancellation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compile test in short mode")
+	}
+
+	tempDir := t.TempDir()
+	config := &Config{
+		OutputDir:      tempDir,
+		PackageName:    "testgen",
+		QuerierPerCall: true,
+		BatchChunkSize: 2,
+		TableConfigs: map[string]TableConfig{
+			"users": {Functions: []string{"update", "bulk_update"}},
+		},
+	}
+	cg := NewCodeGenerator(config)
+	for _, generate := range []func() error{cg.GenerateSharedErrors, cg.GenerateSharedDatabaseOperations, cg.GenerateSharedRetryOperations} {
+		if err := generate(); err != nil {
+			t.Fatalf("shared file generation failed: %v", err)
+		}
+	}
+	if err := cg.GenerateTableRepository(getTestTable()); err != nil {
+		t.Fatalf("GenerateTableRepository failed: %v", err)
+	}
+	if !compileGeneratedCode(t, tempDir) {
+		return
+	}
+
+	// A recording Querier counts the statements BulkUpdate runs and can cancel ctx after the first
+	testContent := `package testgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type recordingQuerier struct {
+	statements int
+	cancel     context.CancelFunc
+}
+
+func (q *recordingQuerier) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	q.statements++
+	if q.cancel != nil {
+		q.cancel()
+	}
+	return pgconn.NewCommandTag(fmt.Sprintf("UPDATE %d", len(args[0].([]uuid.UUID)))), nil
+}
+
+func (q *recordingQuerier) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return nil, errors.New("unexpected Query")
+}
+
+func (q *recordingQuerier) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return nil
+}
+
+func TestBulkUpdateChunks(t *testing.T) {
+	updates := make([]UsersUpdate, 5)
+
+	q := &recordingQuerier{}
+	updated, err := NewUsersRepository().BulkUpdate(context.Background(), q, updates)
+	if err != nil {
+		t.Fatalf("BulkUpdate failed: %v", err)
+	}
+	if q.statements != 3 || updated != 5 {
+		t.Errorf("BulkUpdate ran %d statements updating %d rows, want 3 and 5", q.statements, updated)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q = &recordingQuerier{cancel: cancel}
+	updated, err = NewUsersRepository().BulkUpdate(ctx, q, updates)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("BulkUpdate error = %v, want context.Canceled", err)
+	}
+	// The committed first chunk is still counted
+	if q.statements != 1 || updated != 2 {
+		t.Errorf("BulkUpdate ran %d statements updating %d rows after cancellation, want 1 and 2", q.statements, updated)
+	}
+}
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "chunks_test.go"), []byte(testContent), 0644); err != nil {
+		t.Fatalf("Failed to write generated package test: %v", err)
+	}
+
+	testCmd := exec.Command("go", "test", "./...")
+	testCmd.Dir = tempDir
+	testCmd.Env = append(os.Environ(), "GO111MODULE=on")
+	if output, err := testCmd.CombinedOutput(); err != nil {
+		t.Errorf("Generated chunked batch test failed: %v\nOutput: %s", err, string(output))
+	}
+}
+
+func TestCodeGenerator_WithTx(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compile test in short mode")
+	}
+
+	tempDir := t.TempDir()
+	config := &Config{
+		OutputDir:          tempDir,
+		PackageName:        "testgen",
+		GenerateInterfaces: true,
+		TableConfigs: map[string]TableConfig{
+			"users": {Functions: []string{"create", "delete"}},
+			"posts": {Functions: []string{"delete"}},
+		},
+	}
+	cg := NewCodeGenerator(config)
+	for _, generate := range []func() error{cg.GenerateSharedErrors, cg.GenerateSharedDatabaseOperations, cg.GenerateSharedRetryOperations} {
+		if err := generate(); err != nil {
+			t.Fatalf("shared file generation failed: %v", err)
+		}
+	}
+	posts := Table{
+		Name:   "posts",
+		Schema: "public",
+		Columns: []Column{
+			{Name: "id", Type: "uuid", GoType: "uuid.UUID"},
+			{Name: "user_id", Type: "uuid", GoType: "uuid.UUID"},
+			{Name: "title", Type: "text", GoType: "string"},
+		},
+		PrimaryKey: []string{"id"},
+	}
+	for _, table := range []Table{getTestTable(), posts} {
+		if err := cg.GenerateTableRepository(table); err != nil {
+			t.Fatalf("GenerateTableRepository failed: %v", err)
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "users_generated.go"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	if !strings.Contains(string(content), "\tdb Querier\n") || !strings.Contains(string(content), "func (r *UsersRepository) WithTx(tx pgx.Tx) *UsersRepository {") {
+		t.Errorf("repository does not hold a Querier with a WithTx method\n%s", content)
+	}
+	if strings.Contains(string(content), "\tWithTx(") {
+		t.Errorf("repository interface lists WithTx\n%s", content)
+	}
+	if !compileGeneratedCode(t, tempDir) {
+		return
+	}
+
+	// The repositories hold no database, so every statement must reach the shared transaction, which
+	// only applies what it ran once committed
+	testContent := `package testgen
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type fakeTx struct {
+	pgx.Tx
+	pending   []string
+	committed []string
+}
+
+func (tx *fakeTx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	tx.pending = append(tx.pending, sql)
+	return pgconn.NewCommandTag("DELETE 1"), nil
+}
+
+func (tx *fakeTx) Commit(ctx context.Context) error {
+	tx.committed = append(tx.committed, tx.pending...)
+	tx.pending = nil
+	return nil
+}
+
+func (tx *fakeTx) Rollback(ctx context.Context) error {
+	tx.pending = nil
+	return nil
+}
+
+func TestWithTxSharesTransaction(t *testing.T) {
+	ctx := context.Background()
+	tx := &fakeTx{}
+	users := NewUsersRepository(nil).WithTx(tx)
+	posts := NewPostsRepository(nil).WithTx(tx)
+
+	if err := posts.Delete(ctx, uuid.New()); err != nil {
+		t.Fatalf("posts Delete failed: %v", err)
+	}
+	if err := users.Delete(ctx, uuid.New()); err != nil {
+		t.Fatalf("users Delete failed: %v", err)
+	}
+	if len(tx.pending) != 2 {
+		t.Fatalf("transaction ran %d statements, want both deletes", len(tx.pending))
+	}
+
+	if err := tx.Rollback(ctx); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if len(tx.pending) != 0 || len(tx.committed) != 0 {
+		t.Errorf("rolled back transaction kept %v pending and %v committed", tx.pending, tx.committed)
+	}
+}
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "with_tx_test.go"), []byte(testContent), 0644); err != nil {
+		t.Fatalf("Failed to write generated package test: %v", err)
+	}
+
+	testCmd := exec.Command("go", "test", "./...")
+	testCmd.Dir = tempDir
+	testCmd.Env = append(os.Environ(), "GO111MODULE=on")
+	if output, err := testCmd.CombinedOutput(); err != nil {
+		t.Errorf("Generated WithTx test failed: %v\nOutput: %s", err, string(output))
+	}
+}
+
+func TestCodeGenerator_GenerateInterfaces(t *testing.T) {
+	config := getTestConfig()
+	config.GenerateInterfaces = true
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"create", "get"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := mapColumns(t, cg, getTestTable())
+	code, err := cg.generateTableCode(table)
+	if err != nil {
+		t.Fatalf("generateTableCode failed: %v", err)
+	}
+
+	expected := []string{
+		"type UsersRepositoryInterface interface {",
+		"\tCreate(ctx context.Context, params CreateUsersParams) (*Users, error)\n",
+		"\tGet(ctx context.Context, id uuid.UUID) (*Users, error)\n",
+		"var _ UsersRepositoryInterface = (*UsersRepository)(nil)",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("repository interface missing %q\n%s", want, code)
+		}
+	}
+	// Every interface method is declared with the same signature on the repository
+	for _, method := range []string{"Create(ctx context.Context, params CreateUsersParams) (*Users, error)", "Get(ctx context.Context, id uuid.UUID) (*Users, error)"} {
+		if !strings.Contains(code, "func (r *UsersRepository) "+method+" {") {
+			t.Errorf("repository declares no method %s", method)
+		}
+	}
+	if strings.Contains(code, "\tList(ctx context.Context)") {
+		t.Errorf("interface lists List, which is not generated\n%s", code)
+	}
+
+	// Changing the function list changes the interface with it
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"get", "list"}}
+	code, err = cg.generateTableCode(table)
+	if err != nil {
+		t.Fatalf("generateTableCode failed: %v", err)
+	}
+	if !strings.Contains(code, "\tList(ctx context.Context) ([]Users, error)\n") || strings.Contains(code, "\tCreate(") {
+		t.Errorf("interface does not follow the function list\n%s", code)
+	}
+
+	config.GenerateInterfaces = false
+	code, err = cg.generateTableCode(table)
+	if err != nil {
+		t.Fatalf("generateTableCode failed: %v", err)
+	}
+	if strings.Contains(code, "RepositoryInterface") {
+		t.Errorf("Expected no interface without generate_interfaces\n%s", code)
+	}
+}
+
+func TestCodeGenerator_GenerateMetrics(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compile test in short mode")
+	}
+
+	tempDir := t.TempDir()
+	config := &Config{
+		OutputDir:       tempDir,
+		PackageName:     "testgen",
+		QuerierPerCall:  true,
+		GenerateMetrics: true,
+		TableConfigs: map[string]TableConfig{
+			"users": {Functions: []string{"get", "delete", "list"}},
+		},
+	}
+	cg := NewCodeGenerator(config)
+	for _, generate := range []func() error{cg.GenerateSharedErrors, cg.GenerateSharedDatabaseOperations, cg.GenerateSharedRetryOperations} {
+		if err := generate(); err != nil {
+			t.Fatalf("shared file generation failed: %v", err)
+		}
+	}
+	if err := cg.GenerateTableRepository(getTestTable()); err != nil {
+		t.Fatalf("GenerateTableRepository failed: %v", err)
+	}
+	if !compileGeneratedCode(t, tempDir) {
+		return
+	}
+
+	// A stub Querier answers each statement kind without a database, and the recorder
+	// collects what the generated helpers report
+	testContent := `package testgen
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var errQuery = errors.New("query failed")
+
+type stubRow struct{}
+
+func (stubRow) Scan(dest ...any) error { return pgx.ErrNoRows }
+
+type stubQuerier struct{}
+
+func (stubQuerier) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.NewCommandTag("DELETE 1"), nil
+}
+
+func (stubQuerier) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return nil, errQuery
+}
+
+func (stubQuerier) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return stubRow{}
+}
+
+type recorded struct {
+	entityLabel, operation string
+	err                    error
+}
+
+type recorder struct{ queries []recorded }
+
+func (r *recorder) RecordQuery(entityLabel, operation string, duration time.Duration, err error) {
+	r.queries = append(r.queries, recorded{entityLabel, operation, err})
+}
+
+func TestMetricsRecorder(t *testing.T) {
+	rec := &recorder{}
+	SetMetricsRecorder(rec)
+	defer SetMetricsRecorder(nil)
+
+	ctx := context.Background()
+	repo := NewUsersRepository()
+	repo.Get(ctx, stubQuerier{}, uuid.New())
+	repo.Delete(ctx, stubQuerier{}, uuid.New())
+	repo.List(ctx, stubQuerier{})
+
+	want := []recorded{
+		{"Users", "get", pgx.ErrNoRows},
+		{"Users", "delete", nil},
+		{"Users", "list", errQuery},
+	}
+	if len(rec.queries) != len(want) {
+		t.Fatalf("recorded %+v, want %+v", rec.queries, want)
+	}
+	for i := range want {
+		if rec.queries[i].entityLabel != want[i].entityLabel || rec.queries[i].operation != want[i].operation || !errors.Is(rec.queries[i].err, want[i].err) {
+			t.Errorf("query %d recorded as %+v, want %+v", i, rec.queries[i], want[i])
+		}
+	}
+
+	// Without a recorder the measurements are discarded
+	SetMetricsRecorder(nil)
+	repo.Delete(ctx, stubQuerier{}, uuid.New())
+	if len(rec.queries) != len(want) {
+		t.Errorf("recorder called after being removed: %+v", rec.queries)
+	}
+}
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "metrics_test.go"), []byte(testContent), 0644); err != nil {
+		t.Fatalf("Failed to write generated package test: %v", err)
+	}
+
+	testCmd := exec.Command("go", "test", "./...")
+	testCmd.Dir = tempDir
+	testCmd.Env = append(os.Environ(), "GO111MODULE=on")
+	if output, err := testCmd.CombinedOutput(); err != nil {
+		t.Errorf("Generated metrics test failed: %v\nOutput: %s", err, string(output))
+	}
+}
+
+func TestCodeGenerator_QuerierPerCall(t *testing.T) {
+	config := getTestConfigWithTempDir(t)
+	config.QuerierPerCall = true
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"create", "get", "update", "list", "count_by"}, CountByColumns: []string{"email"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	table.Indexes = []Index{{Name: "idx_users_email", Columns: []string{"email"}, IsUnique: true}}
+	if err := cg.GenerateTableRepository(table); err != nil {
+		t.Fatalf("GenerateTableRepository failed: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(config.OutputDir, "users_generated.go"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	code := string(content)
+
+	expected := []string{
+		// The repository holds no connection
+		"type UsersRepository struct{}",
+		"func NewUsersRepository() *UsersRepository {",
+		// The executor follows ctx in every method and is used instead of r.db
+		"func (r *UsersRepository) Get(ctx context.Context, q Querier, id uuid.UUID) (*Users, error)",
+		`ExecuteQueryRow(ctx, q, "get", "Users", query, id)`,
+		"func (r *UsersRepository) CountByEmail(ctx context.Context, q Querier) (map[string]int64, error)",
+		// Wrappers pass it through
+		"return r.Get(ctx, q, id)",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code missing %q\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "r.db") {
+		t.Errorf("Expected no stored connection in querier_per_call mode\n%s", code)
+	}
+
+	// A pool and a transaction must both be accepted as the Querier parameter
+	if err := cg.GenerateSharedDatabaseOperations(); err != nil {
+		t.Fatalf("GenerateSharedDatabaseOperations failed: %v", err)
+	}
+	methods := querierMethods(t, filepath.Join(config.OutputDir, "database_operations.go"))
+	if len(methods) == 0 {
+		t.Fatal("Querier interface not found in database_operations.go")
+	}
+	db := reflect.ValueOf(pgxkit.NewDB())
+	tx := reflect.TypeOf((*pgx.Tx)(nil)).Elem()
+	for name, signature := range methods {
+		dbMethod := db.MethodByName(name)
+		if !dbMethod.IsValid() || normalizeSignature(dbMethod.Type().String()) != signature {
+			t.Errorf("*pgxkit.DB does not satisfy Querier.%s %s", name, signature)
+		}
+		txMethod, ok := tx.MethodByName(name)
+		if !ok || normalizeSignature(txMethod.Type.String()) != signature {
+			t.Errorf("pgx.Tx does not satisfy Querier.%s %s", name, signature)
+		}
+	}
+}
+
+// querierMethods parses the generated Querier interface into method names and their
+// signatures, written the way reflect prints func types
+func querierMethods(t *testing.T, filename string) map[string]string {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, nil, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %v", filename, err)
+	}
+
+	typeString := func(expr ast.Expr) string {
+		var buf bytes.Buffer
+		if err := printer.Fprint(&buf, fset, expr); err != nil {
+			t.Fatalf("Failed to print type: %v", err)
+		}
+		return buf.String()
+	}
+	fieldTypes := func(fields *ast.FieldList) []string {
+		var types []string
+		if fields == nil {
+			return types
+		}
+		for _, field := range fields.List {
+			for range max(len(field.Names), 1) {
+				types = append(types, typeString(field.Type))
+			}
+		}
+		return types
+	}
+
+	methods := make(map[string]string)
+	ast.Inspect(file, func(n ast.Node) bool {
+		spec, ok := n.(*ast.TypeSpec)
+		if !ok || spec.Name.Name != "Querier" {
+			return true
+		}
+		for _, method := range spec.Type.(*ast.InterfaceType).Methods.List {
+			fn := method.Type.(*ast.FuncType)
+			signature := "func(" + strings.Join(fieldTypes(fn.Params), ", ") + ")"
+			if results := fieldTypes(fn.Results); len(results) == 1 {
+				signature += " " + results[0]
+			} else if len(results) > 1 {
+				signature += " (" + strings.Join(results, ", ") + ")"
+			}
+			methods[method.Names[0].Name] = normalizeSignature(signature)
+		}
+		return false
+	})
+	return methods
+}
+
+// normalizeSignature drops spacing differences such as reflect's "interface {}"
+func normalizeSignature(signature string) string {
+	return strings.ReplaceAll(signature, " ", "")
+}
+
+func TestCodeGenerator_GenerateSchemaHash(t *testing.T) {
+	config := getTestConfigWithTempDir(t)
+	config.Schema = "public"
+	cg := NewCodeGenerator(config)
+
+	if err := cg.GenerateSchemaHash("5d41402abc4b2a76b9719d911017c592", []string{"posts", "users"}); err != nil {
+		t.Fatalf("GenerateSchemaHash failed: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(config.OutputDir, "schema_hash.go"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	code := string(content)
+
+	expected := []string{
+		`const SchemaHash = "5d41402abc4b2a76b9719d911017c592"`,
+		`var schemaHashTables = []string{"posts", "users"}`,
+		"func VerifySchemaHash(ctx context.Context, db *pgxkit.DB) error",
+		`db.QueryRow(ctx, query, "public", schemaHashTables).Scan(&hash)`,
+		"if hash != SchemaHash {",
+		// The runtime check must run the exact query the generator hashed with
+		strings.TrimSpace(schemaHashQuery),
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("schema hash code missing %q\n%s", want, code)
+		}
+	}
+}
+
+func TestCodeGenerator_CompositePrimaryKey(t *testing.T) {
+	config := getTestConfigWithTempDir(t)
+	config.RejectNilIDs = true
+	cg := NewCodeGenerator(config)
+
+	table := Table{
+		Name: "memberships",
+		Columns: []Column{
+			{Name: "tenant_id", Type: "uuid"},
+			{Name: "type", Type: "text"},
+			{Name: "role", Type: "text"},
+			{Name: "created_at", Type: "timestamptz", DefaultValue: "now()"},
+		},
+		PrimaryKey: []string{"tenant_id", "type"},
+	}
+	// Default functions include update and paginate, which composite keys don't support
+	if err := cg.GenerateTableRepository(table); err != nil {
+		t.Fatalf("GenerateTableRepository failed: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(config.OutputDir, table.GoFileName()))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	code := string(content)
+
+	expected := []string{
+		// Key columns are set on create like any other column
+		"INSERT INTO memberships (tenant_id, type, role)",
+		// Parameters are named after the key columns, avoiding Go keywords
+		"func (r *MembershipsRepository) Get(ctx context.Context, tenantId uuid.UUID, typeKey string) (*Memberships, error) {",
+		`if tenantId == uuid.Nil {`,
+		`Detail: "tenantId must not be uuid.Nil"`,
+		"WHERE tenant_id = $1 AND type = $2",
+		`ExecuteQueryRow(ctx, r.db, "get", "Memberships", query, tenantId, typeKey)`,
+		"func (r *MembershipsRepository) Delete(ctx context.Context, tenantId uuid.UUID, typeKey string) error {",
+		"`DELETE FROM memberships WHERE tenant_id = $1 AND type = $2`",
+		"ORDER BY tenant_id ASC, type ASC",
+		"func (r *MembershipsRepository) GetWithRetry(ctx context.Context, tenantId uuid.UUID, typeKey string) (*Memberships, error) {",
+		"return r.Get(ctx, tenantId, typeKey)",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("composite key code missing %q\n%s", want, code)
+		}
+	}
+	for _, unwanted := range []string{"func (r *MembershipsRepository) Update(", "UpdateWithRetry", "GetID()", "ListPaginated"} {
+		if strings.Contains(code, unwanted) {
+			t.Errorf("composite key code should not contain %q\n%s", unwanted, code)
+		}
+	}
+}
+
+func TestCodeGenerator_GenerateEnums(t *testing.T) {
+	config := getTestConfigWithTempDir(t)
+	cg := NewCodeGenerator(config)
+
+	// Without enum columns there is nothing to generate
+	if err := cg.GenerateEnums(); err != nil {
+		t.Fatalf("GenerateEnums failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(config.OutputDir, "enums.go")); !os.IsNotExist(err) {
+		t.Fatalf("enums.go should not be generated without enum columns, stat error = %v", err)
+	}
+
+	table := getTestTable()
+	table.Columns = append(table.Columns, Column{
+		Name: "status", Type: "public.user_status", IsNullable: true,
+		EnumName: "user_status", EnumValues: []string{"active", "on hold", "on-hold", "!"},
+	})
+	if err := cg.GenerateTableRepository(table); err != nil {
+		t.Fatalf("GenerateTableRepository failed: %v", err)
+	}
+	if err := cg.GenerateEnums(); err != nil {
+		t.Fatalf("GenerateEnums failed: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(config.OutputDir, "enums.go"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	code := string(content)
+
+	expected := []string{
+		"type UserStatus string",
+		`UserStatusActive UserStatus = "active"`,
+		`UserStatusOnHold UserStatus = "on hold"`,
+		// Labels without a distinct identifier fall back to their position
+		`UserStatusValue3 UserStatus = "on-hold"`,
+		`UserStatusValue4 UserStatus = "!"`,
+		"func (v UserStatus) Valid() bool {",
+		"case UserStatusActive, UserStatusOnHold, UserStatusValue3, UserStatusValue4:",
+		`var enumTypeNames = []string{"public.user_status", "public._user_status"}`,
+		"func RegisterEnumTypes(ctx context.Context, conn *pgx.Conn) error {",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("enums code missing %q\n%s", want, code)
+		}
+	}
+
+	repository, err := os.ReadFile(filepath.Join(config.OutputDir, table.GoFileName()))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	if !regexp.MustCompile(`Status\s+\*UserStatus`).Match(repository) {
+		t.Errorf("nullable enum column should map to *UserStatus:\n%s", repository)
+	}
+}
+
+func TestCodeGenerator_GenerateGeometry(t *testing.T) {
+	config := getTestConfigWithTempDir(t)
+	config.GeometryMode = GeometryModeStruct
+	cg := NewCodeGenerator(config)
+
+	// Without point columns there is nothing to generate
+	if err := cg.GenerateGeometry(); err != nil {
+		t.Fatalf("GenerateGeometry failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(config.OutputDir, "geometry.go")); !os.IsNotExist(err) {
+		t.Fatalf("geometry.go should not be generated without point columns, stat error = %v", err)
+	}
+
+	table := getTestTable()
+	table.Columns = append(table.Columns, Column{Name: "location", Type: "point", IsNullable: true})
+	if err := cg.GenerateTableRepository(table); err != nil {
+		t.Fatalf("GenerateTableRepository failed: %v", err)
+	}
+	if err := cg.GenerateGeometry(); err != nil {
+		t.Fatalf("GenerateGeometry failed: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(config.OutputDir, "geometry.go"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	code := string(content)
+
+	expected := []string{
+		"type Point struct {",
+		"func (p *Point) ScanPoint(v pgtype.Point) error {",
+		"*p = Point{X: v.P.X, Y: v.P.Y}",
+		"func (p Point) PointValue() (pgtype.Point, error) {",
+		"return pgtype.Point{P: pgtype.Vec2{X: p.X, Y: p.Y}, Valid: true}, nil",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("geometry code missing %q\n%s", want, code)
+		}
+	}
+
+	repository, err := os.ReadFile(filepath.Join(config.OutputDir, table.GoFileName()))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	if !regexp.MustCompile(`Location\s+\*Point`).Match(repository) {
+		t.Errorf("nullable point column should map to *Point:\n%s", repository)
+	}
+}
+
+func TestCodeGenerator_EncryptedColumns(t *testing.T) {
+	config := getTestConfigWithTempDir(t)
+	config.EncryptedColumns = []string{"users.email"}
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"create", "get", "list_filtered"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	// Nothing is encrypted until a configured column has been mapped
+	if err := cg.GenerateEncryption(); err != nil {
+		t.Fatalf("GenerateEncryption failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(config.OutputDir, "encryption.go")); !os.IsNotExist(err) {
+		t.Fatalf("encryption.go should not be generated before an encrypted column is mapped, stat error = %v", err)
+	}
+
+	table := getTestTable()
+	if err := cg.GenerateTableRepository(table); err != nil {
+		t.Fatalf("GenerateTableRepository failed: %v", err)
+	}
+	if err := cg.GenerateEncryption(); err != nil {
+		t.Fatalf("GenerateEncryption failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(config.OutputDir, table.GoFileName()))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	repository := string(content)
+	// The column is typed EncryptedString, so the insert arguments go through Value (encrypt)
+	// and every row scan goes through Scan (decrypt)
+	for _, pattern := range []string{
+		`Email\s+EncryptedString`,
+		`(?s)INSERT INTO users.*params\.Email`,
+		`(?s)RETURNING.*Scan\([^)]*&\w+\.Email`,
+	} {
+		if !regexp.MustCompile(pattern).MatchString(repository) {
+			t.Errorf("repository code does not match %q\n%s", pattern, repository)
+		}
+	}
+	// Ciphertext cannot be compared against plaintext filter values
+	filter := regexp.MustCompile(`(?s)type UsersFilter struct \{.*?\n\}`).FindString(repository)
+	if filter == "" || !strings.Contains(filter, "Name ") || strings.Contains(filter, "Email") {
+		t.Errorf("filter should skip the encrypted column\n%s", filter)
+	}
+
+	content, err = os.ReadFile(filepath.Join(config.OutputDir, "encryption.go"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	expected := []string{
+		"type Cryptor interface {",
+		"func SetCryptor(c Cryptor) {",
+		"type EncryptedString string",
+		"ciphertext, err := c.Encrypt([]byte(s))",
+		"plaintext, err := c.Decrypt(ciphertext)",
+	}
+	for _, want := range expected {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("encryption code missing %q\n%s", want, content)
+		}
+	}
+}
+
+func TestCodeGenerator_EncryptedColumnLookups(t *testing.T) {
+	config := getTestConfig()
+	config.EncryptedColumns = []string{"users.email"}
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"create", "get_by_unique", "ensure_by_unique", "get_by_unique_constraint", "export_csv"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	table.Indexes = []Index{
+		{Name: "users_email_key", Columns: []string{"email"}, IsUnique: true},
+		{Name: "users_name_key", Columns: []string{"name"}, IsUnique: true},
+	}
+	table.UniqueConstraints = []UniqueConstraint{
+		{Name: "users_email_name_key", Columns: []string{"email", "name"}},
+		{Name: "users_name_created_at_key", Columns: []string{"name", "created_at"}},
+	}
+	table = mapColumns(t, cg, table)
+
+	code, err := cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	// Auto-detected keys over the encrypted column are skipped, the others are still generated
+	for _, want := range []string{"GetByNames(", "EnsureByName(", "GetByNameAndCreatedAt("} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code missing %q\n%s", want, code)
+		}
+	}
+	for _, unwanted := range []string{"GetByEmails(", "EnsureByEmail(", "GetByEmailAndName("} {
+		if strings.Contains(code, unwanted) {
+			t.Errorf("Generated code should not look up the encrypted column with %q\n%s", unwanted, code)
+		}
+	}
+	// ExportCSV writes the plaintext instead of re-encrypting it through Value
+	if !strings.Contains(code, "string(u.Email)") {
+		t.Errorf("ExportCSV should write the encrypted column's plaintext\n%s", code)
+	}
+
+	// Explicitly configured columns are errors
+	for name, tableConfig := range map[string]TableConfig{
+		"unique lookup":    {Functions: []string{"get_by_unique_constraint"}, UniqueLookups: [][]string{{"email", "name"}}},
+		"count_by":         {Functions: []string{"count_by"}, CountByColumns: []string{"email"}},
+		"search_by_prefix": {Functions: []string{"search_by_prefix"}, PrefixColumn: "email"},
+	} {
+		config.TableConfigs["users"] = tableConfig
+		if _, err := cg.generateCRUDOperations(table); err == nil || !strings.Contains(err.Error(), "encrypted") {
+			t.Errorf("%s: expected an encrypted column error, got %v", name, err)
+		}
+	}
+
+	// With only an encrypted constraint left there is nothing to look up by
+	table.UniqueConstraints = table.UniqueConstraints[:1]
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"get_by_unique_constraint"}}
+	if _, err := cg.generateCRUDOperations(table); err == nil || !strings.Contains(err.Error(), "without encrypted columns") {
+		t.Errorf("Expected a missing unique constraint error, got %v", err)
+	}
+}
+
+func TestCodeGenerator_NullableJSONMapping(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compile test in short mode")
+	}
+
+	tempDir := t.TempDir()
+	config := &Config{
+		OutputDir:          tempDir,
+		PackageName:        "testgen",
+		ColumnTypeMappings: map[string]string{"users.settings": "UserSettings"},
+		TableConfigs: map[string]TableConfig{
+			"users": {Functions: []string{"create", "get"}},
+		},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	table.Columns = append(table.Columns, Column{Name: "settings", Type: "jsonb", IsNullable: true})
+	for _, generate := range []func() error{cg.GenerateSharedErrors, cg.GenerateSharedDatabaseOperations, cg.GenerateSharedRetryOperations} {
+		if err := generate(); err != nil {
+			t.Fatalf("shared file generation failed: %v", err)
+		}
+	}
+	if err := cg.GenerateTableRepository(table); err != nil {
+		t.Fatalf("GenerateTableRepository failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tempDir, table.GoFileName()))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	if !regexp.MustCompile(`Settings\s+\*UserSettings`).Match(content) {
+		t.Fatalf("nullable jsonb column should map to *UserSettings:\n%s", content)
+	}
+
+	types := "package testgen\n\ntype UserSettings struct {\n\tTheme string `json:\"theme\"`\n}\n"
+	if err := os.WriteFile(filepath.Join(tempDir, "settings.go"), []byte(types), 0644); err != nil {
+		t.Fatalf("Failed to write settings type: %v", err)
+	}
+	if !compileGeneratedCode(t, tempDir) {
+		return
+	}
+
+	// Scan through the codec pgx uses for jsonb: NULL leaves the field nil instead of unmarshaling
+	// into a zero value, and a nil field is written as NULL
+	testContent := `package testgen
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestNullableSettings(t *testing.T) {
+	m := pgtype.NewMap()
+
+	u := Users{Settings: &UserSettings{Theme: "stale"}}
+	if err := m.Scan(pgtype.JSONBOID, pgtype.TextFormatCode, nil, &u.Settings); err != nil {
+		t.Fatalf("Scan(NULL) failed: %v", err)
+	}
+	if u.Settings != nil {
+		t.Errorf("Settings = %+v after scanning NULL, want nil", u.Settings)
+	}
+
+	if err := m.Scan(pgtype.JSONBOID, pgtype.TextFormatCode, []byte(` + "`" + `{"theme":"dark"}` + "`" + `), &u.Settings); err != nil {
+		t.Fatalf("Scan(json) failed: %v", err)
+	}
+	if u.Settings == nil || u.Settings.Theme != "dark" {
+		t.Errorf("Settings = %+v, want Theme dark", u.Settings)
+	}
+
+	buf, err := m.Encode(pgtype.JSONBOID, pgtype.TextFormatCode, CreateUsersParams{}.Settings, nil)
+	if err != nil {
+		t.Fatalf("Encode(nil) failed: %v", err)
+	}
+	if buf != nil {
+		t.Errorf("Encode(nil) = %q, want NULL", buf)
+	}
+}
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "settings_test.go"), []byte(testContent), 0644); err != nil {
+		t.Fatalf("Failed to write generated package test: %v", err)
+	}
+
+	testCmd := exec.Command("go", "test", "./...")
+	testCmd.Dir = tempDir
+	testCmd.Env = append(os.Environ(), "GO111MODULE=on")
+	if output, err := testCmd.CombinedOutput(); err != nil {
+		t.Errorf("Generated nullable JSON test failed: %v\nOutput: %s", err, string(output))
+	}
+}
+
+func TestCodeGenerator_EncryptedStringRoundTrip(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compile test in short mode")
+	}
+
+	tempDir := t.TempDir()
+	config := &Config{
+		OutputDir:        tempDir,
+		PackageName:      "testgen",
+		EncryptedColumns: []string{"users.email", "users.ssn"},
+		TableConfigs: map[string]TableConfig{
+			"users": {Functions: []string{"export_csv"}},
+		},
+	}
+	cg := NewCodeGenerator(config)
+	for _, generate := range []func() error{cg.GenerateSharedErrors, cg.GenerateSharedDatabaseOperations, cg.GenerateSharedRetryOperations} {
+		if err := generate(); err != nil {
+			t.Fatalf("shared file generation failed: %v", err)
+		}
+	}
+	table := getTestTable()
+	table.Columns = append(table.Columns, Column{Name: "ssn", Type: "text", IsNullable: true})
+	if err := cg.GenerateTableRepository(table); err != nil {
+		t.Fatalf("GenerateTableRepository failed: %v", err)
+	}
+	if err := cg.GenerateEncryption(); err != nil {
+		t.Fatalf("GenerateEncryption failed: %v", err)
+	}
+	if !compileGeneratedCode(t, tempDir) {
+		return
+	}
+
+	// Value must hand the driver the encrypted text and Scan must decrypt it again
+	testContent := `package testgen
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+type xorCryptor struct{ encrypts, decrypts int }
+
+func (c *xorCryptor) Encrypt(plaintext []byte) ([]byte, error) {
+	c.encrypts++
+	return xor(plaintext), nil
+}
+
+func (c *xorCryptor) Decrypt(ciphertext []byte) ([]byte, error) {
+	c.decrypts++
+	return xor(ciphertext), nil
+}
+
+func xor(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[i] = b[i] ^ 0x5a
+	}
+	return out
+}
+
+func TestEncryptedString(t *testing.T) {
+	if _, err := EncryptedString("secret").Value(); err == nil {
+		t.Fatal("Value before SetCryptor should fail")
+	}
+
+	c := &xorCryptor{}
+	SetCryptor(c)
+	defer SetCryptor(nil)
+
+	v, err := EncryptedString("secret").Value()
+	if err != nil {
+		t.Fatalf("Value failed: %v", err)
+	}
+	if want := base64.StdEncoding.EncodeToString(xor([]byte("secret"))); v != want {
+		t.Errorf("Value = %v, want %v", v, want)
+	}
+
+	var s EncryptedString
+	if err := s.Scan(v); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if s != "secret" {
+		t.Errorf("Scan = %q, want %q", s, "secret")
+	}
+	if c.encrypts != 1 || c.decrypts != 1 {
+		t.Errorf("Cryptor called %d/%d times, want 1/1", c.encrypts, c.decrypts)
+	}
+
+	// CSV export formats the plaintext of a nullable column without encrypting it again
+	field, err := FormatCSVValue(encryptedPlaintext(&s))
+	if err != nil || field != "secret" {
+		t.Errorf("FormatCSVValue(encryptedPlaintext) = %q, %v, want secret", field, err)
+	}
+	if field, err := FormatCSVValue(encryptedPlaintext(nil)); err != nil || field != "" {
+		t.Errorf("FormatCSVValue(encryptedPlaintext(nil)) = %q, %v, want an empty field", field, err)
+	}
+	if c.encrypts != 1 {
+		t.Errorf("CSV formatting encrypted the value, %d encrypts", c.encrypts)
+	}
+}
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "encryption_test.go"), []byte(testContent), 0644); err != nil {
+		t.Fatalf("Failed to write generated package test: %v", err)
+	}
+
+	testCmd := exec.Command("go", "test", "./...")
+	testCmd.Dir = tempDir
+	testCmd.Env = append(os.Environ(), "GO111MODULE=on")
+	if output, err := testCmd.CombinedOutput(); err != nil {
+		t.Errorf("Generated EncryptedString test failed: %v\nOutput: %s", err, string(output))
+	}
+}
+
+func TestCodeGenerator_RejectNilIDs(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"get", "update", "delete"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	code, err := cg.generateCRUDOperations(mapColumns(t, cg, getTestTable()))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	if strings.Contains(code, "uuid.Nil") {
+		t.Errorf("Expected no nil-id guard unless reject_nil_ids is set\n%s", code)
+	}
+
+	config.RejectNilIDs = true
+	code, err = cg.generateCRUDOperations(mapColumns(t, cg, getTestTable()))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	if got := strings.Count(code, "if id == uuid.Nil {"); got != 3 {
+		t.Errorf("Expected a nil-id guard in Get, Update and Delete, found %d\n%s", got, code)
+	}
+	expected := []string{
+		`return nil, &DatabaseError{Type: ErrValidationFailed, Operation: "get", Entity: "Users", Detail: "id must not be uuid.Nil"}`,
+		`return nil, &DatabaseError{Type: ErrValidationFailed, Operation: "update", Entity: "Users", Detail: "id must not be uuid.Nil"}`,
+		`return &DatabaseError{Type: ErrValidationFailed, Operation: "delete", Entity: "Users", Detail: "id must not be uuid.Nil"}`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("Expected generated code to contain %q\n%s", want, code)
+		}
+	}
+}
+
+func TestCodeGenerator_QueryOptions(t *testing.T) {
+	config := getTestConfigWithTempDir(t)
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"create", "get", "update", "delete", "list", "head"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	code, err := cg.generateCRUDOperations(mapColumns(t, cg, getTestTable()))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	if strings.Contains(code, "QueryOption") || strings.Contains(code, "applyQueryOptions") {
+		t.Errorf("Expected no query options unless query_options is set\n%s", code)
+	}
+
+	config.QueryOptions = true
+	table := mapColumns(t, cg, getTestTable())
+	code, err = cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"func (r *UsersRepository) Create(ctx context.Context, params CreateUsersParams, opts ...QueryOption) (*Users, error) {",
+		"func (r *UsersRepository) Get(ctx context.Context, id uuid.UUID, opts ...QueryOption) (*Users, error) {",
+		"func (r *UsersRepository) Update(ctx context.Context, id uuid.UUID, params UpdateUsersParams, opts ...QueryOption) (*Users, error) {",
+		"func (r *UsersRepository) Delete(ctx context.Context, id uuid.UUID, opts ...QueryOption) error {",
+		"func (r *UsersRepository) List(ctx context.Context, opts ...QueryOption) ([]Users, error) {",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("Expected generated code to contain %q\n%s", want, code)
+		}
+	}
+	// Every method taking options applies them to its context and SQL before running it
+	if got := strings.Count(code, "ctx, query, cancel := applyQueryOptions(ctx, query, opts)\n\tdefer cancel()"); got != 5 {
+		t.Errorf("Expected the options applied in Create, Get, Update, Delete and List, found %d\n%s", got, code)
+	}
+	if !strings.Contains(code, "Head(ctx context.Context, n int32) ([]Users, error) {") {
+		t.Errorf("Expected only the core CRUD methods to take options\n%s", code)
+	}
+
+	retry, err := cg.generateEnhancedFeatures(table)
+	if err != nil {
+		t.Fatalf("generateEnhancedFeatures failed: %v", err)
+	}
+	for _, want := range []string{
+		"GetWithRetry(ctx context.Context, id uuid.UUID, opts ...QueryOption) (*Users, error) {",
+		"return r.Get(ctx, id, opts...)",
+		"return r.Update(ctx, id, params, opts...)",
+	} {
+		if !strings.Contains(retry, want) {
+			t.Errorf("Expected retry methods to forward options with %q\n%s", want, retry)
+		}
+	}
+
+	if err := cg.GenerateSharedQueryOptions(); err != nil {
+		t.Fatalf("GenerateSharedQueryOptions failed: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(config.OutputDir, "query_options.go"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	for _, want := range []string{
+		"type QueryOption func(*queryOptions)",
+		"func WithQueryTimeout(timeout time.Duration) QueryOption {",
+		"func WithQueryTag(tag string) QueryOption {",
+		"ctx, cancel = context.WithTimeout(ctx, o.timeout)",
+		`query = "/* " + sanitizeQueryTag(o.tag) + " */ " + query`,
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("query options code missing %q\n%s", want, content)
+		}
+	}
+}
+
+func TestCodeGenerator_QueryTagComment(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compile test in short mode")
+	}
+
+	tempDir := t.TempDir()
+	cg := NewCodeGenerator(&Config{OutputDir: tempDir, PackageName: "testgen", QueryOptions: true})
+	if err := cg.GenerateSharedQueryOptions(); err != nil {
+		t.Fatalf("GenerateSharedQueryOptions failed: %v", err)
+	}
+	if !compileGeneratedCode(t, tempDir) {
+		return
+	}
+
+	// PostgreSQL nests block comments, so the tag must neither close the comment nor open another one
+	testContent := `package testgen
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestQueryTagStaysInComment(t *testing.T) {
+	for _, tag := range []string{"checkout", "*/ DROP TABLE users; --", "/* nested", "/*/", "**//**", "line\nbreak\x00"} {
+		_, query, cancel := applyQueryOptions(context.Background(), "SELECT 1", []QueryOption{WithQueryTag(tag)})
+		cancel()
+
+		comment, rest, ok := strings.Cut(strings.TrimPrefix(query, "/*"), "*/")
+		if !ok || rest != " SELECT 1" {
+			t.Errorf("tag %q: comment does not end right before the query: %q", tag, query)
+		}
+		if strings.Contains(comment, "/*") || strings.ContainsFunc(comment, func(r rune) bool { return r < ' ' }) {
+			t.Errorf("tag %q: comment %q opens a nested comment or holds control characters", tag, comment)
+		}
+	}
+}
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "query_tag_test.go"), []byte(testContent), 0644); err != nil {
+		t.Fatalf("Failed to write generated package test: %v", err)
+	}
+
+	testCmd := exec.Command("go", "test", "./...")
+	testCmd.Dir = tempDir
+	testCmd.Env = append(os.Environ(), "GO111MODULE=on")
+	if output, err := testCmd.CombinedOutput(); err != nil {
+		t.Errorf("Generated query tag test failed: %v\nOutput: %s", err, string(output))
+	}
+}
+
+func TestCodeGenerator_CollectRows(t *testing.T) {
+	config := getTestConfig()
+	config.ScanMode = ScanModeCollectRows
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"list", "paginate"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	code, err := cg.generateCRUDOperations(mapColumns(t, cg, getTestTable()))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"results, err := pgx.CollectRows(rows, pgx.RowToStructByName[Users])",
+		"items, err := pgx.CollectRows(rows, pgx.RowToStructByName[Users])",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("CollectRows code missing %q\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "rows.Scan(") {
+		t.Errorf("Expected List and ListPaginated to drop their scan loops\n%s", code)
+	}
+
+	// The default mode keeps the explicit scan loops
+	config.ScanMode = ""
+	code, err = cg.generateCRUDOperations(mapColumns(t, cg, getTestTable()))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	if strings.Contains(code, "pgx.CollectRows") || !strings.Contains(code, "rows.Scan(") {
+		t.Errorf("Expected scan loops by default\n%s", code)
+	}
+}
+
+func TestCodeGenerator_Stats(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"stats"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := mapColumns(t, cg, getTestTable())
+	code, err := cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"type UsersStats struct {",
+		"MinCreatedAt *time.Time",
+		"MaxCreatedAt *time.Time",
+		"func (r *UsersRepository) Stats(ctx context.Context) (UsersStats, error)",
+		// One aggregate query covers the count and every bound
+		"SELECT COUNT(*),\n\t\t\tMIN(id::text COLLATE \"C\")::uuid,\n\t\t\tMAX(id::text COLLATE \"C\")::uuid,\n\t\t\tMIN(created_at),\n\t\t\tMAX(created_at)\n\t\tFROM users\n",
+		"row.Scan(&stats.RowCount, &stats.MinID, &stats.MaxID, &stats.MinCreatedAt, &stats.MaxCreatedAt)",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("Stats code missing %q\n%s", want, code)
+		}
+	}
+
+	// Without the default timestamp column only the count and ID bounds are reported
+	table.Columns = slices.DeleteFunc(slices.Clone(table.Columns), func(col Column) bool { return col.Name == "created_at" })
+	code, err = cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	if strings.Contains(code, "MinCreatedAt") || !strings.Contains(code, "row.Scan(&stats.RowCount, &stats.MinID, &stats.MaxID)") {
+		t.Errorf("Expected Stats without timestamp bounds\n%s", code)
+	}
+
+	// An explicitly configured column must be a timestamp
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"stats"}, RangeColumn: "name"}
+	if _, err := cg.generateCRUDOperations(table); err == nil || !strings.Contains(err.Error(), "must be a timestamp column") {
+		t.Errorf("Expected range_column type error, got %v", err)
+	}
+}
+
+func TestCodeGenerator_GetNeighbors(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"get_neighbors"}, SoftDeleteColumn: "deleted_at"},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	table.Columns = append(table.Columns, Column{Name: "deleted_at", Type: "timestamptz", IsNullable: true})
+	code, err := cg.generateCRUDOperations(mapColumns(t, cg, table))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"func (r *UsersRepository) GetNeighbors(ctx context.Context, id uuid.UUID) (prev, next *Users, err error)",
+		// The previous row is the largest id below, the next the smallest id above
+		"WHERE id < $1 AND deleted_at IS NULL\n\t\tORDER BY id DESC\n\t\tLIMIT 1",
+		"WHERE id > $1 AND deleted_at IS NULL\n\t\tORDER BY id ASC\n\t\tLIMIT 1",
+		// Reaching either end yields nil rather than an error
+		"if IsNotFound(err) {\n\t\t\t\treturn nil, nil\n\t\t\t}",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("GetNeighbors code missing %q\n%s", want, code)
+		}
+	}
+}
+
+func TestCodeGenerator_CountFiltered(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"list_filtered", "count_filtered"}, SoftDeleteColumn: "deleted_at"},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	table.Columns = append(table.Columns,
+		Column{Name: "order", Type: "integer", IsNullable: true},
+		Column{Name: "deleted_at", Type: "timestamptz", IsNullable: true},
+	)
+	code, err := cg.generateCRUDOperations(mapColumns(t, cg, table))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	if got := strings.Count(code, "type UsersFilter struct {"); got != 1 {
+		t.Errorf("Expected the filter struct to be declared once, found %d\n%s", got, code)
+	}
+	expected := []string{
+		"Name *string",
+		// Nullable columns filter on their non-null value
+		"Order *int32",
+		`conditions = append(conditions, fmt.Sprintf("email = $%d", len(args)))`,
+		`conditions = append(conditions, fmt.Sprintf("\"order\" = $%d", len(args)))`,
+		`conditions = append(conditions, "deleted_at IS NULL")`,
+		"func (r *UsersRepository) ListFiltered(ctx context.Context, filter UsersFilter) ([]Users, error)",
+		"func (r *UsersRepository) CountFiltered(ctx context.Context, filter UsersFilter) (int64, error)",
+		"SELECT COUNT(*)\n\t\tFROM users\n\t\t` + where + `",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("Filtered code missing %q\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "DeletedAt *") || strings.Contains(code, "Metadata *") {
+		t.Errorf("Expected no filter fields for the soft delete and JSON columns\n%s", code)
+	}
+
+	// Both methods build their WHERE clause from the same where method
+	if got := strings.Count(code, "where, args := filter.where()"); got != 2 {
+		t.Errorf("Expected ListFiltered and CountFiltered to share filter.where(), found %d calls\n%s", got, code)
+	}
+	if got := strings.Count(code, "FROM users\n\t\t` + where + `"); got != 2 {
+		t.Errorf("Expected both queries to apply the shared WHERE clause, found %d\n%s", got, code)
+	}
+
+	// The soft delete condition is always present, so the empty-clause branch is left out
+	if strings.Contains(code, "if len(conditions) == 0 {") {
+		t.Errorf("Expected no empty-clause branch with a soft delete column\n%s", code)
+	}
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"list_filtered"}}
+	code, err = cg.generateCRUDOperations(mapColumns(t, cg, getTestTable()))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	if !strings.Contains(code, "if len(conditions) == 0 {\n\t\treturn \"\", args\n\t}") {
+		t.Errorf("Expected the zero filter to build no WHERE clause without a soft delete column\n%s", code)
+	}
+}
+
+func TestCodeGenerator_StreamFiltered(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"list_filtered", "stream_filtered"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	code, err := cg.generateCRUDOperations(mapColumns(t, cg, getTestTable()))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	if got := strings.Count(code, "type UsersFilter struct {"); got != 1 {
+		t.Errorf("Expected the filter struct to be declared once, found %d\n%s", got, code)
+	}
+	method := code[strings.Index(code, "func (r *UsersRepository) StreamFiltered("):]
+	expected := []string{
+		"func (r *UsersRepository) StreamFiltered(ctx context.Context, filter UsersFilter, fn func(Users) error) error {",
+		// The streamed query applies the same dynamic WHERE as ListFiltered
+		"where, args := filter.where()",
+		"FROM users\n\t\t` + where + `",
+		`ExecuteQuery(ctx, r.db, "stream_filtered", "Users", query, args...)`,
+		// Each row is handed to fn as soon as it is scanned
+		"for rows.Next() {",
+		"if err := fn(u); err != nil {\n\t\t\treturn err\n\t\t}",
+		`return HandleRowsResult("Users", rows)`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(method, want) {
+			t.Errorf("StreamFiltered code missing %q\n%s", want, method)
+		}
+	}
+	if strings.Contains(method, "append(") {
+		t.Errorf("StreamFiltered should not collect rows\n%s", method)
+	}
+}
+
+func TestCodeGenerator_NumericMode(t *testing.T) {
+	config := getTestConfigWithTempDir(t)
+	config.NumericMode = NumericModePgtype
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"create", "get"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	table.Columns = append(table.Columns,
+		Column{Name: "balance", Type: "numeric"},
+		Column{Name: "credit_limit", Type: "numeric", IsNullable: true},
+	)
+	if err := cg.GenerateTableRepository(table); err != nil {
+		t.Fatalf("GenerateTableRepository failed: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(config.OutputDir, "users_generated.go"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	code := string(content)
+
+	expected := []string{
+		`"github.com/jackc/pgx/v5/pgtype"`,
+		"Balance     pgtype.Numeric",
+		"CreditLimit pgtype.Numeric",
+		// Rows scan straight into the precise type
+		"&u.Balance, &u.CreditLimit)",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code missing %q\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "float64") {
+		t.Errorf("Expected no float64 fields in numeric_mode pgtype\n%s", code)
+	}
+}
+
+func TestCodeGenerator_IntEnums(t *testing.T) {
+	config := getTestConfigWithTempDir(t)
+	config.TableConfigs = map[string]TableConfig{
+		"users": {
+			Functions: []string{"get", "create"},
+			IntEnums: map[string]IntEnumConfig{
+				"role": {Type: "UserRole", Values: map[string]int16{"member": 1, "admin": 2, "guest": 0}},
+			},
+		},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	table.Columns = append(table.Columns, Column{Name: "role", Type: "smallint"})
+	if err := cg.GenerateTableRepository(table); err != nil {
+		t.Fatalf("GenerateTableRepository failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(config.OutputDir, "users_generated.go"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	code := string(content)
+
+	expected := []string{
+		"type UserRole int16",
+		// Constants are ordered by value
+		"UserRoleGuest  UserRole = 0\n\tUserRoleMember UserRole = 1\n\tUserRoleAdmin  UserRole = 2",
+		// The struct and create params carry the named type instead of int16
+		"Role      UserRole",
+		"Role     UserRole",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code missing %q\n%s", want, code)
+		}
+	}
+
+	config.TableConfigs["users"] = TableConfig{
+		IntEnums: map[string]IntEnumConfig{"name": {Type: "UserName"}},
+	}
+	if err := cg.GenerateTableRepository(table); err == nil || !strings.Contains(err.Error(), "must be a smallint") {
+		t.Errorf("expected smallint column error, got %v", err)
+	}
+}
+
+func TestCodeGenerator_CountBy(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"count_by"}, CountByColumns: []string{"status", "age"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	table.Columns = append(table.Columns,
+		Column{Name: "status", Type: "text"},
+		Column{Name: "age", Type: "integer", IsNullable: true},
+	)
+	table.Indexes = []Index{
+		{Name: "idx_users_status", Columns: []string{"status"}},
+		{Name: "idx_users_age", Columns: []string{"age", "created_at"}},
+	}
+	table = mapColumns(t, cg, table)
+
+	code, err := cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"func (r *UsersRepository) CountByStatus(ctx context.Context) (map[string]int64, error)",
+		"SELECT status, COUNT(*)\n\t\tFROM users\n\t\tGROUP BY status",
+		"counts := make(map[string]int64)",
+		"if err := rows.Scan(&value, &count); err != nil",
+		"counts[value] = count",
+		// Nullable columns count by their plain type and leave NULLs out
+		"func (r *UsersRepository) CountByAge(ctx context.Context) (map[int32]int64, error)",
+		"WHERE age IS NOT NULL\n\t\tGROUP BY age",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("count_by code missing %q\n%s", want, code)
+		}
+	}
+
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"count_by"}, CountByColumns: []string{"name"}}
+	if _, err := cg.generateCRUDOperations(table); err == nil || !strings.Contains(err.Error(), "requires an index on users.name") {
+		t.Errorf("expected missing index error, got %v", err)
+	}
+
+	// Map-typed columns such as hstore can't key the counts map
+	table.Columns = append(table.Columns, Column{Name: "attributes", Type: "hstore", IsNullable: true})
+	table.Indexes = append(table.Indexes, Index{Name: "idx_users_attributes", Columns: []string{"attributes"}})
+	table = mapColumns(t, cg, table)
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"count_by"}, CountByColumns: []string{"attributes"}}
+	if _, err := cg.generateCRUDOperations(table); err == nil || !strings.Contains(err.Error(), "must hold a comparable value") {
+		t.Errorf("expected non-comparable column error, got %v", err)
+	}
+}
+
+func TestCodeGenerator_CountDistinct(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"count_by", "count_distinct"}, CountByColumns: []string{"status"}, SoftDeleteColumn: "deleted_at"},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	table.Columns = append(table.Columns,
+		Column{Name: "status", Type: "text"},
+		Column{Name: "deleted_at", Type: "timestamptz", IsNullable: true},
+	)
+	table.Indexes = []Index{{Name: "idx_users_status", Columns: []string{"status"}}}
+	table = mapColumns(t, cg, table)
+
+	code, err := cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	expected := []string{
+		"func (r *UsersRepository) CountByStatus(ctx context.Context) (map[string]int64, error)",
+		"func (r *UsersRepository) CountDistinctStatuses(ctx context.Context) (int64, error) {",
+		"SELECT COUNT(DISTINCT status)\n\t\tFROM users\n\t\tWHERE deleted_at IS NULL\n",
+		`ExecuteQueryRow(ctx, r.db, "count_distinct_status", "Users", query)`,
+		`HandleQueryRowError("count_distinct_status", "Users", row.Scan(&count))`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("count_distinct code missing %q\n%s", want, code)
+		}
+	}
+
+	// The distinct count needs the same index as the grouped one
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"count_distinct"}, CountByColumns: []string{"name"}}
+	if _, err := cg.generateCRUDOperations(table); err == nil || !strings.Contains(err.Error(), "count_distinct requires an index on users.name") {
+		t.Errorf("expected missing index error, got %v", err)
+	}
+}
+
+var updateGolden = flag.Bool("update-golden", false, "rewrite the golden files under testdata")
+
+func TestCodeGenerator_UpdateSetClause(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"update"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	code, err := cg.generateCRUDOperations(mapColumns(t, cg, getTestTable()))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	// Assignments are numbered in the same order as the ExecuteQueryRow args, with the id last
+	expected := []string{
+		"SET name = $1, email = $2, is_active = $3, created_at = $4, metadata = $5\n",
+		"WHERE id = $6\n",
+		`query, params.Name, params.Email, params.IsActive, params.CreatedAt, params.Metadata, id)`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("Update code missing %q\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "<no value>") {
+		t.Errorf("Update code references a missing template value\n%s", code)
+	}
+
+	golden := filepath.Join("testdata", "update_users.golden")
+	if *updateGolden {
+		if err := os.WriteFile(golden, []byte(code), 0644); err != nil {
+			t.Fatalf("Failed to write golden file: %v", err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("Failed to read golden file (run with -update-golden to create it): %v", err)
+	}
+	if code != string(want) {
+		t.Errorf("Update code does not match %s (run with -update-golden to refresh it)\ngot:\n%s", golden, code)
+	}
+}
+
+func TestCodeGenerator_PartialUpdate(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"get", "update"}, PartialUpdate: true},
+	}
+	cg := NewCodeGenerator(config)
+
+	table := getTestTable()
+	table.Checks = []CheckConstraint{{Name: "users_name_check", Column: "name", Operator: "<>", Values: []string{""}}}
+	code, err := cg.generateCRUDOperations(mapColumns(t, cg, table))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+
+	expected := []string{
+		"Name *string `json:\"name\" db:\"name\"`",
+		// Nullable columns keep their NULL-capable type behind the pointer
+		"IsActive *pgtype.Bool `json:\"is_active\" db:\"is_active\"`",
+		"if params.Name != nil {\n\t\targs = append(args, *params.Name)\n\t\tsets = append(sets, fmt.Sprintf(`name = $%d`, len(args)))",
+		"if len(sets) == 0 {\n\t\treturn r.Get(ctx, id)\n\t}",
+		"SET %s\n\t\tWHERE id = $%d",
+		`strings.Join(sets, ", "), len(args))`,
+		`ExecuteQueryRow(ctx, r.db, "update", "Users", query, args...)`,
+		// Unset fields are not validated
+		`if p.Name != nil && !((*p.Name) != "") {`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("Partial update code missing %q\n%s", want, code)
+		}
+	}
+
+	// The empty update reads the row back through Get
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"update"}, PartialUpdate: true}
+	if _, err := cg.generateCRUDOperations(mapColumns(t, cg, getTestTable())); err == nil || !strings.Contains(err.Error(), "partial_update requires get") {
+		t.Errorf("Expected missing get error, got %v", err)
+	}
+
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"get", "update", "bulk_update"}, PartialUpdate: true}
+	if _, err := cg.generateCRUDOperations(mapColumns(t, cg, getTestTable())); err == nil || !strings.Contains(err.Error(), "cannot be combined with partial_update") {
+		t.Errorf("Expected bulk_update conflict error, got %v", err)
+	}
+}
+
+func TestCodeGenerator_DomainMapping(t *testing.T) {
+	config := getTestConfigWithTempDir(t)
+	config.CustomImports = map[string]string{"domain": "example.com/app/domain"}
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"get", "list"}, DomainMapping: "domain.User"},
+	}
+	cg := NewCodeGenerator(config)
+	table := getTestTable()
+
+	code, err := cg.generateTableCode(mapColumns(t, cg, table))
+	if err != nil {
+		t.Fatalf("generateTableCode failed: %v", err)
+	}
+	expected := []string{
+		`"example.com/app/domain"`,
+		"func (r *UsersRepository) GetDomain(ctx context.Context, id uuid.UUID) (*domain.User, error) {",
+		"result := UsersToDomain(row)",
+		"func (r *UsersRepository) ListDomain(ctx context.Context) ([]domain.User, error) {",
+		"result[i] = UsersToDomain(&rows[i])",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("Domain mapping code missing %q\n%s", want, code)
+		}
+	}
+
+	// The conversion skeleton has an assignment for every field for the user to fill in
+	if err := cg.GenerateTableRepository(table); err != nil {
+		t.Fatalf("GenerateTableRepository failed: %v", err)
+	}
+	filename := filepath.Join(config.OutputDir, "users_domain.go")
+	scaffold, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read domain mapping scaffold: %v", err)
+	}
+	if !strings.Contains(string(scaffold), "func UsersToDomain(row *Users) domain.User {") {
+		t.Errorf("Scaffold missing UsersToDomain\n%s", scaffold)
+	}
+	if strings.Contains(string(scaffold), "DO NOT EDIT") {
+		t.Error("The scaffold is meant to be edited and must not be marked as generated")
+	}
+	for _, col := range table.Columns {
+		want := fmt.Sprintf("// result.%s = row.%s", col.GoFieldName(), col.GoFieldName())
+		if !strings.Contains(string(scaffold), want) {
+			t.Errorf("Scaffold missing %q\n%s", want, scaffold)
+		}
+	}
+
+	// A filled-in conversion survives regeneration
+	edited := strings.Replace(string(scaffold), "// result.Name = row.Name", "result.Name = row.Name", 1)
+	if err := os.WriteFile(filename, []byte(edited), 0644); err != nil {
+		t.Fatalf("Failed to edit scaffold: %v", err)
+	}
+	if err := cg.GenerateTableRepository(table); err != nil {
+		t.Fatalf("GenerateTableRepository failed: %v", err)
+	}
+	if kept, _ := os.ReadFile(filename); string(kept) != edited {
+		t.Errorf("Regeneration overwrote the edited scaffold\n%s", kept)
+	}
+
+	config.CustomImports = nil
+	if _, err := cg.generateTableCode(mapColumns(t, cg, table)); err == nil || !strings.Contains(err.Error(), "types.custom_imports") {
+		t.Errorf("Expected missing custom import error, got %v", err)
+	}
+}
+
+func TestCodeGenerator_DeleteFiltered(t *testing.T) {
+	config := getTestConfig()
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"delete_filtered"}},
+	}
+	cg := NewCodeGenerator(config)
+	table := mapColumns(t, cg, getTestTable())
+
+	code, err := cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	expected := []string{
+		// The filter struct is declared ahead of DeleteFiltered when no other filtered method is generated
+		"type UsersFilter struct {",
+		`conditions = append(conditions, fmt.Sprintf("name = $%d", len(args)))`,
+		"func (r *UsersRepository) DeleteFiltered(ctx context.Context, filter UsersFilter) (int64, error) {",
+		"where, args := filter.where()",
+		// The zero filter sets no arguments and would otherwise delete every row
+		"if len(args) == 0 {\n\t\treturn 0, &DatabaseError{Type: ErrValidationFailed, Operation: \"delete_filtered\", Entity: \"Users\", Detail: \"filter must set at least one field\"}",
+		"DELETE FROM users\n\t\t` + where + `",
+		`return ExecuteNonQueryWithRowsAffected(ctx, r.db, "delete_filtered", "Users", query, args...)`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("DeleteFiltered code missing %q\n%s", want, code)
+		}
+	}
+
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"delete_filtered"}, AllowFullDelete: true}
+	code, err = cg.generateCRUDOperations(table)
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	if strings.Contains(code, "if len(args) == 0 {") {
+		t.Errorf("Expected no empty filter guard with allow_full_delete\n%s", code)
+	}
+
+	// Soft-deleted tables mark the matching rows instead of removing them
+	softTable := getTestTable()
+	softTable.Columns = append(softTable.Columns, Column{Name: "deleted_at", Type: "timestamptz", IsNullable: true})
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"delete_filtered"}, SoftDeleteColumn: "deleted_at"}
+	code, err = cg.generateCRUDOperations(mapColumns(t, cg, softTable))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	if want := "UPDATE users\n\t\tSET deleted_at = NOW()\n\t\t` + where + `"; !strings.Contains(code, want) {
+		t.Errorf("DeleteFiltered code missing %q\n%s", want, code)
+	}
+}
+
+func TestCodeGenerator_Relationships(t *testing.T) {
+	tempDir := t.TempDir()
+	config := &Config{
+		OutputDir:             tempDir,
+		PackageName:           "testgen",
+		GenerateRelationships: true,
+		TableConfigs: map[string]TableConfig{
+			"users": {Functions: []string{"get"}},
+			"posts": {Functions: []string{"get"}},
+		},
+	}
+	users := Table{
+		Name:       "users",
+		Schema:     "public",
+		Columns:    []Column{{Name: "id", Type: "uuid"}, {Name: "name", Type: "text"}},
+		PrimaryKey: []string{"id"},
+	}
+	posts := Table{
+		Name:   "posts",
+		Schema: "public",
+		Columns: []Column{
+			{Name: "id", Type: "uuid"},
+			{Name: "author_id", Type: "uuid"},
+			{Name: "editor_id", Type: "uuid", IsNullable: true},
+			{Name: "category_id", Type: "uuid"},
+		},
+		PrimaryKey: []string{"id"},
+		ForeignKeys: []ForeignKey{
+			{Name: "posts_author_id_fkey", Columns: []string{"author_id"}, RefTable: "users", RefColumns: []string{"id"}},
+			{Name: "posts_editor_id_fkey", Columns: []string{"editor_id"}, RefTable: "users", RefColumns: []string{"id"}},
+			{Name: "posts_category_id_fkey", Columns: []string{"category_id"}, RefTable: "categories", RefColumns: []string{"id"}},
+		},
+	}
+	cg := NewCodeGenerator(config)
+	cg.relatedTables = []Table{users, posts}
+
+	code, err := cg.generateTableCode(mapColumns(t, cg, posts))
+	if err != nil {
+		t.Fatalf("generateTableCode failed: %v", err)
+	}
+	expected := []string{
+		"func (p Posts) GetAuthorID() uuid.UUID {\n\treturn p.AuthorId\n}",
+		"func (p Posts) GetEditorID() pgtype.UUID {\n\treturn p.EditorId\n}",
+		"func (r *PostsRepository) GetAuthor(ctx context.Context, p Posts) (*Users, error) {\n\treturn (&UsersRepository{db: r.db}).Get(ctx, p.AuthorId)\n}",
+		// A NULL key references no row, so nothing is queried
+		"func (r *PostsRepository) GetEditor(ctx context.Context, p Posts) (*Users, error) {\n\tif !p.EditorId.Valid {\n\t\treturn nil, nil\n\t}",
+		"return (&UsersRepository{db: r.db}).Get(ctx, uuid.UUID(p.EditorId.Bytes))",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("Relationship code missing %q\n%s", want, code)
+		}
+	}
+	// categories is not generated, so there is no repository to load it with
+	if strings.Contains(code, "GetCategory") {
+		t.Errorf("Expected no relationship to a table outside the run\n%s", code)
+	}
+
+	// With a Querier per call the referenced repository runs on the caller's Querier
+	config.QuerierPerCall = true
+	code, err = cg.generateTableCode(mapColumns(t, cg, posts))
+	if err != nil {
+		t.Fatalf("generateTableCode failed: %v", err)
+	}
+	if want := "func (r *PostsRepository) GetAuthor(ctx context.Context, q Querier, p Posts) (*Users, error) {\n\treturn (&UsersRepository{}).Get(ctx, q, p.AuthorId)\n}"; !strings.Contains(code, want) {
+		t.Errorf("Relationship code missing %q\n%s", want, code)
+	}
+	config.QuerierPerCall = false
+
+	// Relationships need the referenced table to generate Get
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"list"}}
+	code, err = cg.generateTableCode(mapColumns(t, cg, posts))
+	if err != nil {
+		t.Fatalf("generateTableCode failed: %v", err)
+	}
+	if strings.Contains(code, "GetAuthor") {
+		t.Errorf("Expected no relationship without a referenced Get\n%s", code)
+	}
+	config.TableConfigs["users"] = TableConfig{Functions: []string{"get"}}
+
+	config.GenerateRelationships = false
+	code, err = cg.generateTableCode(mapColumns(t, cg, posts))
+	if err != nil {
+		t.Fatalf("generateTableCode failed: %v", err)
+	}
+	if strings.Contains(code, "GetAuthor") {
+		t.Errorf("Expected no relationships unless generate_relationships is set\n%s", code)
+	}
+	config.GenerateRelationships = true
+
+	if testing.Short() {
+		return
+	}
+	for _, generate := range []func() error{cg.GenerateSharedErrors, cg.GenerateSharedDatabaseOperations, cg.GenerateSharedRetryOperations} {
+		if err := generate(); err != nil {
+			t.Fatalf("shared file generation failed: %v", err)
+		}
+	}
+	for _, table := range cg.relatedTables {
+		if err := cg.GenerateTableRepository(table); err != nil {
+			t.Fatalf("GenerateTableRepository failed for %s: %v", table.Name, err)
+		}
+	}
+	compileGeneratedCode(t, tempDir)
+}
+
+func TestCodeGenerator_EagerLoad(t *testing.T) {
+	tempDir := t.TempDir()
+	config := &Config{
+		OutputDir:      tempDir,
+		PackageName:    "testgen",
+		QuerierPerCall: true,
+		TableConfigs: map[string]TableConfig{
+			"posts":    {Functions: []string{"get"}, EagerLoad: []string{"comments"}},
+			"comments": {Functions: []string{"get"}},
+		},
+	}
+	posts := Table{
+		Name:       "posts",
+		Schema:     "public",
+		Columns:    []Column{{Name: "id", Type: "uuid"}, {Name: "title", Type: "text"}},
+		PrimaryKey: []string{"id"},
+	}
+	comments := Table{
+		Name:   "comments",
+		Schema: "public",
+		Columns: []Column{
+			{Name: "id", Type: "uuid"},
+			{Name: "post_id", Type: "uuid"},
+			{Name: "body", Type: "text", IsNullable: true},
+		},
+		PrimaryKey:  []string{"id"},
+		ForeignKeys: []ForeignKey{{Name: "comments_post_id_fkey", Columns: []string{"post_id"}, RefTable: "posts", RefColumns: []string{"id"}}},
+	}
+	cg := NewCodeGenerator(config)
+	cg.relatedTables = []Table{posts, comments}
+
+	code, err := cg.generateTableCode(mapColumns(t, cg, posts))
+	if err != nil {
+		t.Fatalf("generateTableCode failed: %v", err)
+	}
+	expected := []string{
+		"type PostsWithComments struct {\n\tPosts\n\tComments []Comments `json:\"comments\"`\n}",
+		"func (r *PostsRepository) ListWithComments(ctx context.Context, q Querier) ([]PostsWithComments, error) {",
+		"SELECT parent.id, parent.title, child.post_id IS NOT NULL, child.id, child.post_id, child.body",
+		"FROM posts parent\n\t\tLEFT JOIN comments child ON child.post_id = parent.id\n\t\tORDER BY parent.id, child.id",
+		// The NOT NULL child columns are NULL for a post without comments
+		"var childId *uuid.UUID",
+		"err := rows.Scan(&parent.Id, &parent.Title, &hasChild, &childId, &childPostId, &child.Body)",
+		"child.PostId = *childPostId",
+	}
+	for _, want := range expected {
+		if !strings.Contains(code, want) {
+			t.Errorf("Eager load code missing %q\n%s", want, code)
+		}
+	}
+
+	// Soft-deleted children are left out of the join rather than filtering out their parent
+	comments.Columns = append(comments.Columns, Column{Name: "deleted_at", Type: "timestamptz", IsNullable: true})
+	cg.relatedTables = []Table{posts, comments}
+	config.TableConfigs["comments"] = TableConfig{Functions: []string{"get"}, SoftDeleteColumn: "deleted_at"}
+	code, err = cg.generateTableCode(mapColumns(t, cg, posts))
+	if err != nil {
+		t.Fatalf("generateTableCode failed: %v", err)
+	}
+	if want := "LEFT JOIN comments child ON child.post_id = parent.id AND child.deleted_at IS NULL"; !strings.Contains(code, want) {
+		t.Errorf("Eager load code missing %q\n%s", want, code)
+	}
+
+	errorCases := []struct {
+		name      string
+		eagerLoad []string
+		related   []Table
+		wantErr   string
+	}{
+		{"child not generated", []string{"comments"}, []Table{posts}, "is not generated"},
+		{"no foreign key", []string{"comments"}, []Table{posts, {Name: "comments", Columns: comments.Columns}}, "found 0"},
+		{"itself", []string{"posts"}, []Table{posts, comments}, "cannot load itself"},
+	}
+	for _, tc := range errorCases {
+		config.TableConfigs["posts"] = TableConfig{Functions: []string{"get"}, EagerLoad: tc.eagerLoad}
+		cg.relatedTables = tc.related
+		if _, err := cg.generateTableCode(mapColumns(t, cg, posts)); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: expected error containing %q, got %v", tc.name, tc.wantErr, err)
+		}
+	}
+
+	if testing.Short() {
+		return
+	}
+	comments.Columns = comments.Columns[:3]
+	config.TableConfigs["posts"] = TableConfig{Functions: []string{"get"}, EagerLoad: []string{"comments"}}
+	config.TableConfigs["comments"] = TableConfig{Functions: []string{"get"}}
+	cg.relatedTables = []Table{posts, comments}
+	for _, generate := range []func() error{cg.GenerateSharedErrors, cg.GenerateSharedDatabaseOperations, cg.GenerateSharedRetryOperations} {
+		if err := generate(); err != nil {
+			t.Fatalf("shared file generation failed: %v", err)
+		}
+	}
+	for _, table := range cg.relatedTables {
+		if err := cg.GenerateTableRepository(table); err != nil {
+			t.Fatalf("GenerateTableRepository failed for %s: %v", table.Name, err)
+		}
+	}
+	if !compileGeneratedCode(t, tempDir) {
+		return
+	}
+
+	// The fake joined rows hold two comments on the first post, none on the second and one on the
+	// third, with the first post's comments split apart to check grouping goes by ID, not adjacency
+	testContent := `package testgen
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func id(n byte) uuid.UUID { return uuid.UUID{15: n} }
+
+type joinedRow struct {
+	post    byte
+	comment byte // 0 for a post without comments
+}
+
+type joinedRows struct {
+	pgx.Rows
+	rows []joinedRow
+	pos  int
+}
+
+func (r *joinedRows) Next() bool { r.pos++; return r.pos <= len(r.rows) }
+func (r *joinedRows) Scan(dest ...any) error {
+	row := r.rows[r.pos-1]
+	*dest[0].(*uuid.UUID) = id(row.post)
+	*dest[1].(*string) = "post"
+	*dest[2].(*bool) = row.comment != 0
+	if row.comment == 0 {
+		*dest[3].(**uuid.UUID), *dest[4].(**uuid.UUID) = nil, nil
+		*dest[5].(*pgtype.Text) = pgtype.Text{}
+		return nil
+	}
+	commentID, postID := id(row.comment), id(row.post)
+	*dest[3].(**uuid.UUID), *dest[4].(**uuid.UUID) = &commentID, &postID
+	*dest[5].(*pgtype.Text) = pgtype.Text{String: "comment", Valid: true}
+	return nil
+}
+func (r *joinedRows) Err() error { return nil }
+func (r *joinedRows) Close()     {}
+
+type joinQuerier struct{ rows []joinedRow }
+
+func (q joinQuerier) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return &joinedRows{rows: q.rows}, nil
+}
+func (q joinQuerier) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+func (q joinQuerier) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return nil
+}
+
+func TestListWithCommentsGroupsByPost(t *testing.T) {
+	q := joinQuerier{rows: []joinedRow{{1, 11}, {2, 0}, {3, 31}, {1, 12}}}
+	posts, err := NewPostsRepository().ListWithComments(context.Background(), q)
+	if err != nil {
+		t.Fatalf("ListWithComments failed: %v", err)
+	}
+
+	want := map[byte][]byte{1: {11, 12}, 2: {}, 3: {31}}
+	if len(posts) != len(want) {
+		t.Fatalf("got %d posts, want %d", len(posts), len(want))
+	}
+	for i, post := range posts {
+		if post.Id != id(byte(i+1)) {
+			t.Errorf("post %d has ID %v, want the order of first appearance", i, post.Id)
+		}
+		wantComments := want[post.Id[15]]
+		if post.Comments == nil || len(post.Comments) != len(wantComments) {
+			t.Errorf("post %d has comments %+v, want %v", post.Id[15], post.Comments, wantComments)
+			continue
+		}
+		for j, comment := range post.Comments {
+			if comment.Id != id(wantComments[j]) || comment.PostId != post.Id || comment.Body.String != "comment" {
+				t.Errorf("post %d comment %d = %+v", post.Id[15], j, comment)
+			}
+		}
+	}
+}
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "eager_load_test.go"), []byte(testContent), 0644); err != nil {
+		t.Fatalf("Failed to write test: %v", err)
+	}
+	testCmd := exec.Command("go", "test", "./...")
+	testCmd.Dir = tempDir
+	testCmd.Env = append(os.Environ(), "GO111MODULE=on")
+	if output, err := testCmd.CombinedOutput(); err != nil {
+		t.Errorf("Generated eager load test failed: %v\nOutput: %s", err, string(output))
+	}
+}
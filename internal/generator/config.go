@@ -2,8 +2,14 @@ package generator
 
 import (
 	"fmt"
+	"go/token"
+	"maps"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -14,16 +20,49 @@ type Config struct {
 	DSN    string `yaml:"dsn"`
 	Schema string `yaml:"schema"`
 
+	// Optional connections overriding DSN: introspection is read-only and may use a replica,
+	// while query analysis prepares statements in a rolled-back transaction and needs a writable instance
+	IntrospectionDSN string `yaml:"introspection_dsn"`
+	AnalysisDSN      string `yaml:"analysis_dsn"`
+
 	// Output configuration
 	OutputDir   string `yaml:"output_dir"`
 	PackageName string `yaml:"package_name"`
 
+	// Version generates into a subpackage of OutputDir named after it (e.g. v2), so code for an old
+	// and a new schema version can live side by side during a migration
+	Version string `yaml:"version"`
+
+	// ImportPath is the import path of the generated package, which the mocks import; it defaults to
+	// the output directory's path within the module of the nearest enclosing go.mod
+	ImportPath string `yaml:"import_path"`
+
+	// SingleFile aggregates all generated code into this one file when set
+	SingleFile string `yaml:"single_file"`
+
+	// SharedFiles controls the names and generation of the shared utility files
+	SharedFiles SharedFilesConfig `yaml:"shared_files"`
+
 	// Generation modes
 	Tables     bool   `yaml:"tables"`
 	QueriesDir string `yaml:"queries_dir"`
 
-	// Table filtering
+	// PrintSQL prints the SQL each generated method runs instead of writing Go files
+	PrintSQL bool `yaml:"print_sql"`
+
+	// DryRun generates every file in memory and lists its path and size without writing anything
+	DryRun bool `yaml:"dry_run"`
+
+	// ReuseTableStructs scans query results into a generated table struct when the columns match exactly
+	ReuseTableStructs bool `yaml:"reuse_table_structs"`
+
+	// ValidateExec executes :exec, :execrows and :batch queries in an always rolled-back transaction during analysis,
+	// catching runtime errors such as NOT NULL violations that preparing alone misses
+	ValidateExec bool `yaml:"validate_exec"`
+
+	// Table filtering: a table is generated when it matches an include pattern and no exclude pattern
 	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
 
 	// Table configurations (functions to generate per table)
 	TableConfigs map[string]TableConfig `yaml:"table_configs"`
@@ -34,51 +73,303 @@ type Config struct {
 	// Options
 	Verbose bool `yaml:"verbose"`
 
-	// Type mappings (future extension)
+	// Plurals overrides pluralization of generated names, keyed by singular word (e.g. person: people)
+	Plurals map[string]string `yaml:"plurals"`
+
+	// GenerateRoundtripTests emits a JSON round-trip test file for each table struct
+	GenerateRoundtripTests bool `yaml:"generate_roundtrip_tests"`
+
+	// GenerateTestHelpers emits destructive test-only methods such as TruncateForTesting; never enable it for production code
+	GenerateTestHelpers bool `yaml:"generate_test_helpers"`
+
+	// GenerateContextHelpers emits WithUserID and UserIDFromContext, backed by an unexported context key
+	// type, and makes every write method set each table's uuid updated_by column from the context user ID
+	GenerateContextHelpers bool `yaml:"generate_context_helpers"`
+
+	// GenerateSchemaHash emits SchemaHash, the generated tables' column fingerprint, and VerifySchemaHash
+	// to compare it with the live database at startup
+	GenerateSchemaHash bool `yaml:"generate_schema_hash"`
+
+	// ScanMode selects how List and ListPaginated read rows: "scan" (the default) emits explicit
+	// rows.Scan loops, "collect_rows" uses pgx.CollectRows with pgx.RowToStructByName over the db tags
+	ScanMode string `yaml:"scan_mode"`
+
+	// DBBackend selects the connection generated constructors take: "pgxkit" (the default) for a
+	// *pgxkit.DB, "pgxpool" for a *pgxpool.Pool, leaving pgx as generated code's only database dependency
+	DBBackend string `yaml:"db_backend"`
+
+	// GenerateInterfaces emits an <Struct>RepositoryInterface beside each repository, listing every
+	// method generated for the table, for services to depend on and tests to mock
+	GenerateInterfaces bool `yaml:"generate_interfaces"`
+
+	// GenerateMocks emits a Mock<Struct>Repository implementing each repository interface into a mocks
+	// subpackage, with a stub function field per method; it requires GenerateInterfaces
+	GenerateMocks bool `yaml:"generate_mocks"`
+
+	// GenerateMetrics times every generated query and reports it to the MetricsRecorder installed with
+	// SetMetricsRecorder, which discards the measurements until one is set
+	GenerateMetrics bool `yaml:"generate_metrics"`
+
+	// GenerateRelationships emits, for each single-column foreign key to another generated table, a
+	// Get<Relation>ID accessor on the struct and a Get<Relation> repository method loading the referenced row
+	GenerateRelationships bool `yaml:"generate_relationships"`
+
+	// QuerierPerCall makes repositories stateless: every generated method takes the Querier to run on
+	// right after ctx, so callers choose a pool or transaction per call
+	QuerierPerCall bool `yaml:"querier_per_call"`
+
+	// RejectNilIDs makes the generated Get, Update and Delete return a validation error for a
+	// uuid.Nil id instead of querying, since a zero id almost always means one was never set
+	RejectNilIDs bool `yaml:"reject_nil_ids"`
+
+	// QueryOptions adds a trailing opts ...QueryOption parameter to Create, Get, Update, Delete and List
+	// for per-call settings such as a timeout or a tag identifying the query in database logs
+	QueryOptions bool `yaml:"query_options"`
+
+	// BatchChunkSize makes CreateBatch, BulkCreateIDs and BulkUpdate split their input into statements of at
+	// most this many rows, checking ctx between them; 0 runs each call as a single statement
+	BatchChunkSize int `yaml:"batch_chunk_size"`
+
+	// EncryptedColumns lists "table.column" text columns stored encrypted: they map to the generated
+	// EncryptedString, which encrypts on write and decrypts on read through a user-provided Cryptor
+	EncryptedColumns []string `yaml:"encrypted_columns"`
+
+	// TypeMappings maps PostgreSQL type names to Go types
 	TypeMappings map[string]string `yaml:"type_mappings"`
+
+	// ColumnTypeMappings maps single json or jsonb columns, keyed by "table.column", to a type the user
+	// defines elsewhere in the package (e.g. "posts.metadata": "PostMetadata"), which pgx marshals and
+	// unmarshals with encoding/json; nullable columns become pointers to it
+	ColumnTypeMappings map[string]string `yaml:"column_type_mappings"`
+
+	// CustomImports maps the package name used in mapped Go types (e.g. mypkg in mypkg.ID) to its import path
+	CustomImports map[string]string `yaml:"custom_imports"`
+
+	// NumericMode selects the Go type for numeric and decimal columns: "float64" (the default) or
+	// "pgtype" for pgtype.Numeric, which keeps every digit of money and high-scale values
+	NumericMode string `yaml:"numeric_mode"`
+
+	// NetworkMode selects the Go types for inet and cidr columns: "string" (the default) or "netip" for
+	// netip.Addr and netip.Prefix; inet values carrying a netmask (e.g. 10.0.0.0/8) then fail to scan
+	NetworkMode string `yaml:"network_mode"`
+
+	// GeometryMode selects the Go type for point columns: "string" (the default) or "struct" for a
+	// generated Point{X, Y float64}
+	GeometryMode string `yaml:"geometry_mode"`
 }
 
 // DatabaseConfig represents database-specific configuration
 type DatabaseConfig struct {
-	DSN    string `yaml:"dsn"`
-	Schema string `yaml:"schema"`
+	DSN              string `yaml:"dsn"`
+	Schema           string `yaml:"schema"`
+	IntrospectionDSN string `yaml:"introspection_dsn"`
+	AnalysisDSN      string `yaml:"analysis_dsn"`
 }
 
 // OutputConfig represents output-specific configuration
 type OutputConfig struct {
-	Directory string `yaml:"directory"`
-	Package   string `yaml:"package"`
+	Directory   string            `yaml:"directory"`
+	Package     string            `yaml:"package"`
+	Version     string            `yaml:"version"`
+	ImportPath  string            `yaml:"import_path"`
+	SingleFile  string            `yaml:"single_file"`
+	SharedFiles SharedFilesConfig `yaml:"shared_files"`
+}
+
+// SharedFileConfig controls the file name of a shared utility file and whether it is generated
+type SharedFileConfig struct {
+	Filename string `yaml:"filename"`
+	Disabled bool   `yaml:"disabled"`
+}
+
+// FileName returns the configured file name, or defaultName when none is set
+func (s SharedFileConfig) FileName(defaultName string) string {
+	if s.Filename != "" {
+		return s.Filename
+	}
+	return defaultName
+}
+
+// SharedFilesConfig represents the shared utility files generated alongside table repositories
+// Disabling retry omits the retry methods and disabling pagination disables pagination for every
+// table. Table repositories always call the error and database operation helpers, so disabling
+// those files requires the package to provide its own implementations
+type SharedFilesConfig struct {
+	Pagination         SharedFileConfig `yaml:"pagination"`
+	Errors             SharedFileConfig `yaml:"errors"`
+	DatabaseOperations SharedFileConfig `yaml:"database_operations"`
+	Retry              SharedFileConfig `yaml:"retry"`
 }
 
 // TableConfig represents configuration for a specific table
 type TableConfig struct {
 	Functions []string `yaml:"functions"`
+
+	// ModifiedColumn is the timestamp column used by modified_since (defaults to updated_at)
+	ModifiedColumn string `yaml:"modified_column"`
+
+	// UpdatedByColumn is the audit column recording who last updated a row, which list_updated_by
+	// matches (defaults to updated_by)
+	UpdatedByColumn string `yaml:"updated_by_column"`
+
+	// PrefixColumn is the indexed text column search_by_prefix matches prefixes of (defaults to name)
+	PrefixColumn string `yaml:"prefix_column"`
+
+	// RangeColumn is the timestamp column list_between filters on and stats reports bounds for (defaults to created_at)
+	RangeColumn string `yaml:"range_column"`
+
+	// ReturningColumns narrows the columns Create and Update return and scan (defaults to all columns)
+	ReturningColumns []string `yaml:"returning_columns"`
+
+	// Paginate set to false drops ListPaginated and the struct's GetID pagination hook (defaults to true),
+	// and lets a single-column primary key be of a type other than UUID, such as a serial
+	Paginate *bool `yaml:"paginate"`
+
+	// PaginateBy names a NOT NULL timestamp column ListPaginated orders by, with the primary key
+	// breaking ties, in place of the primary key alone
+	PaginateBy string `yaml:"paginate_by"`
+
+	// SoftDeleteColumn names a nullable timestamp column (e.g. deleted_at) that marks rows as deleted;
+	// when set, Delete sets it instead of removing the row and reads skip rows where it is set
+	SoftDeleteColumn string `yaml:"soft_delete_column"`
+
+	// ColumnTypes overrides the Go type of individual columns, keyed by column name; like type_mappings,
+	// nullable columns use the pointer form of the type
+	ColumnTypes map[string]string `yaml:"column_types"`
+
+	// ConflictColumns is the ON CONFLICT target for upserts (defaults to the first single-column
+	// unique index set by the create params)
+	ConflictColumns []string `yaml:"conflict_columns"`
+
+	// UniqueLookups selects the column sets get_by_unique_constraint generates finders for, in parameter
+	// order; each must match a UNIQUE constraint (defaults to every unique constraint)
+	UniqueLookups [][]string `yaml:"unique_lookups"`
+
+	// CountByColumns are the indexed columns count_by generates grouped counts and count_distinct
+	// distinct value counts for (e.g. status)
+	CountByColumns []string `yaml:"count_by_columns"`
+
+	// PartialUpdate makes the Update params fields pointers; nil fields are left unchanged and
+	// an Update with no fields set reads the row back with Get instead
+	PartialUpdate bool `yaml:"partial_update"`
+
+	// AllowFullDelete lets DeleteFiltered run with the zero filter and remove every row, which is
+	// otherwise rejected as a likely mistake
+	AllowFullDelete bool `yaml:"allow_full_delete"`
+
+	// DomainMapping is a domain type (e.g. domain.User, with its package in types.custom_imports) that
+	// GetDomain and ListDomain return, converted by a <Struct>ToDomain function scaffolded once per table
+	DomainMapping string `yaml:"domain_mapping"`
+
+	// IntEnums maps smallint columns that encode enums to named Go int types, keyed by column name
+	IntEnums map[string]IntEnumConfig `yaml:"int_enums"`
+
+	// EagerLoad names child tables referencing this table by foreign key; for each, a ListWith<Child>
+	// method lists the rows together with their children from a single joined query
+	EagerLoad []string `yaml:"eager_load"`
 }
 
+// IntEnumConfig describes the named Go type generated for an enum-like smallint column
+type IntEnumConfig struct {
+	// Type is the Go type name, e.g. OrderKind; it must be unique across all tables' int_enums
+	Type string `yaml:"type"`
+
+	// Values maps constant names to their stored values; pending: 1 yields OrderKindPending OrderKind = 1
+	Values map[string]int16 `yaml:"values"`
+}
+
+// Scan modes for reading multi-row results
+const (
+	ScanModeScan        = "scan"
+	ScanModeCollectRows = "collect_rows"
+)
+
+// Connection types generated repositories are constructed with
+const (
+	DBBackendPgxkit  = "pgxkit"
+	DBBackendPgxpool = "pgxpool"
+)
+
+// Go types for numeric and decimal columns
+const (
+	NumericModeFloat64 = "float64"
+	NumericModePgtype  = "pgtype"
+)
+
+// Go types for inet and cidr columns
+const (
+	NetworkModeString = "string"
+	NetworkModeNetip  = "netip"
+)
+
+// Go types for point columns
+const (
+	GeometryModeString = "string"
+	GeometryModeStruct = "struct"
+)
+
+// paginationFunctions are the functions built on cursor pagination and the GetID hook
+var paginationFunctions = []string{"paginate", "stream", "modified_since", "list_between_paginated"}
+
+// compositeKeyFunctions are the functions generated for tables with a composite primary key; the
+// others rely on a single UUID primary key
+var compositeKeyFunctions = []string{"create", "get", "delete", "list", "upsert", "count", "exists", "create_batch"}
+
+// nonUUIDKeyFunctions are the functions generated for tables with a single primary key of another type,
+// such as a serial, which pagination: false lets through
+var nonUUIDKeyFunctions = slices.Concat(compositeKeyFunctions, []string{"update"})
+
 // TablesConfig represents table generation configuration
 type TablesConfig map[string]TableConfig
 
 // QueriesConfig represents query generation configuration
 type QueriesConfig struct {
-	Enabled   bool     `yaml:"enabled"`
-	Directory string   `yaml:"directory"`
-	Files     []string `yaml:"files"`
+	Enabled           bool     `yaml:"enabled"`
+	Directory         string   `yaml:"directory"`
+	Files             []string `yaml:"files"`
+	ReuseTableStructs *bool    `yaml:"reuse_table_structs"` // Defaults to true
+	ValidateExec      bool     `yaml:"validate_exec"`
 }
 
 // TypesConfig represents type mapping configuration
 type TypesConfig struct {
-	Mappings map[string]string `yaml:"mappings"`
+	Mappings       map[string]string `yaml:"mappings"`
+	ColumnMappings map[string]string `yaml:"column_mappings"`
+	CustomImports  map[string]string `yaml:"custom_imports"`
+	NumericMode    string            `yaml:"numeric_mode"`
+	NetworkMode    string            `yaml:"network_mode"`
+	GeometryMode   string            `yaml:"geometry_mode"`
 }
 
 // FileConfig represents the structure of a configuration file
 type FileConfig struct {
-	Database         DatabaseConfig `yaml:"database"`
-	Output           OutputConfig   `yaml:"output"`
-	Tables           TablesConfig   `yaml:"tables"`
-	Queries          QueriesConfig  `yaml:"queries"`
-	Types            TypesConfig    `yaml:"types"`
-	DefaultFunctions interface{}    `yaml:"default_functions"` // "all" or []string
-	Verbose          bool           `yaml:"verbose"`
+	Database         DatabaseConfig    `yaml:"database"`
+	Output           OutputConfig      `yaml:"output"`
+	Tables           TablesConfig      `yaml:"tables"`
+	Exclude          []string          `yaml:"exclude"`
+	Queries          QueriesConfig     `yaml:"queries"`
+	Types            TypesConfig       `yaml:"types"`
+	DefaultFunctions interface{}       `yaml:"default_functions"` // "all" or []string
+	Verbose          bool              `yaml:"verbose"`
+	Plurals          map[string]string `yaml:"plurals"`
+
+	GenerateRoundtripTests bool `yaml:"generate_roundtrip_tests"`
+	GenerateTestHelpers    bool `yaml:"generate_test_helpers"`
+	GenerateContextHelpers bool `yaml:"generate_context_helpers"`
+	GenerateSchemaHash     bool `yaml:"generate_schema_hash"`
+	GenerateInterfaces     bool `yaml:"generate_interfaces"`
+	GenerateMocks          bool `yaml:"generate_mocks"`
+	GenerateMetrics        bool `yaml:"generate_metrics"`
+	GenerateRelationships  bool `yaml:"generate_relationships"`
+	QuerierPerCall         bool `yaml:"querier_per_call"`
+	RejectNilIDs           bool `yaml:"reject_nil_ids"`
+	QueryOptions           bool `yaml:"query_options"`
+	BatchChunkSize         int  `yaml:"batch_chunk_size"`
+
+	EncryptedColumns []string `yaml:"encrypted_columns"`
+
+	ScanMode  string `yaml:"scan_mode"`
+	DBBackend string `yaml:"db_backend"`
 }
 
 // parseDefaultFunctions parses the default_functions field from YAML
@@ -135,41 +426,132 @@ func LoadConfig(path string) (*Config, error) {
 
 	// Convert FileConfig to Config
 	cfg := &Config{
-		DSN:              fileConfig.Database.DSN,
-		Schema:           fileConfig.Database.Schema,
-		OutputDir:        fileConfig.Output.Directory,
-		PackageName:      fileConfig.Output.Package,
-		Tables:           len(fileConfig.Tables) > 0,
-		QueriesDir:       fileConfig.Queries.Directory,
-		Include:          tableNames,
-		TableConfigs:     fileConfig.Tables,
-		DefaultFunctions: defaultFunctions,
-		TypeMappings:     fileConfig.Types.Mappings,
-		Verbose:          fileConfig.Verbose,
+		DSN:                fileConfig.Database.DSN,
+		Schema:             fileConfig.Database.Schema,
+		IntrospectionDSN:   fileConfig.Database.IntrospectionDSN,
+		AnalysisDSN:        fileConfig.Database.AnalysisDSN,
+		OutputDir:          fileConfig.Output.Directory,
+		PackageName:        fileConfig.Output.Package,
+		Version:            fileConfig.Output.Version,
+		ImportPath:         fileConfig.Output.ImportPath,
+		SingleFile:         fileConfig.Output.SingleFile,
+		SharedFiles:        fileConfig.Output.SharedFiles,
+		Tables:             len(fileConfig.Tables) > 0,
+		QueriesDir:         fileConfig.Queries.Directory,
+		Include:            tableNames,
+		Exclude:            fileConfig.Exclude,
+		TableConfigs:       fileConfig.Tables,
+		DefaultFunctions:   defaultFunctions,
+		TypeMappings:       fileConfig.Types.Mappings,
+		ColumnTypeMappings: fileConfig.Types.ColumnMappings,
+		CustomImports:      fileConfig.Types.CustomImports,
+		NumericMode:        fileConfig.Types.NumericMode,
+		NetworkMode:        fileConfig.Types.NetworkMode,
+		GeometryMode:       fileConfig.Types.GeometryMode,
+		Verbose:            fileConfig.Verbose,
+		Plurals:            fileConfig.Plurals,
+
+		ReuseTableStructs:      fileConfig.Queries.ReuseTableStructs == nil || *fileConfig.Queries.ReuseTableStructs,
+		ValidateExec:           fileConfig.Queries.ValidateExec,
+		GenerateRoundtripTests: fileConfig.GenerateRoundtripTests,
+		GenerateTestHelpers:    fileConfig.GenerateTestHelpers,
+		GenerateContextHelpers: fileConfig.GenerateContextHelpers,
+		GenerateSchemaHash:     fileConfig.GenerateSchemaHash,
+		GenerateInterfaces:     fileConfig.GenerateInterfaces,
+		GenerateMocks:          fileConfig.GenerateMocks,
+		GenerateMetrics:        fileConfig.GenerateMetrics,
+		GenerateRelationships:  fileConfig.GenerateRelationships,
+		QuerierPerCall:         fileConfig.QuerierPerCall,
+		RejectNilIDs:           fileConfig.RejectNilIDs,
+		QueryOptions:           fileConfig.QueryOptions,
+		BatchChunkSize:         fileConfig.BatchChunkSize,
+		EncryptedColumns:       fileConfig.EncryptedColumns,
+		ScanMode:               fileConfig.ScanMode,
+		DBBackend:              fileConfig.DBBackend,
 	}
 
-	// Set defaults
-	if cfg.Schema == "" {
-		cfg.Schema = "public"
+	cfg.setDefaults()
+	return cfg, nil
+}
+
+// DefaultConfig returns the configuration used without a config file, for command-line flags to fill in
+func DefaultConfig() *Config {
+	cfg := &Config{}
+	cfg.setDefaults()
+	return cfg
+}
+
+// setDefaults fills in the schema, output directory and package name when unset
+func (c *Config) setDefaults() {
+	if c.Schema == "" {
+		c.Schema = "public"
 	}
-	if cfg.OutputDir == "" {
-		cfg.OutputDir = "./repositories"
+	if c.OutputDir == "" {
+		c.OutputDir = "./repositories"
 	}
-	if cfg.PackageName == "" {
-		cfg.PackageName = "repositories"
+	if c.PackageName == "" {
+		c.PackageName = "repositories"
 	}
+}
 
-	return cfg, nil
+// postgresEnvVars are the environment variables postgresEnvDSN reads, with their defaults
+var postgresEnvVars = []struct{ name, fallback string }{
+	{"POSTGRES_HOST", "localhost"},
+	{"POSTGRES_PORT", "5432"},
+	{"POSTGRES_USER", "postgres"},
+	{"POSTGRES_PASSWORD", ""},
+	{"POSTGRES_DB", "postgres"},
+	{"POSTGRES_SSLMODE", "disable"},
+}
+
+// postgresEnvDSN builds a DSN from the POSTGRES_* environment variables, using the defaults for
+// the unset ones. It returns "" when none of them is set
+func postgresEnvDSN() string {
+	values := make(map[string]string, len(postgresEnvVars))
+	set := false
+	for _, v := range postgresEnvVars {
+		values[v.name] = v.fallback
+		if value := os.Getenv(v.name); value != "" {
+			values[v.name] = value
+			set = true
+		}
+	}
+	if !set {
+		return ""
+	}
+
+	user := url.User(values["POSTGRES_USER"])
+	if password := values["POSTGRES_PASSWORD"]; password != "" {
+		user = url.UserPassword(values["POSTGRES_USER"], password)
+	}
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     user,
+		Host:     net.JoinHostPort(values["POSTGRES_HOST"], values["POSTGRES_PORT"]),
+		Path:     "/" + values["POSTGRES_DB"],
+		RawQuery: url.Values{"sslmode": {values["POSTGRES_SSLMODE"]}}.Encode(),
+	}
+	return dsn.String()
 }
 
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
-	if c.DSN == "" {
+	return c.validate(true)
+}
+
+// validate checks the configuration, requiring a DSN only when the generator opens its own connections
+func (c *Config) validate(requireDSN bool) error {
+	// DSN may be omitted when both dedicated connections are configured
+	if requireDSN && c.DSN == "" && (c.IntrospectionDSN == "" || c.AnalysisDSN == "") {
 		// Check for TEST_DATABASE_URL environment variable for integration tests
 		if testURL := os.Getenv("TEST_DATABASE_URL"); testURL != "" {
 			c.DSN = testURL
+		} else if databaseURL := os.Getenv("DATABASE_URL"); databaseURL != "" {
+			c.DSN = databaseURL
+		} else if envDSN := postgresEnvDSN(); envDSN != "" {
+			c.DSN = envDSN
 		} else {
-			return fmt.Errorf("database connection string (DSN) is required")
+			return fmt.Errorf("database connection string (DSN) is required, or set DATABASE_URL or the POSTGRES_* environment variables")
 		}
 	}
 
@@ -177,27 +559,150 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("must enable either table generation (--tables) or query generation (--queries)")
 	}
 
+	if c.Version != "" && !token.IsIdentifier(c.Version) {
+		return fmt.Errorf("output.version must be a valid Go package name, such as v2: %s", c.Version)
+	}
+
+	if c.SingleFile != "" && (filepath.Base(c.SingleFile) != c.SingleFile || filepath.Ext(c.SingleFile) != ".go") {
+		return fmt.Errorf("single_file must be a .go file name without a directory: %s", c.SingleFile)
+	}
+
+	for _, shared := range []SharedFileConfig{c.SharedFiles.Pagination, c.SharedFiles.Errors, c.SharedFiles.DatabaseOperations, c.SharedFiles.Retry} {
+		if shared.Filename != "" && (filepath.Base(shared.Filename) != shared.Filename || filepath.Ext(shared.Filename) != ".go") {
+			return fmt.Errorf("shared file name must be a .go file name without a directory: %s", shared.Filename)
+		}
+	}
+
+	if c.NumericMode != "" && c.NumericMode != NumericModeFloat64 && c.NumericMode != NumericModePgtype {
+		return fmt.Errorf("numeric_mode must be %q or %q: %s", NumericModeFloat64, NumericModePgtype, c.NumericMode)
+	}
+
+	if c.NetworkMode != "" && c.NetworkMode != NetworkModeString && c.NetworkMode != NetworkModeNetip {
+		return fmt.Errorf("network_mode must be %q or %q: %s", NetworkModeString, NetworkModeNetip, c.NetworkMode)
+	}
+
+	if c.GeometryMode != "" && c.GeometryMode != GeometryModeString && c.GeometryMode != GeometryModeStruct {
+		return fmt.Errorf("geometry_mode must be %q or %q: %s", GeometryModeString, GeometryModeStruct, c.GeometryMode)
+	}
+
+	for _, pattern := range slices.Concat(c.Include, c.Exclude) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid table pattern %q: %w", pattern, err)
+		}
+	}
+
+	if c.GenerateMocks && !c.GenerateInterfaces {
+		return fmt.Errorf("generate_mocks requires generate_interfaces, since the mocks implement the repository interfaces")
+	}
+
+	if c.BatchChunkSize < 0 {
+		return fmt.Errorf("batch_chunk_size must not be negative: %d", c.BatchChunkSize)
+	}
+
+	for _, column := range c.EncryptedColumns {
+		if table, name, ok := strings.Cut(column, "."); !ok || table == "" || name == "" {
+			return fmt.Errorf("encrypted_columns entries must be table.column: %s", column)
+		}
+	}
+
+	for column := range c.ColumnTypeMappings {
+		if table, name, ok := strings.Cut(column, "."); !ok || table == "" || name == "" {
+			return fmt.Errorf("types.column_mappings keys must be table.column: %s", column)
+		}
+	}
+
+	if c.ScanMode != "" && c.ScanMode != ScanModeScan && c.ScanMode != ScanModeCollectRows {
+		return fmt.Errorf("scan_mode must be %q or %q: %s", ScanModeScan, ScanModeCollectRows, c.ScanMode)
+	}
+
+	if c.DBBackend != "" && c.DBBackend != DBBackendPgxkit && c.DBBackend != DBBackendPgxpool {
+		return fmt.Errorf("db_backend must be %q or %q: %s", DBBackendPgxkit, DBBackendPgxpool, c.DBBackend)
+	}
+
+	for tableName, tableConfig := range c.TableConfigs {
+		if c.IsPaginationEnabled(tableName) {
+			continue
+		}
+		for _, function := range tableConfig.Functions {
+			if slices.Contains(paginationFunctions, function) {
+				if c.SharedFiles.Pagination.Disabled {
+					return fmt.Errorf("table %s requests function %s, which needs the shared pagination file that shared_files.pagination.disabled turns off", tableName, function)
+				}
+				return fmt.Errorf("table %s disables pagination but requests function %s", tableName, function)
+			}
+		}
+	}
+
+	if err := c.validateIntEnumTypes(); err != nil {
+		return err
+	}
+
 	if c.QueriesDir != "" {
 		if _, err := os.Stat(c.QueriesDir); os.IsNotExist(err) {
 			return fmt.Errorf("queries directory does not exist: %s", c.QueriesDir)
 		}
 	}
 
-	// Ensure output directory exists or can be created
-	if err := os.MkdirAll(c.OutputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+	// Ensure output directory exists or can be created; a dry run leaves the disk untouched
+	if !c.DryRun {
+		if err := os.MkdirAll(c.GetOutputDir(), 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
 	}
 
 	return nil
 }
 
+// GetIntrospectionDSN returns the connection string used for schema introspection
+func (c *Config) GetIntrospectionDSN() string {
+	if c.IntrospectionDSN != "" {
+		return c.IntrospectionDSN
+	}
+	return c.DSN
+}
+
+// GetAnalysisDSN returns the connection string used for query analysis
+func (c *Config) GetAnalysisDSN() string {
+	if c.AnalysisDSN != "" {
+		return c.AnalysisDSN
+	}
+	return c.DSN
+}
+
+// GetOutputDir returns the directory generated files are written to, the version subdirectory
+// of OutputDir when a version is set
+func (c *Config) GetOutputDir() string {
+	return filepath.Join(c.OutputDir, c.Version)
+}
+
+// GetMocksDir returns the directory of the mocks subpackage generate_mocks writes to
+func (c *Config) GetMocksDir() string {
+	return filepath.Join(c.GetOutputDir(), "mocks")
+}
+
+// GetPackageName returns the package name of the generated code; a versioned package is named
+// after its version
+func (c *Config) GetPackageName() string {
+	if c.Version != "" {
+		return c.Version
+	}
+	return c.PackageName
+}
+
 // GetOutputPath returns the full path for a generated file
 func (c *Config) GetOutputPath(filename string) string {
-	return filepath.Join(c.OutputDir, filename)
+	return filepath.Join(c.GetOutputDir(), filename)
 }
 
-// ShouldIncludeTable checks if a table should be included based on include patterns
+// ShouldIncludeTable checks if a table should be included based on include and exclude patterns;
+// an exclude match wins, so "*" can include every table but a few
 func (c *Config) ShouldIncludeTable(tableName string) bool {
+	for _, pattern := range c.Exclude {
+		if matched, _ := filepath.Match(pattern, tableName); matched {
+			return false
+		}
+	}
+
 	// No include patterns means no tables are included
 	if len(c.Include) == 0 {
 		return false
@@ -213,6 +718,84 @@ func (c *Config) ShouldIncludeTable(tableName string) bool {
 	return false
 }
 
+// GetModifiedColumn returns the timestamp column tracking row modifications for a table
+func (c *Config) GetModifiedColumn(tableName string) string {
+	if config, exists := c.TableConfigs[tableName]; exists && config.ModifiedColumn != "" {
+		return config.ModifiedColumn
+	}
+	return "updated_at"
+}
+
+// GetUpdatedByColumn returns the audit column recording who last updated a table's rows
+func (c *Config) GetUpdatedByColumn(tableName string) string {
+	if config, exists := c.TableConfigs[tableName]; exists && config.UpdatedByColumn != "" {
+		return config.UpdatedByColumn
+	}
+	return "updated_by"
+}
+
+// GetRangeColumn returns the timestamp column date range listings filter on for a table
+func (c *Config) GetRangeColumn(tableName string) string {
+	if config, exists := c.TableConfigs[tableName]; exists && config.RangeColumn != "" {
+		return config.RangeColumn
+	}
+	return "created_at"
+}
+
+// GetDBType returns the Go type of the connection generated constructors take and the import path
+// of its package
+func (c *Config) GetDBType() (string, string) {
+	if c.DBBackend == DBBackendPgxpool {
+		return "*pgxpool.Pool", "github.com/jackc/pgx/v5/pgxpool"
+	}
+	return "*pgxkit.DB", "github.com/nhalm/pgxkit"
+}
+
+// GetPrefixColumn returns the text column prefix searches match on for a table
+func (c *Config) GetPrefixColumn(tableName string) string {
+	if config, exists := c.TableConfigs[tableName]; exists && config.PrefixColumn != "" {
+		return config.PrefixColumn
+	}
+	return "name"
+}
+
+// GetSoftDeleteColumn returns the table's soft-delete column, or "" when rows are hard-deleted
+func (c *Config) GetSoftDeleteColumn(tableName string) string {
+	return c.TableConfigs[tableName].SoftDeleteColumn
+}
+
+// GetDomainMapping returns the domain type a table's rows are converted to, if any
+func (c *Config) GetDomainMapping(tableName string) string {
+	return c.TableConfigs[tableName].DomainMapping
+}
+
+// GetColumnTypes returns the per-column Go type overrides for a table
+func (c *Config) GetColumnTypes(tableName string) map[string]string {
+	return c.TableConfigs[tableName].ColumnTypes
+}
+
+// validateIntEnumTypes ensures no two int_enums columns name the same Go type, which every table's
+// repository would otherwise declare again in the one generated package
+func (c *Config) validateIntEnumTypes() error {
+	declaredBy := make(map[string]string)
+	for _, tableName := range slices.Sorted(maps.Keys(c.TableConfigs)) {
+		intEnums := c.TableConfigs[tableName].IntEnums
+		for _, column := range slices.Sorted(maps.Keys(intEnums)) {
+			enumType := intEnums[column].Type
+			if other, exists := declaredBy[enumType]; exists {
+				return fmt.Errorf("int_enums type %s is configured for both %s and %s.%s; give each column its own type name", enumType, other, tableName, column)
+			}
+			declaredBy[enumType] = tableName + "." + column
+		}
+	}
+	return nil
+}
+
+// GetIntEnums returns the enum-like smallint columns of a table
+func (c *Config) GetIntEnums(tableName string) map[string]IntEnumConfig {
+	return c.TableConfigs[tableName].IntEnums
+}
+
 // GetTableFunctions returns the list of functions to generate for a specific table
 func (c *Config) GetTableFunctions(tableName string) []string {
 	// Check for table-specific override first
@@ -224,10 +807,28 @@ func (c *Config) GetTableFunctions(tableName string) []string {
 	}
 
 	// Use global default_functions if specified
-	if len(c.DefaultFunctions) > 0 {
-		return c.DefaultFunctions
+	functions := c.DefaultFunctions
+	if len(functions) == 0 {
+		// Final fallback to all functions
+		functions = []string{"create", "get", "update", "delete", "list", "paginate"}
 	}
 
-	// Final fallback to all functions
-	return []string{"create", "get", "update", "delete", "list", "paginate"}
+	// Defaults drop the pagination functions for tables that opt out
+	if !c.IsPaginationEnabled(tableName) {
+		functions = slices.DeleteFunc(slices.Clone(functions), func(function string) bool {
+			return slices.Contains(paginationFunctions, function)
+		})
+	}
+
+	return functions
+}
+
+// IsPaginationEnabled reports whether a table gets pagination support and the GetID hook
+func (c *Config) IsPaginationEnabled(tableName string) bool {
+	// The pagination functions build on the shared pagination file
+	if c.SharedFiles.Pagination.Disabled {
+		return false
+	}
+	config, exists := c.TableConfigs[tableName]
+	return !exists || config.Paginate == nil || *config.Paginate
 }
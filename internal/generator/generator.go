@@ -3,7 +3,11 @@ package generator
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"slices"
+	"strings"
 
 	"github.com/nhalm/pgxkit"
 )
@@ -11,34 +15,79 @@ import (
 // Generator handles the code generation process
 type Generator struct {
 	config     *Config
-	db         *pgxkit.DB
+	db         *pgxkit.DB // Introspection connection, which may be a read replica
+	analysisDB *pgxkit.DB // Query analysis connection, which must be writable
 	introspect *Introspector
+	analyzer   *QueryAnalyzer
 	codegen    *CodeGenerator
+
+	// tables holds the generated tables whose structs queries may reuse
+	tables []Table
+
+	// connectDB opens a database connection for a DSN
+	connectDB func(ctx context.Context, dsn string) (*pgxkit.DB, error)
+
+	// injected is set when the caller supplied the connection, which the generator then neither opens nor closes
+	injected bool
+
+	// output receives the generated SQL when PrintSQL is set and the file list of a dry run
+	output io.Writer
 }
 
 // New creates a new generator instance
 func New(config *Config) *Generator {
 	return &Generator{
-		config: config,
+		config:    config,
+		connectDB: connectPgxkit,
+		output:    os.Stdout,
 	}
 }
 
+// NewWithDB creates a generator that uses an existing, connected database for both
+// introspection and query analysis. The caller keeps ownership of db: Generate neither
+// connects nor shuts it down, and the config's DSNs are not required.
+func NewWithDB(config *Config, db *pgxkit.DB) *Generator {
+	g := New(config)
+	g.db = db
+	g.analysisDB = db
+	g.injected = true
+	return g
+}
+
+// newCodeGenerator creates the code generator. In a dry run every file is still
+// rendered and formatted, so broken output fails generation, but only its path and
+// size are reported instead of writing it
+func (g *Generator) newCodeGenerator() *CodeGenerator {
+	cg := NewCodeGenerator(g.config)
+	if g.config.DryRun {
+		cg.writeFile = func(filename string, data []byte) error {
+			_, err := fmt.Fprintf(g.output, "%s (%d bytes)\n", filename, len(data))
+			return err
+		}
+	}
+	return cg
+}
+
 // Generate runs the complete generation process
 func (g *Generator) Generate(ctx context.Context) error {
-	// Validate configuration
-	if err := g.config.Validate(); err != nil {
+	// Validate configuration; an injected connection makes the DSNs unnecessary
+	if err := g.config.validate(!g.injected); err != nil {
 		return fmt.Errorf("configuration validation failed: %w", err)
 	}
 
-	// Connect to database
-	if err := g.connect(ctx); err != nil {
-		return fmt.Errorf("database connection failed: %w", err)
+	// Connect to database unless the caller supplied one
+	if !g.injected {
+		if err := g.connect(ctx); err != nil {
+			return fmt.Errorf("database connection failed: %w", err)
+		}
+		defer g.shutdown()
 	}
-	defer g.db.Shutdown(context.Background())
 
 	// Initialize components
 	g.introspect = NewIntrospector(g.db, g.config.Schema)
-	g.codegen = NewCodeGenerator(g.config)
+	g.analyzer = NewQueryAnalyzer(g.analysisDB, newConfiguredTypeMapper(g.config))
+	g.analyzer.validateExec = g.config.ValidateExec
+	g.codegen = g.newCodeGenerator()
 
 	if g.config.Verbose {
 		log.Printf("Connected to database, schema: %s", g.config.Schema)
@@ -46,26 +95,23 @@ func (g *Generator) Generate(ctx context.Context) error {
 
 	// Generate table-based repositories
 	if g.config.Tables {
-		// Generate shared files first
-		if err := g.generateSharedPaginationTypes(); err != nil {
-			return fmt.Errorf("shared pagination types generation failed: %w", err)
-		}
-
-		if err := g.generateSharedErrors(); err != nil {
-			return fmt.Errorf("shared error handling generation failed: %w", err)
-		}
-
-		if err := g.generateSharedDatabaseOperations(); err != nil {
-			return fmt.Errorf("shared database operations generation failed: %w", err)
-		}
-
-		if err := g.generateSharedRetryOperations(); err != nil {
-			return fmt.Errorf("shared retry operations generation failed: %w", err)
+		// Generate shared files first; they hold no table SQL to print
+		if !g.config.PrintSQL {
+			if err := g.generateSharedFiles(); err != nil {
+				return err
+			}
 		}
 
 		if err := g.generateTables(ctx); err != nil {
 			return fmt.Errorf("table generation failed: %w", err)
 		}
+
+		// Mocks implement the repository interfaces, so they follow the tables
+		if g.config.GenerateMocks && !g.config.PrintSQL {
+			if err := g.generateMocks(); err != nil {
+				return fmt.Errorf("mock generation failed: %w", err)
+			}
+		}
 	}
 
 	// Generate query-based code
@@ -75,26 +121,80 @@ func (g *Generator) Generate(ctx context.Context) error {
 		}
 	}
 
+	// Write the aggregated file once everything has been generated
+	if g.config.SingleFile != "" && !g.config.PrintSQL {
+		if err := g.codegen.WriteSingleFile(); err != nil {
+			return fmt.Errorf("single file generation failed: %w", err)
+		}
+	}
+
 	if g.config.Verbose {
-		log.Printf("Successfully generated code in %s", g.config.OutputDir)
+		log.Printf("Successfully generated code in %s", g.config.GetOutputDir())
 	}
 
 	return nil
 }
 
-// connect establishes a connection to the PostgreSQL database
+// reusableTables returns the generated tables whose structs can hold the query's results
+// Paginated queries need the GetID hook, which tables with pagination disabled don't have
+func (g *Generator) reusableTables(query Query) []Table {
+	if query.Type != QueryTypePaginated {
+		return g.tables
+	}
+
+	var tables []Table
+	for _, table := range g.tables {
+		if g.config.IsPaginationEnabled(table.Name) && !table.HasCompositePrimaryKey() {
+			tables = append(tables, table)
+		}
+	}
+	return tables
+}
+
+// connect establishes the introspection and analysis connections to PostgreSQL
+// A single connection is shared when both use the same DSN
 func (g *Generator) connect(ctx context.Context) error {
-	// Use pgxkit for connection management
-	db := pgxkit.NewDB()
-	err := db.Connect(ctx, g.config.DSN)
+	introspectionDSN := g.config.GetIntrospectionDSN()
+	analysisDSN := g.config.GetAnalysisDSN()
+
+	db, err := g.connectDB(ctx, introspectionDSN)
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
-
 	g.db = db
+
+	if analysisDSN == introspectionDSN {
+		g.analysisDB = db
+		return nil
+	}
+
+	analysisDB, err := g.connectDB(ctx, analysisDSN)
+	if err != nil {
+		db.Shutdown(context.Background())
+		return fmt.Errorf("failed to connect to analysis database: %w", err)
+	}
+	g.analysisDB = analysisDB
+
 	return nil
 }
 
+// shutdown closes the database connections
+func (g *Generator) shutdown() {
+	if g.analysisDB != nil && g.analysisDB != g.db {
+		g.analysisDB.Shutdown(context.Background())
+	}
+	g.db.Shutdown(context.Background())
+}
+
+// connectPgxkit connects to PostgreSQL using pgxkit for connection management
+func connectPgxkit(ctx context.Context, dsn string) (*pgxkit.DB, error) {
+	db := pgxkit.NewDB()
+	if err := db.Connect(ctx, dsn); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
 // generateTables generates repositories for database tables
 func (g *Generator) generateTables(ctx context.Context) error {
 	if g.config.Verbose {
@@ -123,6 +223,13 @@ func (g *Generator) generateTables(ctx context.Context) error {
 		log.Printf("Generating code for %d tables after filtering", len(filteredTables))
 	}
 
+	if err := g.validateColumnMappings(filteredTables); err != nil {
+		return err
+	}
+
+	// Relationship loaders may only reference tables generated alongside them
+	g.codegen.relatedTables = filteredTables
+
 	// Generate code for each table
 	for _, table := range filteredTables {
 		if g.config.Verbose {
@@ -133,11 +240,112 @@ func (g *Generator) generateTables(ctx context.Context) error {
 		if err := g.validateTablePrimaryKey(table); err != nil {
 			return fmt.Errorf("table %s validation failed: %w", table.Name, err)
 		}
-
-		// Generate repository code
-		if err := g.codegen.GenerateTableRepository(table); err != nil {
+		if keyFunctions(table) != nil {
+			g.logKeySkips(table)
+		}
+		g.logEncryptedSkips(table)
+
+		// Generate repository code, or only print its SQL
+		if g.config.PrintSQL {
+			if err := g.codegen.WriteTableSQL(g.output, table); err != nil {
+				return fmt.Errorf("failed to print SQL for table %s: %w", table.Name, err)
+			}
+		} else if err := g.codegen.GenerateTableRepository(table); err != nil {
 			return fmt.Errorf("failed to generate repository for table %s: %w", table.Name, err)
 		}
+		g.tables = append(g.tables, table)
+	}
+
+	// Enum and geometric types are shared across tables, so they are generated once all tables are mapped
+	if !g.config.PrintSQL {
+		if err := g.codegen.GenerateEnums(); err != nil {
+			return err
+		}
+		if err := g.codegen.GenerateGeometry(); err != nil {
+			return err
+		}
+		if err := g.codegen.GenerateEncryption(); err != nil {
+			return err
+		}
+	}
+
+	// The fingerprint covers exactly the generated tables
+	if g.config.GenerateSchemaHash && !g.config.PrintSQL {
+		tableNames := make([]string, len(g.tables))
+		for i, table := range g.tables {
+			tableNames[i] = table.Name
+		}
+		hash, err := g.introspect.SchemaHash(ctx, tableNames)
+		if err != nil {
+			return fmt.Errorf("failed to compute schema hash: %w", err)
+		}
+		if err := g.codegen.GenerateSchemaHash(hash, tableNames); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// generateMocks generates the mocks subpackage with a mock of each generated table's repository
+func (g *Generator) generateMocks() error {
+	if !g.config.DryRun {
+		if err := os.MkdirAll(g.config.GetMocksDir(), 0755); err != nil {
+			return fmt.Errorf("failed to create mocks directory: %w", err)
+		}
+	}
+
+	if err := g.codegen.GenerateMockCall(); err != nil {
+		return err
+	}
+	for _, table := range g.tables {
+		if err := g.codegen.GenerateTableMock(table); err != nil {
+			return fmt.Errorf("failed to generate mock for table %s: %w", table.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// generateSharedFiles generates each shared utility file that is not disabled in the configuration
+func (g *Generator) generateSharedFiles() error {
+	shared := g.config.SharedFiles
+
+	if !shared.Pagination.Disabled {
+		if err := g.generateSharedPaginationTypes(); err != nil {
+			return fmt.Errorf("shared pagination types generation failed: %w", err)
+		}
+	}
+
+	if !shared.Errors.Disabled {
+		if err := g.generateSharedErrors(); err != nil {
+			return fmt.Errorf("shared error handling generation failed: %w", err)
+		}
+	}
+
+	if !shared.DatabaseOperations.Disabled {
+		if err := g.generateSharedDatabaseOperations(); err != nil {
+			return fmt.Errorf("shared database operations generation failed: %w", err)
+		}
+	}
+
+	if !shared.Retry.Disabled {
+		if err := g.generateSharedRetryOperations(); err != nil {
+			return fmt.Errorf("shared retry operations generation failed: %w", err)
+		}
+	}
+
+	if g.config.QueryOptions {
+		if err := g.codegen.GenerateSharedQueryOptions(); err != nil {
+			return fmt.Errorf("shared query options generation failed: %w", err)
+		}
+	}
+
+	// Context helpers are opt-in, as only audit and tracing features read from context
+	if g.config.GenerateContextHelpers {
+		if err := g.codegen.GenerateSharedContextHelpers(); err != nil {
+			return fmt.Errorf("shared context helpers generation failed: %w", err)
+		}
 	}
 
 	return nil
@@ -180,16 +388,39 @@ func (g *Generator) generateQueries(ctx context.Context) error {
 		log.Printf("Found %d queries to generate", len(queries))
 	}
 
-	// Analyze queries against database
-	analyzer := NewQueryAnalyzer(g.db)
+	if err := g.validateQueryTypes(queries); err != nil {
+		return err
+	}
+
+	// Analyze queries against the analysis database
 	for i := range queries {
 		if g.config.Verbose {
 			log.Printf("Analyzing query: %s", queries[i].Name)
 		}
 
-		if err := analyzer.AnalyzeQuery(ctx, &queries[i]); err != nil {
+		if err := g.analyzer.AnalyzeQuery(ctx, &queries[i]); err != nil {
 			return fmt.Errorf("failed to analyze query %s: %w", queries[i].Name, err)
 		}
+
+		if queries[i].Type == QueryTypeExec && len(queries[i].Columns) > 0 {
+			log.Printf("Warning: query %s is :exec with a RETURNING clause, so it returns a slice of the returned rows; annotate it :one to return a single row", queries[i].Name)
+		}
+
+		if g.config.ReuseTableStructs {
+			if err := g.analyzer.MatchTableStruct(&queries[i], g.reusableTables(queries[i])); err != nil {
+				return fmt.Errorf("failed to match query %s to a table struct: %w", queries[i].Name, err)
+			}
+			if g.config.Verbose && queries[i].ResultStruct != "" {
+				log.Printf("Query %s reuses the %s struct", queries[i].Name, queries[i].ResultStruct)
+			}
+		}
+	}
+
+	if g.config.PrintSQL {
+		if err := g.codegen.WriteQuerySQL(g.output, queries); err != nil {
+			return fmt.Errorf("failed to print query SQL: %w", err)
+		}
+		return nil
 	}
 
 	// Generate code for queries
@@ -200,14 +431,103 @@ func (g *Generator) generateQueries(ctx context.Context) error {
 	return nil
 }
 
-// validateTablePrimaryKey ensures the table has a UUID primary key
+// validateQueryTypes rejects :paginated queries when the shared pagination file their generated
+// code builds on (cursor encoding and PaginationResult) is disabled
+func (g *Generator) validateQueryTypes(queries []Query) error {
+	if !g.config.SharedFiles.Pagination.Disabled {
+		return nil
+	}
+	for _, query := range queries {
+		if query.Type == QueryTypePaginated {
+			return fmt.Errorf("query %s is :paginated, which needs the shared pagination file that shared_files.pagination.disabled turns off", query.Name)
+		}
+	}
+	return nil
+}
+
+// validateColumnMappings rejects column mappings whose table is not among the generated tables,
+// since the mapping would otherwise be silently unused
+func (g *Generator) validateColumnMappings(tables []Table) error {
+	for key := range g.config.ColumnTypeMappings {
+		tableName, _, _ := strings.Cut(key, ".")
+		if !slices.ContainsFunc(tables, func(table Table) bool { return table.Name == tableName }) {
+			return fmt.Errorf("types.column_mappings key %s references table %s, which is not generated", key, tableName)
+		}
+	}
+	return nil
+}
+
+// logKeySkips reports the configured functions a table with a composite or non-UUID primary key
+// doesn't get, since they rely on a single UUID primary key
+func (g *Generator) logKeySkips(table Table) {
+	var skipped []string
+	for _, function := range g.config.GetTableFunctions(table.Name) {
+		if !slices.Contains(keyFunctions(table), function) {
+			skipped = append(skipped, function)
+		}
+	}
+	if len(skipped) == 0 {
+		return
+	}
+	key := "a composite primary key"
+	if !table.HasCompositePrimaryKey() {
+		key = "a non-UUID primary key"
+	}
+	log.Printf("Table %s has %s (%s); skipping %s, which need a single UUID primary key",
+		table.Name, key, strings.Join(table.PrimaryKey, ", "), strings.Join(skipped, ", "))
+}
+
+// logEncryptedSkips reports the unique indexes and constraints over encrypted columns that the
+// unique-key lookups skip, since the stored ciphertext never matches a plaintext key
+func (g *Generator) logEncryptedSkips(table Table) {
+	if len(g.config.EncryptedColumns) == 0 {
+		return
+	}
+	encrypted := func(name string) bool {
+		return slices.Contains(g.config.EncryptedColumns, table.Name+"."+name)
+	}
+	functions := g.config.GetTableFunctions(table.Name)
+	tableConfig := g.config.TableConfigs[table.Name]
+
+	var users []string
+	for _, function := range []string{"get_by_unique", "ensure_by_unique", "upsert", "upsert_with_status"} {
+		if slices.Contains(functions, function) && (!strings.HasPrefix(function, "upsert") || len(tableConfig.ConflictColumns) == 0) {
+			users = append(users, function)
+		}
+	}
+	if len(users) > 0 {
+		for _, index := range table.Indexes {
+			if index.IsUnique && len(index.Columns) == 1 && encrypted(index.Columns[0]) {
+				log.Printf("Table %s: skipping unique index %s for %s, since column %s is encrypted",
+					table.Name, index.Name, strings.Join(users, ", "), index.Columns[0])
+			}
+		}
+	}
+
+	if slices.Contains(functions, "get_by_unique_constraint") && len(tableConfig.UniqueLookups) == 0 {
+		for _, constraint := range table.UniqueConstraints {
+			if slices.ContainsFunc(constraint.Columns, encrypted) {
+				log.Printf("Table %s: skipping unique constraint %s for get_by_unique_constraint, since it covers an encrypted column",
+					table.Name, constraint.Name)
+			}
+		}
+	}
+}
+
+// validateTablePrimaryKey ensures the table has a UUID primary key, a primary key of another type
+// with pagination disabled, or a composite primary key whose columns exist
 func (g *Generator) validateTablePrimaryKey(table Table) error {
 	if len(table.PrimaryKey) == 0 {
 		return fmt.Errorf("table has no primary key")
 	}
 
-	if len(table.PrimaryKey) > 1 {
-		return fmt.Errorf("composite primary keys are not supported")
+	if table.HasCompositePrimaryKey() {
+		for _, name := range table.PrimaryKey {
+			if table.GetColumn(name) == nil {
+				return fmt.Errorf("primary key column %s not found", name)
+			}
+		}
+		return nil
 	}
 
 	pkColumn := table.PrimaryKey[0]
@@ -216,7 +536,13 @@ func (g *Generator) validateTablePrimaryKey(table Table) error {
 		return fmt.Errorf("primary key column %s not found", pkColumn)
 	}
 
-	if !column.IsUUID() {
+	// Only cursor pagination needs a UUID key; other tables may use a serial or natural key
+	if !column.IsUUID() && g.config.IsPaginationEnabled(table.Name) {
+		// A serial or identity key is a supported integer key once the table opts out of pagination
+		if column.IsAutoGenerated {
+			return fmt.Errorf("primary key column %s must be UUID type for cursor pagination, got a database-generated %s. "+
+				"Set paginate: false on table %s to generate CRUD keyed by it", pkColumn, column.Type, table.Name)
+		}
 		return fmt.Errorf("primary key column %s must be UUID type, got %s. "+
 			"skimatik requires UUID v7 primary keys for consistent time-ordered pagination. "+
 			"Please migrate your table to use UUID primary keys", pkColumn, column.Type)
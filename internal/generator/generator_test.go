@@ -0,0 +1,366 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/nhalm/pgxkit"
+)
+
+func TestGenerator_generateSharedFiles(t *testing.T) {
+	config := getTestConfigWithTempDir(t)
+	config.SharedFiles = SharedFilesConfig{
+		Errors: SharedFileConfig{Filename: "db_errors.go"},
+		Retry:  SharedFileConfig{Disabled: true},
+	}
+
+	g := New(config)
+	g.codegen = NewCodeGenerator(config)
+	if err := g.generateSharedFiles(); err != nil {
+		t.Fatalf("generateSharedFiles failed: %v", err)
+	}
+
+	for _, filename := range []string{"pagination.go", "db_errors.go", "database_operations.go"} {
+		if _, err := os.Stat(filepath.Join(config.OutputDir, filename)); err != nil {
+			t.Errorf("Expected shared file %s to be generated: %v", filename, err)
+		}
+	}
+	for _, filename := range []string{"errors.go", "retry_operations.go"} {
+		if _, err := os.Stat(filepath.Join(config.OutputDir, filename)); !os.IsNotExist(err) {
+			t.Errorf("Shared file %s should not be generated", filename)
+		}
+	}
+
+	// Table repositories must not reference the disabled retry helpers
+	if err := g.codegen.GenerateTableRepository(getTestTable()); err != nil {
+		t.Fatalf("GenerateTableRepository failed: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(config.OutputDir, "users_generated.go"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	for _, reference := range []string{"RetryOperation", "DefaultRetryConfig", "WithRetry"} {
+		if strings.Contains(string(content), reference) {
+			t.Errorf("Generated repository references %s although retry is disabled", reference)
+		}
+	}
+}
+
+func TestGenerator_PaginationFileDisabled(t *testing.T) {
+	config := getTestConfigWithTempDir(t)
+	config.DSN = "postgres://localhost/test"
+	config.Tables = true
+	config.SharedFiles = SharedFilesConfig{Pagination: SharedFileConfig{Disabled: true}}
+
+	// Explicitly requesting a function built on the disabled file is a configuration error
+	config.TableConfigs = map[string]TableConfig{"users": {Functions: []string{"get", "paginate"}}}
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "shared_files.pagination.disabled") {
+		t.Errorf("Expected a disabled pagination file error, got %v", err)
+	}
+	config.TableConfigs = nil
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	g := New(config)
+	g.codegen = NewCodeGenerator(config)
+	if err := g.generateSharedFiles(); err != nil {
+		t.Fatalf("generateSharedFiles failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(config.OutputDir, "pagination.go")); !os.IsNotExist(err) {
+		t.Error("pagination.go should not be generated")
+	}
+
+	// The default functions drop pagination, so the repository needs nothing from the missing file
+	if err := g.codegen.GenerateTableRepository(getTestTable()); err != nil {
+		t.Fatalf("GenerateTableRepository failed: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(config.OutputDir, "users_generated.go"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	for _, reference := range []string{"PaginationParams", "PaginationResult", "encodeCursor", "ListPaginated"} {
+		if strings.Contains(string(content), reference) {
+			t.Errorf("Generated repository references %s although the pagination file is disabled", reference)
+		}
+	}
+
+	if testing.Short() {
+		t.Skip("skipping compile test in short mode")
+	}
+	compileGeneratedCode(t, config.OutputDir)
+}
+
+func TestGenerator_PaginatedQueryWithPaginationFileDisabled(t *testing.T) {
+	config := getTestConfigWithTempDir(t)
+	config.QueriesDir = t.TempDir()
+	config.SharedFiles = SharedFilesConfig{Pagination: SharedFileConfig{Disabled: true}}
+	queries := "-- name: ListActiveUsers :paginated\nSELECT id, name FROM users WHERE is_active = true;\n"
+	if err := os.WriteFile(filepath.Join(config.QueriesDir, "users.sql"), []byte(queries), 0644); err != nil {
+		t.Fatalf("Failed to write queries file: %v", err)
+	}
+
+	// The query is rejected before analysis, whose generated code would call the missing cursor helpers
+	g := New(config)
+	err := g.generateQueries(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "query ListActiveUsers is :paginated") || !strings.Contains(err.Error(), "shared_files.pagination.disabled") {
+		t.Errorf("Expected a disabled pagination file error, got %v", err)
+	}
+}
+
+func TestGenerator_ValidateColumnMappings(t *testing.T) {
+	config := getTestConfigWithTempDir(t)
+	config.ColumnTypeMappings = map[string]string{"posts.metadata": "PostMetadata"}
+	g := New(config)
+
+	if err := g.validateColumnMappings([]Table{{Name: "users"}, {Name: "posts"}}); err != nil {
+		t.Errorf("validateColumnMappings() error = %v, want nil for a generated table", err)
+	}
+	// A mapping for a table outside the generated set would otherwise go unused
+	err := g.validateColumnMappings([]Table{{Name: "users"}})
+	if err == nil || !strings.Contains(err.Error(), "references table posts, which is not generated") {
+		t.Errorf("Expected an unknown table error, got %v", err)
+	}
+}
+
+func TestGenerator_DryRun(t *testing.T) {
+	config := getTestConfigWithTempDir(t)
+	config.OutputDir = filepath.Join(config.OutputDir, "generated")
+	config.DSN = "postgres://localhost/test"
+	config.Tables = true
+	config.DryRun = true
+
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	var output strings.Builder
+	g := New(config)
+	g.output = &output
+	g.codegen = g.newCodeGenerator()
+	if err := g.generateSharedFiles(); err != nil {
+		t.Fatalf("generateSharedFiles failed: %v", err)
+	}
+	if err := g.codegen.GenerateTableRepository(getTestTable()); err != nil {
+		t.Fatalf("GenerateTableRepository failed: %v", err)
+	}
+
+	if _, err := os.Stat(config.OutputDir); !os.IsNotExist(err) {
+		t.Errorf("Dry run created the output directory %s", config.OutputDir)
+	}
+	for _, filename := range []string{"pagination.go", "users_generated.go"} {
+		pattern := regexp.QuoteMeta(filepath.Join(config.OutputDir, filename)) + ` \(\d+ bytes\)`
+		if !regexp.MustCompile(pattern).MatchString(output.String()) {
+			t.Errorf("Dry run output does not list %s with its size:\n%s", filename, output.String())
+		}
+	}
+}
+
+func TestGenerator_generateSharedContextHelpers(t *testing.T) {
+	config := getTestConfigWithTempDir(t)
+	g := New(config)
+	g.codegen = NewCodeGenerator(config)
+
+	filename := filepath.Join(config.OutputDir, "context_helpers.go")
+	if err := g.generateSharedFiles(); err != nil {
+		t.Fatalf("generateSharedFiles failed: %v", err)
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Error("Context helpers should only be generated when enabled")
+	}
+
+	config.GenerateContextHelpers = true
+	if err := g.generateSharedFiles(); err != nil {
+		t.Fatalf("generateSharedFiles failed: %v", err)
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read context helpers: %v", err)
+	}
+
+	expected := []string{
+		"type contextKey struct",
+		"var userIDContextKey = &contextKey{\"user_id\"}",
+		"func WithUserID(ctx context.Context, id uuid.UUID) context.Context",
+		"func UserIDFromContext(ctx context.Context) (uuid.UUID, bool)",
+	}
+	for _, want := range expected {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("context helpers missing %q\n%s", want, content)
+		}
+	}
+}
+
+func TestGenerator_connectSeparateDSNs(t *testing.T) {
+	ctx := context.Background()
+
+	config := getTestConfigWithTempDir(t)
+	config.DSN = ""
+	config.IntrospectionDSN = "postgres://replica/app"
+	config.AnalysisDSN = "postgres://primary/app"
+	config.Tables = true
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate failed without a shared DSN: %v", err)
+	}
+
+	connected := make(map[*pgxkit.DB]string)
+	g := New(config)
+	g.connectDB = func(ctx context.Context, dsn string) (*pgxkit.DB, error) {
+		db := pgxkit.NewDB()
+		connected[db] = dsn
+		return db, nil
+	}
+
+	if err := g.connect(ctx); err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	defer g.shutdown()
+
+	if got := connected[g.db]; got != config.IntrospectionDSN {
+		t.Errorf("Introspection connected to %q, want replica %q", got, config.IntrospectionDSN)
+	}
+	if got := connected[g.analysisDB]; got != config.AnalysisDSN {
+		t.Errorf("Analysis connected to %q, want primary %q", got, config.AnalysisDSN)
+	}
+
+	// Without overrides both roles share a single connection
+	config = getTestConfigWithTempDir(t)
+	config.DSN = "postgres://primary/app"
+	shared := New(config)
+	connections := 0
+	shared.connectDB = func(ctx context.Context, dsn string) (*pgxkit.DB, error) {
+		connections++
+		return pgxkit.NewDB(), nil
+	}
+
+	if err := shared.connect(ctx); err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	defer shared.shutdown()
+
+	if connections != 1 || shared.db != shared.analysisDB {
+		t.Errorf("Expected one shared connection, got %d connections", connections)
+	}
+}
+
+func TestGenerator_validateTablePrimaryKey(t *testing.T) {
+	g := New(&Config{})
+	table := Table{
+		Name: "memberships",
+		Columns: []Column{
+			{Name: "tenant_id", Type: "uuid"},
+			{Name: "seq", Type: "integer"},
+		},
+		PrimaryKey: []string{"tenant_id", "seq"},
+	}
+
+	// Composite keys may use any column types
+	if err := g.validateTablePrimaryKey(table); err != nil {
+		t.Errorf("composite primary key should be accepted, got %v", err)
+	}
+
+	table.PrimaryKey = []string{"tenant_id", "missing"}
+	if err := g.validateTablePrimaryKey(table); err == nil || !strings.Contains(err.Error(), "missing not found") {
+		t.Errorf("expected missing key column error, got %v", err)
+	}
+
+	// A single-column key must still be a UUID
+	table.PrimaryKey = []string{"seq"}
+	if err := g.validateTablePrimaryKey(table); err == nil || !strings.Contains(err.Error(), "must be UUID type") {
+		t.Errorf("expected UUID primary key error, got %v", err)
+	}
+
+	// A sequence-generated key points at paginate: false, which accepts it as an integer key
+	table.Columns[1].IsAutoGenerated = true
+	if err := g.validateTablePrimaryKey(table); err == nil || !strings.Contains(err.Error(), "Set paginate: false on table memberships") {
+		t.Errorf("expected a paginate: false hint for a generated integer key, got %v", err)
+	}
+
+	// Either key is accepted once the table opts out of the cursor pagination that relies on a UUID
+	paginate := false
+	g.config.TableConfigs = map[string]TableConfig{"memberships": {Paginate: &paginate}}
+	if err := g.validateTablePrimaryKey(table); err != nil {
+		t.Errorf("non-UUID primary key should be accepted with pagination disabled, got %v", err)
+	}
+}
+
+func TestGenerator_NewWithDB(t *testing.T) {
+	ctx := context.Background()
+
+	// A queries file without annotations exercises the pipeline without touching the database
+	queriesDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(queriesDir, "empty.sql"), []byte("-- no queries yet\n"), 0644); err != nil {
+		t.Fatalf("Failed to write queries file: %v", err)
+	}
+	config := &Config{
+		Schema:      "public",
+		OutputDir:   t.TempDir(),
+		PackageName: "testgen",
+		QueriesDir:  queriesDir,
+	}
+
+	db := pgxkit.NewDB()
+	g := NewWithDB(config, db)
+	g.connectDB = func(ctx context.Context, dsn string) (*pgxkit.DB, error) {
+		t.Fatalf("Generator connected to %q despite an injected database", dsn)
+		return nil, nil
+	}
+
+	// No DSN is needed when the connection is injected
+	if err := g.Generate(ctx); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if g.db != db || g.analysisDB != db {
+		t.Error("Expected the injected database to be used for introspection and analysis")
+	}
+
+	// The standalone generator still requires a DSN
+	t.Setenv("TEST_DATABASE_URL", "")
+	t.Setenv("DATABASE_URL", "")
+	for _, v := range postgresEnvVars {
+		t.Setenv(v.name, "")
+	}
+	if err := New(config).Generate(ctx); err == nil || !strings.Contains(err.Error(), "DSN") {
+		t.Errorf("Expected missing DSN error without an injected database, got %v", err)
+	}
+}
+
+func TestGenerator_NewWithDB_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	db := getTestDB(t)
+	defer db.Shutdown(context.Background())
+
+	ctx := context.Background()
+	config := &Config{
+		Schema:      "public",
+		OutputDir:   t.TempDir(),
+		PackageName: "testgen",
+		Tables:      true,
+		Include:     []string{"users"},
+	}
+
+	g := NewWithDB(config, db)
+	g.connectDB = func(ctx context.Context, dsn string) (*pgxkit.DB, error) {
+		t.Fatalf("Generator connected to %q despite an injected database", dsn)
+		return nil, nil
+	}
+	if err := g.Generate(ctx); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(config.OutputDir, "users_generated.go")); err != nil {
+		t.Errorf("Expected users repository to be generated: %v", err)
+	}
+
+	// The caller's connection must still be open after generation
+	var one int
+	if err := db.QueryRow(ctx, "SELECT 1").Scan(&one); err != nil {
+		t.Errorf("Injected database was shut down by the generator: %v", err)
+	}
+}
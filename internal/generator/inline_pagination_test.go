@@ -2,6 +2,8 @@ package generator
 
 import (
 	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -58,10 +60,10 @@ func TestInlinePagination_TemplateGeneration(t *testing.T) {
 	}
 
 	expectedListComponents := []string{
-		"func (r *UsersRepository) ListPaginated(ctx context.Context, params PaginationParams) (*PaginationResult[Users], error)",
+		"func (r *UsersRepository) ListPaginated(ctx context.Context, params PaginationParams) (*Page[Users], error)",
 		"validatePaginationParams(params)",
 		"decodeCursor(params.Cursor)",
-		"encodeCursor(lastItem.GetID())",
+		"encodeCursor(nextCursorID)",
 		"WHERE ($1::uuid IS NULL OR id > $1)",
 		"ORDER BY id ASC",
 		"LIMIT $2",
@@ -139,6 +141,33 @@ func TestInlinePagination_CursorLogic(t *testing.T) {
 			t.Errorf("Missing parameter validation component: %s", component)
 		}
 	}
+
+	// The total count is opt-in
+	for _, component := range []string{
+		"IncludeTotal bool `json:\"include_total,omitempty\"`",
+		"Total *int `json:\"total,omitempty\"`",
+	} {
+		if !strings.Contains(paginationTypes, component) {
+			t.Errorf("Missing total count component: %s", component)
+		}
+	}
+
+	table := getTestTable()
+	table.Columns = append(table.Columns, Column{Name: "deleted_at", Type: "timestamptz", GoType: "pgtype.Timestamptz", IsNullable: true})
+	config.TableConfigs = map[string]TableConfig{"users": {Functions: []string{"paginate"}, SoftDeleteColumn: "deleted_at"}}
+	code, err := cg.generateCRUDOperations(mapColumns(t, cg, table))
+	if err != nil {
+		t.Fatalf("generateCRUDOperations failed: %v", err)
+	}
+	for _, component := range []string{
+		"if params.IncludeTotal {",
+		"countQuery := `SELECT COUNT(*) FROM users WHERE deleted_at IS NULL`",
+		"Total:      total,",
+	} {
+		if !strings.Contains(code, component) {
+			t.Errorf("ListPaginated missing total count component: %s\n%s", component, code)
+		}
+	}
 }
 
 func TestInlinePagination_GetIDMethod(t *testing.T) {
@@ -168,3 +197,69 @@ func TestInlinePagination_GetIDMethod(t *testing.T) {
 		t.Error("GetID method should not use pointer receiver")
 	}
 }
+
+func TestInlinePagination_CursorForRoundTrip(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compile test in short mode")
+	}
+
+	tempDir := t.TempDir()
+	config := &Config{
+		OutputDir:   tempDir,
+		PackageName: "testgen",
+	}
+	cg := NewCodeGenerator(config)
+
+	if err := cg.GenerateSharedPaginationTypes(); err != nil {
+		t.Fatalf("GenerateSharedPaginationTypes failed: %v", err)
+	}
+	paginationContent, err := os.ReadFile(cg.config.GetOutputPath("pagination.go"))
+	if err != nil {
+		t.Fatalf("Failed to read pagination file: %v", err)
+	}
+	if !strings.Contains(string(paginationContent), "func CursorFor[T HasIDInterface](entity T) string") {
+		t.Fatal("Pagination types missing CursorFor")
+	}
+	if !compileGeneratedCode(t, tempDir) {
+		return
+	}
+
+	// CursorFor must produce the same cursor ListPaginated hands out for the entity
+	testContent := `package testgen
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+type item struct{ id uuid.UUID }
+
+func (i item) GetID() uuid.UUID { return i.id }
+
+func TestCursorFor(t *testing.T) {
+	id := uuid.New()
+	cursor := CursorFor(item{id: id})
+	if cursor != encodeCursor(id) {
+		t.Errorf("CursorFor = %q, want %q", cursor, encodeCursor(id))
+	}
+	decoded, err := decodeCursor(cursor)
+	if err != nil {
+		t.Fatalf("decodeCursor failed: %v", err)
+	}
+	if decoded != id {
+		t.Errorf("decodeCursor(CursorFor(item)) = %v, want %v", decoded, id)
+	}
+}
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "cursor_test.go"), []byte(testContent), 0644); err != nil {
+		t.Fatalf("Failed to write generated package test: %v", err)
+	}
+
+	testCmd := exec.Command("go", "test", "./...")
+	testCmd.Dir = tempDir
+	testCmd.Env = append(os.Environ(), "GO111MODULE=on")
+	if output, err := testCmd.CombinedOutput(); err != nil {
+		t.Errorf("Generated CursorFor test failed: %v\nOutput: %s", err, string(output))
+	}
+}
@@ -3,6 +3,7 @@ package generator
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/nhalm/pgxkit"
@@ -43,13 +44,18 @@ func (i *Introspector) GetTables(ctx context.Context) ([]Table, error) {
 }
 
 // getTableNames retrieves all table names in the schema
+// Partitioned parent tables are included while their child partitions are excluded,
+// so generation targets the logical table rather than each partition
 func (i *Introspector) getTableNames(ctx context.Context) ([]string, error) {
 	query := `
-		SELECT table_name 
-		FROM information_schema.tables 
-		WHERE table_schema = $1 
-		  AND table_type = 'BASE TABLE'
-		ORDER BY table_name
+		SELECT c.relname
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_partitioned_table pt ON pt.partrelid = c.oid
+		WHERE n.nspname = $1
+		  AND (c.relkind = 'r' OR pt.partrelid IS NOT NULL)
+		  AND NOT c.relispartition
+		ORDER BY c.relname
 	`
 
 	rows, err := i.db.Query(ctx, query, i.schema)
@@ -98,10 +104,35 @@ func (i *Introspector) getTableDetails(ctx context.Context, tableName string) (T
 	}
 	table.Indexes = indexes
 
+	// Get UNIQUE constraints
+	uniqueConstraints, err := i.getTableUniqueConstraints(ctx, tableName)
+	if err != nil {
+		return table, fmt.Errorf("failed to get unique constraints: %w", err)
+	}
+	table.UniqueConstraints = uniqueConstraints
+
+	// Get CHECK constraints
+	checks, err := i.getTableChecks(ctx, tableName)
+	if err != nil {
+		return table, fmt.Errorf("failed to get check constraints: %w", err)
+	}
+	table.Checks = checks
+
+	// Get FOREIGN KEY constraints
+	foreignKeys, err := i.getTableForeignKeys(ctx, tableName)
+	if err != nil {
+		return table, fmt.Errorf("failed to get foreign keys: %w", err)
+	}
+	table.ForeignKeys = foreignKeys
+
 	return table, nil
 }
 
 // getTableColumns retrieves all columns for a table
+// User-defined types (enums, extension types and their arrays) are reported schema-qualified,
+// e.g. "billing.currency", since they may live outside the introspected schema; the ltree and
+// hstore extension types keep their bare names, whichever schema the extension was installed into.
+// Enum columns and enum arrays also get the enum's labels, read from pg_enum
 func (i *Introspector) getTableColumns(ctx context.Context, tableName string) ([]Column, error) {
 	query := `
 		SELECT 
@@ -109,14 +140,21 @@ func (i *Introspector) getTableColumns(ctx context.Context, tableName string) ([
 			data_type,
 			is_nullable,
 			column_default,
+			is_identity = 'YES' as is_identity,
 			character_maximum_length,
 			CASE 
 				WHEN data_type = 'ARRAY' THEN true 
 				ELSE false 
 			END as is_array,
 			CASE 
+				WHEN udt_name IN ('ltree', '_ltree', 'hstore', '_hstore') THEN
+					LTRIM(udt_name, '_')
+				WHEN data_type = 'ARRAY' AND udt_schema <> 'pg_catalog' THEN
+					udt_schema || '.' || SUBSTRING(udt_name FROM 2)
 				WHEN data_type = 'ARRAY' THEN 
 					REPLACE(REPLACE(udt_name, '_', ''), 'varchar', 'text')
+				WHEN data_type = 'USER-DEFINED' AND udt_schema <> 'pg_catalog' THEN
+					udt_schema || '.' || udt_name
 				ELSE 
 					CASE 
 						WHEN data_type = 'character varying' THEN 'varchar'
@@ -124,7 +162,13 @@ func (i *Introspector) getTableColumns(ctx context.Context, tableName string) ([
 						WHEN data_type = 'timestamp with time zone' THEN 'timestamptz'
 						ELSE data_type
 					END
-			END as normalized_type
+			END as normalized_type,
+			(
+				SELECT array_agg(e.enumlabel::text ORDER BY e.enumsortorder)
+				FROM pg_type t
+				JOIN pg_enum e ON e.enumtypid = CASE WHEN t.typelem <> 0 THEN t.typelem ELSE t.oid END
+				WHERE t.oid = format('%I.%I', udt_schema, udt_name)::regtype
+			) as enum_values
 		FROM information_schema.columns
 		WHERE table_schema = $1 AND table_name = $2
 		ORDER BY ordinal_position
@@ -141,6 +185,7 @@ func (i *Introspector) getTableColumns(ctx context.Context, tableName string) ([
 		var col Column
 		var isNullable string
 		var defaultValue *string
+		var isIdentity bool
 		var maxLength *int
 
 		err := rows.Scan(
@@ -148,9 +193,11 @@ func (i *Introspector) getTableColumns(ctx context.Context, tableName string) ([
 			&col.Type,
 			&isNullable,
 			&defaultValue,
+			&isIdentity,
 			&maxLength,
 			&col.IsArray,
 			&col.Type, // This overwrites the original data_type with normalized_type
+			&col.EnumValues,
 		)
 		if err != nil {
 			return nil, err
@@ -160,9 +207,14 @@ func (i *Introspector) getTableColumns(ctx context.Context, tableName string) ([
 		if defaultValue != nil {
 			col.DefaultValue = *defaultValue
 		}
+		// Identity columns have no column default, yet draw from a sequence just like serial ones
+		col.IsAutoGenerated = isIdentity || isSequenceDefault(col.DefaultValue)
 		if maxLength != nil {
 			col.MaxLength = *maxLength
 		}
+		if len(col.EnumValues) > 0 {
+			col.EnumName = col.Type[strings.LastIndex(col.Type, ".")+1:]
+		}
 
 		columns = append(columns, col)
 	}
@@ -170,6 +222,11 @@ func (i *Introspector) getTableColumns(ctx context.Context, tableName string) ([
 	return columns, rows.Err()
 }
 
+// isSequenceDefault reports whether a column default is a nextval() call, as created for serial columns
+func isSequenceDefault(defaultValue string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(defaultValue)), "nextval(")
+}
+
 // getTablePrimaryKey retrieves the primary key columns for a table
 func (i *Introspector) getTablePrimaryKey(ctx context.Context, tableName string) ([]string, error) {
 	query := `
@@ -202,6 +259,95 @@ func (i *Introspector) getTablePrimaryKey(ctx context.Context, tableName string)
 	return primaryKey, rows.Err()
 }
 
+// getTableUniqueConstraints retrieves the UNIQUE constraints for a table with their columns in constraint order
+func (i *Introspector) getTableUniqueConstraints(ctx context.Context, tableName string) ([]UniqueConstraint, error) {
+	query := `
+		SELECT tc.constraint_name, kcu.column_name
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu
+			ON tc.constraint_name = kcu.constraint_name
+			AND tc.table_schema = kcu.table_schema
+			AND tc.table_name = kcu.table_name
+		WHERE tc.table_schema = $1
+		  AND tc.table_name = $2
+		  AND tc.constraint_type = 'UNIQUE'
+		ORDER BY tc.constraint_name, kcu.ordinal_position
+	`
+
+	rows, err := i.db.Query(ctx, query, i.schema, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var constraints []UniqueConstraint
+	for rows.Next() {
+		var constraintName, columnName string
+		if err := rows.Scan(&constraintName, &columnName); err != nil {
+			return nil, err
+		}
+		if n := len(constraints); n > 0 && constraints[n-1].Name == constraintName {
+			constraints[n-1].Columns = append(constraints[n-1].Columns, columnName)
+			continue
+		}
+		constraints = append(constraints, UniqueConstraint{Name: constraintName, Columns: []string{columnName}})
+	}
+
+	return constraints, rows.Err()
+}
+
+// getTableForeignKeys retrieves the FOREIGN KEY constraints for a table that reference a table in the
+// same schema, pairing each column with the referenced column at the same position in the constraint
+func (i *Introspector) getTableForeignKeys(ctx context.Context, tableName string) ([]ForeignKey, error) {
+	query := `
+		SELECT tc.constraint_name, kcu.column_name, ref.table_name, ref.column_name
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu
+			ON tc.constraint_schema = kcu.constraint_schema
+			AND tc.constraint_name = kcu.constraint_name
+			AND tc.table_name = kcu.table_name
+		JOIN information_schema.referential_constraints rc
+			ON tc.constraint_schema = rc.constraint_schema
+			AND tc.constraint_name = rc.constraint_name
+		JOIN information_schema.key_column_usage ref
+			ON rc.unique_constraint_schema = ref.constraint_schema
+			AND rc.unique_constraint_name = ref.constraint_name
+			AND kcu.position_in_unique_constraint = ref.ordinal_position
+		WHERE tc.table_schema = $1
+		  AND tc.table_name = $2
+		  AND tc.constraint_type = 'FOREIGN KEY'
+		  AND ref.table_schema = $1
+		ORDER BY tc.constraint_name, kcu.ordinal_position
+	`
+
+	rows, err := i.db.Query(ctx, query, i.schema, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var foreignKeys []ForeignKey
+	for rows.Next() {
+		var constraintName, columnName, refTable, refColumn string
+		if err := rows.Scan(&constraintName, &columnName, &refTable, &refColumn); err != nil {
+			return nil, err
+		}
+		if n := len(foreignKeys); n > 0 && foreignKeys[n-1].Name == constraintName {
+			foreignKeys[n-1].Columns = append(foreignKeys[n-1].Columns, columnName)
+			foreignKeys[n-1].RefColumns = append(foreignKeys[n-1].RefColumns, refColumn)
+			continue
+		}
+		foreignKeys = append(foreignKeys, ForeignKey{
+			Name:       constraintName,
+			Columns:    []string{columnName},
+			RefTable:   refTable,
+			RefColumns: []string{refColumn},
+		})
+	}
+
+	return foreignKeys, rows.Err()
+}
+
 // getTableIndexes retrieves all indexes for a table
 func (i *Introspector) getTableIndexes(ctx context.Context, tableName string) ([]Index, error) {
 	query := `
@@ -288,3 +434,222 @@ func (i *Introspector) parseIndexColumns(indexDef string) []string {
 
 	return columns
 }
+
+// getTableChecks retrieves the CHECK constraints for a table that are simple enough to mirror
+// in generated code; constraints parseCheckConstraint cannot fully understand are skipped
+func (i *Introspector) getTableChecks(ctx context.Context, tableName string) ([]CheckConstraint, error) {
+	query := `
+		SELECT con.conname, pg_get_constraintdef(con.oid)
+		FROM pg_constraint con
+		JOIN pg_class c ON c.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1
+		  AND c.relname = $2
+		  AND con.contype = 'c'
+		ORDER BY con.conname
+	`
+
+	rows, err := i.db.Query(ctx, query, i.schema, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var checks []CheckConstraint
+	for rows.Next() {
+		var name, definition string
+		if err := rows.Scan(&name, &definition); err != nil {
+			return nil, err
+		}
+		checks = append(checks, parseCheckConstraint(name, definition)...)
+	}
+
+	return checks, rows.Err()
+}
+
+var (
+	checkCastRegex       = regexp.MustCompile(`::\s*(character varying|double precision|(?:timestamp|time) with(?:out)? time zone|"?\w+"?)(\(\d+(\s*,\s*\d+)?\))?(\[\])*`)
+	checkInRegex         = regexp.MustCompile(`(?i)^"?(\w+)"?\s+IN\s+(.+)$`)
+	checkAnyRegex        = regexp.MustCompile(`(?i)^"?(\w+)"?\s*=\s*ANY\s*ARRAY\s*\[(.+)\]$`)
+	checkComparisonRegex = regexp.MustCompile(`^"?(\w+)"?\s*(>=|<=|<>|!=|=|>|<)\s*(.+)$`)
+	checkNumberRegex     = regexp.MustCompile(`^-?\d+(\.\d+)?$`)
+)
+
+// parseCheckConstraint parses a CHECK definition into simple per-column conditions
+// Only ANDed IN lists (or the "= ANY (ARRAY[...])" form PostgreSQL normalizes them to) and
+// comparisons of a column against a literal are understood; if any part of the definition is
+// more complex the whole constraint is skipped, so generated validation never rejects a row
+// the database would accept
+func parseCheckConstraint(name, definition string) []CheckConstraint {
+	expr := strings.TrimSpace(definition)
+	expr = strings.TrimSpace(strings.TrimSuffix(expr, "NOT VALID"))
+	if len(expr) < 5 || !strings.EqualFold(expr[:5], "CHECK") {
+		return nil
+	}
+	expr = expr[5:]
+
+	var checks []CheckConstraint
+	for _, part := range splitOutsideQuotes(expr, " AND ") {
+		part = blankOutsideQuotes(stripCasts(part), "()")
+		part = strings.Join(strings.Fields(part), " ")
+
+		check, ok := parseCheckCondition(part)
+		if !ok {
+			return nil
+		}
+		check.Name = name
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+// parseCheckCondition parses a single condition with casts and parentheses already removed
+func parseCheckCondition(condition string) (CheckConstraint, bool) {
+	var column, list string
+	if m := checkAnyRegex.FindStringSubmatch(condition); m != nil {
+		column, list = m[1], m[2]
+	} else if m := checkInRegex.FindStringSubmatch(condition); m != nil {
+		column, list = m[1], m[2]
+	}
+	if column != "" {
+		var values []string
+		for _, item := range splitOutsideQuotes(list, ",") {
+			value, ok := parseCheckLiteral(item)
+			if !ok {
+				return CheckConstraint{}, false
+			}
+			values = append(values, value)
+		}
+		return CheckConstraint{Column: column, Operator: "IN", Values: values}, true
+	}
+
+	m := checkComparisonRegex.FindStringSubmatch(condition)
+	if m == nil {
+		return CheckConstraint{}, false
+	}
+	value, ok := parseCheckLiteral(m[3])
+	if !ok {
+		return CheckConstraint{}, false
+	}
+	operator := m[2]
+	if operator == "!=" {
+		operator = "<>"
+	}
+	return CheckConstraint{Column: m[1], Operator: operator, Values: []string{value}}, true
+}
+
+// parseCheckLiteral returns the value of a numeric or single-quoted string literal
+func parseCheckLiteral(literal string) (string, bool) {
+	literal = strings.TrimSpace(literal)
+	if checkNumberRegex.MatchString(literal) {
+		return literal, true
+	}
+	if len(literal) >= 2 && literal[0] == '\'' && literal[len(literal)-1] == '\'' {
+		inner := literal[1 : len(literal)-1]
+		if strings.Contains(strings.ReplaceAll(inner, "''", ""), "'") {
+			return "", false
+		}
+		return strings.ReplaceAll(inner, "''", "'"), true
+	}
+	return "", false
+}
+
+// stripCasts strips type casts such as ::text or ::character varying[] outside string literals
+func stripCasts(expr string) string {
+	var b strings.Builder
+	for i, segment := range splitQuoted(expr) {
+		if i%2 == 0 {
+			segment = checkCastRegex.ReplaceAllString(segment, "")
+		}
+		b.WriteString(segment)
+	}
+	return b.String()
+}
+
+// blankOutsideQuotes replaces the given characters with spaces everywhere except inside string
+// literals; blanking rather than deleting keeps a function call like length(name) from reading as a column
+func blankOutsideQuotes(expr, chars string) string {
+	var b strings.Builder
+	for i, segment := range splitQuoted(expr) {
+		if i%2 == 0 {
+			segment = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(chars, r) {
+					return ' '
+				}
+				return r
+			}, segment)
+		}
+		b.WriteString(segment)
+	}
+	return b.String()
+}
+
+// splitOutsideQuotes splits expr on a case-insensitive separator that is not inside a string literal
+func splitOutsideQuotes(expr, sep string) []string {
+	var parts []string
+	var current strings.Builder
+	for i, segment := range splitQuoted(expr) {
+		if i%2 == 1 {
+			current.WriteString(segment)
+			continue
+		}
+		for {
+			idx := strings.Index(strings.ToUpper(segment), strings.ToUpper(sep))
+			if idx < 0 {
+				break
+			}
+			current.WriteString(segment[:idx])
+			parts = append(parts, current.String())
+			current.Reset()
+			segment = segment[idx+len(sep):]
+		}
+		current.WriteString(segment)
+	}
+	return append(parts, current.String())
+}
+
+// splitQuoted splits expr into alternating unquoted and single-quoted segments,
+// with quoted segments (at odd indexes) keeping their quotes; ” escapes stay inside a literal
+func splitQuoted(expr string) []string {
+	var segments []string
+	start, inQuote := 0, false
+	for i := 0; i < len(expr); i++ {
+		if expr[i] != '\'' {
+			continue
+		}
+		if inQuote {
+			if i+1 < len(expr) && expr[i+1] == '\'' {
+				i++
+				continue
+			}
+			segments = append(segments, expr[start:i+1])
+			start, inQuote = i+1, false
+		} else {
+			segments = append(segments, expr[start:i])
+			start, inQuote = i, true
+		}
+	}
+	return append(segments, expr[start:])
+}
+
+// schemaHashQuery fingerprints the columns of a set of tables. Generated code embeds the same
+// query to recompute the hash at runtime, so both sides must hash identically; columns are
+// ordered by name so reordering them is not reported as drift.
+const schemaHashQuery = `
+		SELECT md5(COALESCE(string_agg(
+			table_name || '.' || column_name || ' ' || udt_schema || '.' || udt_name || ' ' || is_nullable,
+			',' ORDER BY table_name, column_name
+		), ''))
+		FROM information_schema.columns
+		WHERE table_schema = $1 AND table_name = ANY($2)
+	`
+
+// SchemaHash returns the fingerprint of the given tables' columns
+func (i *Introspector) SchemaHash(ctx context.Context, tableNames []string) (string, error) {
+	var hash string
+	if err := i.db.QueryRow(ctx, schemaHashQuery, i.schema, tableNames).Scan(&hash); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
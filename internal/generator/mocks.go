@@ -0,0 +1,271 @@
+package generator
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/imports"
+)
+
+// mockMethod is the template data for one method of a generated mock
+type mockMethod struct {
+	Name       string
+	Signature  string   // parameters and results, with the generated package's types qualified
+	FuncType   string   // the type of the method's stub field
+	Params     []string // parameter names, recorded with each call
+	CallArgs   string   // arguments passing the parameters on to the stub
+	HasResults bool
+}
+
+// GenerateMockCall generates the Call type shared by every mock in the mocks subpackage
+func (cg *CodeGenerator) GenerateMockCall() error {
+	var code strings.Builder
+
+	// Header
+	code.WriteString("// Code generated by skimatik. DO NOT EDIT.\n")
+	code.WriteString("// This file provides the call record shared by the repository mocks\n\n")
+
+	// Package declaration
+	code.WriteString("package mocks\n\n")
+
+	result, err := cg.templateMgr.ExecuteTemplate(TemplateMockCall, nil)
+	if err != nil {
+		return fmt.Errorf("failed to execute mock call template: %w", err)
+	}
+	code.WriteString(result)
+
+	// Mocks live in their own package, so they never join the single output file
+	filename := filepath.Join(cg.config.GetMocksDir(), "mock.go")
+	if err := cg.formatAndWriteFile(filename, code.String()); err != nil {
+		return fmt.Errorf("failed to write mock call file: %w", err)
+	}
+
+	return nil
+}
+
+// GenerateTableMock generates a mock of the table's repository interface into the mocks subpackage
+func (cg *CodeGenerator) GenerateTableMock(table Table) error {
+	if err := cg.mapTableTypes(&table); err != nil {
+		return err
+	}
+
+	importPath, err := cg.outputImportPath()
+	if err != nil {
+		return err
+	}
+
+	// The mock's signatures are read off the repository's methods, formatted so every
+	// package they reference is imported
+	code, err := cg.generateTableCode(table)
+	if err != nil {
+		return fmt.Errorf("failed to generate code: %w", err)
+	}
+	formatted, err := imports.Process("", []byte(code), nil)
+	if err != nil {
+		return fmt.Errorf("failed to format generated code: %w", err)
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", formatted, parser.SkipObjectResolution)
+	if err != nil {
+		return fmt.Errorf("failed to parse generated code: %w", err)
+	}
+
+	pkg := cg.config.GetPackageName()
+	repositoryName := table.GoStructName() + "Repository"
+	var methods []mockMethod
+	for _, fn := range repositoryMethods(file, repositoryName) {
+		method, err := newMockMethod(fset, fn, pkg)
+		if err != nil {
+			return err
+		}
+		methods = append(methods, method)
+	}
+
+	data := struct {
+		Package       string
+		InterfaceName string
+		MockName      string
+		Methods       []mockMethod
+	}{
+		Package:       pkg,
+		InterfaceName: repositoryName + "Interface",
+		MockName:      "Mock" + repositoryName,
+		Methods:       methods,
+	}
+	mockCode, err := cg.templateMgr.ExecuteTemplate(TemplateMockRepository, data)
+	if err != nil {
+		return fmt.Errorf("failed to execute mock template: %w", err)
+	}
+
+	var out strings.Builder
+	out.WriteString("// Code generated by skimatik. DO NOT EDIT.\n")
+	out.WriteString(fmt.Sprintf("// Source: table %s\n\n", table.Name))
+	out.WriteString("package mocks\n\n")
+
+	// The repository file's imports cover the types in the signatures; unused ones are dropped on formatting
+	out.WriteString("import (\n")
+	if path.Base(importPath) == pkg {
+		out.WriteString(fmt.Sprintf("\t%q\n", importPath))
+	} else {
+		out.WriteString(fmt.Sprintf("\t%s %q\n", pkg, importPath))
+	}
+	out.WriteString("\t\"slices\"\n\t\"sync\"\n")
+	for _, spec := range file.Imports {
+		if spec.Name != nil {
+			out.WriteString("\t" + spec.Name.Name + " " + spec.Path.Value + "\n")
+		} else {
+			out.WriteString("\t" + spec.Path.Value + "\n")
+		}
+	}
+	out.WriteString(")\n\n")
+	out.WriteString(mockCode)
+
+	filename := filepath.Join(cg.config.GetMocksDir(), "mock_"+table.Name+"_repository.go")
+	if err := cg.formatAndWriteFile(filename, out.String()); err != nil {
+		return fmt.Errorf("failed to write mock for table %s: %w", table.Name, err)
+	}
+
+	return nil
+}
+
+// newMockMethod describes a repository method for the mock template, qualifying the generated
+// package's types since the mock is declared outside of it
+func newMockMethod(fset *token.FileSet, fn *ast.FuncDecl, pkg string) (mockMethod, error) {
+	method := mockMethod{Name: fn.Name.Name, HasResults: fn.Type.Results != nil && len(fn.Type.Results.List) > 0}
+
+	var callArgs []string
+	for i, field := range fn.Type.Params.List {
+		if len(field.Names) == 0 {
+			field.Names = []*ast.Ident{ast.NewIdent(fmt.Sprintf("arg%d", i))}
+		}
+		_, variadic := field.Type.(*ast.Ellipsis)
+		for _, name := range field.Names {
+			method.Params = append(method.Params, name.Name)
+			if variadic {
+				callArgs = append(callArgs, name.Name+"...")
+			} else {
+				callArgs = append(callArgs, name.Name)
+			}
+		}
+		field.Type = qualifyPackageTypes(field.Type, pkg)
+	}
+	if fn.Type.Results != nil {
+		for _, field := range fn.Type.Results.List {
+			field.Type = qualifyPackageTypes(field.Type, pkg)
+		}
+	}
+	method.CallArgs = strings.Join(callArgs, ", ")
+
+	var funcType strings.Builder
+	if err := printer.Fprint(&funcType, fset, fn.Type); err != nil {
+		return mockMethod{}, fmt.Errorf("failed to print method %s: %w", fn.Name.Name, err)
+	}
+	method.FuncType = funcType.String()
+	method.Signature = strings.TrimPrefix(method.FuncType, "func")
+
+	return method, nil
+}
+
+// qualifyPackageTypes prefixes the types of the generated package within a type expression with
+// the package name. Generated types are exported and predeclared ones are not, so every exported
+// bare identifier belongs to the package
+func qualifyPackageTypes(expr ast.Expr, pkg string) ast.Expr {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if e.IsExported() {
+			return &ast.SelectorExpr{X: ast.NewIdent(pkg), Sel: e}
+		}
+	case *ast.StarExpr:
+		e.X = qualifyPackageTypes(e.X, pkg)
+	case *ast.ArrayType:
+		e.Elt = qualifyPackageTypes(e.Elt, pkg)
+	case *ast.Ellipsis:
+		e.Elt = qualifyPackageTypes(e.Elt, pkg)
+	case *ast.MapType:
+		e.Key = qualifyPackageTypes(e.Key, pkg)
+		e.Value = qualifyPackageTypes(e.Value, pkg)
+	case *ast.ChanType:
+		e.Value = qualifyPackageTypes(e.Value, pkg)
+	case *ast.IndexExpr:
+		e.X = qualifyPackageTypes(e.X, pkg)
+		e.Index = qualifyPackageTypes(e.Index, pkg)
+	case *ast.IndexListExpr:
+		e.X = qualifyPackageTypes(e.X, pkg)
+		for i := range e.Indices {
+			e.Indices[i] = qualifyPackageTypes(e.Indices[i], pkg)
+		}
+	case *ast.FuncType:
+		for _, list := range []*ast.FieldList{e.Params, e.Results} {
+			if list == nil {
+				continue
+			}
+			for _, field := range list.List {
+				field.Type = qualifyPackageTypes(field.Type, pkg)
+			}
+		}
+	}
+	return expr
+}
+
+// outputImportPath returns the import path of the generated package: output.import_path when set,
+// otherwise the output directory's path within the module of the nearest enclosing go.mod
+func (cg *CodeGenerator) outputImportPath() (string, error) {
+	if cg.config.ImportPath != "" {
+		return cg.config.ImportPath, nil
+	}
+
+	outputDir, err := filepath.Abs(cg.config.GetOutputDir())
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve output directory: %w", err)
+	}
+	for dir := outputDir; ; dir = filepath.Dir(dir) {
+		modulePath, err := readModulePath(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			rel, err := filepath.Rel(dir, outputDir)
+			if err != nil {
+				return "", err
+			}
+			return path.Join(modulePath, filepath.ToSlash(rel)), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		if filepath.Dir(dir) == dir {
+			return "", fmt.Errorf("no go.mod found above %s; set output.import_path for the mocks to import the generated package", outputDir)
+		}
+	}
+}
+
+// readModulePath returns the module path declared in a go.mod file
+func readModulePath(goModPath string) (string, error) {
+	f, err := os.Open(goModPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "module" {
+			if modulePath, err := strconv.Unquote(fields[1]); err == nil {
+				return modulePath, nil
+			}
+			return fields[1], nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", goModPath, err)
+	}
+	return "", fmt.Errorf("%s declares no module path", goModPath)
+}
@@ -0,0 +1,170 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WriteTableSQL writes the SQL each generated method of a table runs, without writing any Go files
+func (cg *CodeGenerator) WriteTableSQL(w io.Writer, table Table) error {
+	if err := cg.mapTableTypes(&table); err != nil {
+		return err
+	}
+
+	code, err := cg.generateTableCode(table)
+	if err != nil {
+		return fmt.Errorf("failed to generate code: %w", err)
+	}
+
+	return writeEmbeddedSQL(w, "table "+table.Name, code)
+}
+
+// WriteQuerySQL writes the SQL each generated query method runs, without writing any Go files
+func (cg *CodeGenerator) WriteQuerySQL(w io.Writer, queries []Query) error {
+	queryGroups := cg.groupQueriesByFile(queries)
+
+	// Print files in a stable order
+	var sourceFiles []string
+	for sourceFile := range queryGroups {
+		sourceFiles = append(sourceFiles, sourceFile)
+	}
+	sort.Strings(sourceFiles)
+
+	for _, sourceFile := range sourceFiles {
+		fileQueries := queryGroups[sourceFile]
+		for i := range fileQueries {
+			if err := cg.typeMapper.MapQueryColumns(&fileQueries[i]); err != nil {
+				return fmt.Errorf("failed to map column types for query %s: %w", fileQueries[i].Name, err)
+			}
+		}
+
+		code, err := cg.generateQueryCode(sourceFile, fileQueries)
+		if err != nil {
+			return fmt.Errorf("failed to generate query code: %w", err)
+		}
+
+		if err := writeEmbeddedSQL(w, "queries "+sourceFile, code); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeEmbeddedSQL prints the SQL string assigned to each query variable in the generated code,
+// labelled with its method. Parts of dynamically built statements are shown as <expr>
+func writeEmbeddedSQL(w io.Writer, source, code string) error {
+	file, err := parser.ParseFile(token.NewFileSet(), "", code, 0)
+	if err != nil {
+		return fmt.Errorf("failed to parse generated code for %s: %w", source, err)
+	}
+
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+
+		name := fn.Name.Name
+		if fn.Recv != nil && len(fn.Recv.List) == 1 {
+			receiver := fn.Recv.List[0].Type
+			if star, ok := receiver.(*ast.StarExpr); ok {
+				receiver = star.X
+			}
+			if ident, ok := receiver.(*ast.Ident); ok {
+				name = ident.Name + "." + name
+			}
+		}
+
+		var werr error
+		ast.Inspect(fn.Body, func(node ast.Node) bool {
+			assign, ok := node.(*ast.AssignStmt)
+			if !ok || len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+				return true
+			}
+			ident, ok := assign.Lhs[0].(*ast.Ident)
+			if !ok || !strings.HasSuffix(strings.ToLower(ident.Name), "query") {
+				return true
+			}
+			sql, ok := sqlText(assign.Rhs[0])
+			if !ok {
+				return true
+			}
+			if werr == nil {
+				_, werr = fmt.Fprintf(w, "-- %s: %s\n%s\n\n", source, name, dedent(sql))
+			}
+			return true
+		})
+		if werr != nil {
+			return werr
+		}
+	}
+	return nil
+}
+
+// sqlText returns the text of a string literal or concatenation, reporting false when the
+// expression holds no string literal and so isn't SQL
+func sqlText(expr ast.Expr) (string, bool) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.STRING {
+			return "", false
+		}
+		text, err := strconv.Unquote(e.Value)
+		return text, err == nil
+	case *ast.BinaryExpr:
+		if e.Op != token.ADD {
+			return "", false
+		}
+		left, leftOK := sqlText(e.X)
+		right, rightOK := sqlText(e.Y)
+		if !leftOK && !rightOK {
+			return "", false
+		}
+		if !leftOK {
+			left = "<expr>"
+		}
+		if !rightOK {
+			right = "<expr>"
+		}
+		return left + right, true
+	case *ast.ParenExpr:
+		return sqlText(e.X)
+	}
+	return "", false
+}
+
+// dedent trims surrounding blank lines and the indentation shared by all lines of a SQL string
+func dedent(sql string) string {
+	lines := strings.Split(sql, "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		width := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || width < indent {
+			indent = width
+		}
+	}
+
+	for i, line := range lines {
+		if len(line) >= indent && indent > 0 {
+			lines[i] = line[indent:]
+		}
+		lines[i] = strings.TrimRight(lines[i], " \t")
+	}
+	return strings.Join(lines, "\n")
+}
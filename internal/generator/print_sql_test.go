@@ -0,0 +1,90 @@
+package generator
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCodeGenerator_WriteTableSQL(t *testing.T) {
+	config := getTestConfigWithTempDir(t)
+	config.TableConfigs = map[string]TableConfig{
+		"users": {Functions: []string{"create", "get", "update", "delete", "list"}},
+	}
+	cg := NewCodeGenerator(config)
+
+	var out bytes.Buffer
+	if err := cg.WriteTableSQL(&out, getTestTable()); err != nil {
+		t.Fatalf("WriteTableSQL failed: %v", err)
+	}
+
+	expected := []string{
+		"-- table users: UsersRepository.Create\nINSERT INTO users (name, email, metadata)\nVALUES ($1, $2, $3)\nRETURNING id, name, email, is_active, created_at, metadata\n",
+		"-- table users: UsersRepository.Get\nSELECT id, name, email, is_active, created_at, metadata\nFROM users\nWHERE id = $1\n",
+		"-- table users: UsersRepository.Update\nUPDATE users\n",
+		"-- table users: UsersRepository.Delete\nDELETE FROM users WHERE id = $1\n",
+		"-- table users: UsersRepository.List\nSELECT id, name, email, is_active, created_at, metadata\nFROM users\nORDER BY id ASC\n",
+	}
+	for _, want := range expected {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("printed SQL missing %q\n%s", want, out.String())
+		}
+	}
+
+	// Nothing is written to the output directory
+	if entries, err := os.ReadDir(config.OutputDir); err == nil && len(entries) != 0 {
+		t.Errorf("WriteTableSQL wrote %d files", len(entries))
+	}
+}
+
+func TestWriteEmbeddedSQL_DynamicParts(t *testing.T) {
+	code := "package p\n\nfunc f() {\n\tquery := `SELECT 1 FROM t WHERE ` + strings.Join(conds, \" AND \") + ` LIMIT 1`\n}\n"
+
+	var out bytes.Buffer
+	if err := writeEmbeddedSQL(&out, "test", code); err != nil {
+		t.Fatalf("writeEmbeddedSQL failed: %v", err)
+	}
+	if want := "-- test: f\nSELECT 1 FROM t WHERE <expr> LIMIT 1\n"; out.String() != want+"\n" {
+		t.Errorf("writeEmbeddedSQL = %q, want %q", out.String(), want)
+	}
+}
+
+func TestCodeGenerator_WriteQuerySQL_SortsFiles(t *testing.T) {
+	cg := NewCodeGenerator(getTestConfigWithTempDir(t))
+
+	var queries []Query
+	for _, name := range []string{"users", "accounts", "posts", "events"} {
+		queries = append(queries, Query{
+			Name:       "Touch" + toPascalCase(name),
+			SQL:        "UPDATE " + name + " SET touched = true",
+			Type:       QueryTypeExec,
+			SourceFile: "queries/" + name + ".sql",
+		})
+	}
+
+	// Map iteration would vary the order from run to run
+	var first string
+	for i := 0; i < 10; i++ {
+		var out bytes.Buffer
+		if err := cg.WriteQuerySQL(&out, queries); err != nil {
+			t.Fatalf("WriteQuerySQL failed: %v", err)
+		}
+		if i == 0 {
+			first = out.String()
+			continue
+		}
+		if out.String() != first {
+			t.Fatalf("WriteQuerySQL output changed between runs:\n%s\n---\n%s", first, out.String())
+		}
+	}
+
+	previous := -1
+	for _, file := range []string{"queries/accounts.sql", "queries/events.sql", "queries/posts.sql", "queries/users.sql"} {
+		index := strings.Index(first, "-- queries "+file+":")
+		if index <= previous {
+			t.Errorf("Expected %s after the previous file\n%s", file, first)
+		}
+		previous = index
+	}
+}
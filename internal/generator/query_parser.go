@@ -184,10 +184,14 @@ func (qp *QueryParser) parseQueryType(typeStr string) (QueryType, error) {
 		return QueryTypeMany, nil
 	case "exec":
 		return QueryTypeExec, nil
+	case "execrows":
+		return QueryTypeExecRows, nil
+	case "batch":
+		return QueryTypeBatch, nil
 	case "paginated":
 		return QueryTypePaginated, nil
 	default:
-		return "", fmt.Errorf("invalid query type: %s (supported: one, many, exec, paginated)", typeStr)
+		return "", fmt.Errorf("invalid query type: %s (supported: one, many, exec, execrows, batch, paginated)", typeStr)
 	}
 }
 
@@ -215,7 +219,16 @@ func (qp *QueryParser) ValidateQuery(query Query) error {
 
 	// Check query type matches SQL statement
 	switch query.Type {
-	case QueryTypeOne, QueryTypeMany, QueryTypePaginated:
+	case QueryTypeOne, QueryTypeMany:
+		// Allow SELECT statements, CTEs (Common Table Expressions) and statements returning rows with RETURNING
+		if !strings.HasPrefix(sqlLower, "select") && !strings.HasPrefix(sqlLower, "with") && !hasReturningClause(query.SQL) {
+			sqlSnippet := query.SQL
+			if len(sqlSnippet) > 50 {
+				sqlSnippet = sqlSnippet[:50] + "..."
+			}
+			return fmt.Errorf("query type %s requires SELECT statement, CTE or RETURNING clause, got: %s", query.Type, sqlSnippet)
+		}
+	case QueryTypePaginated:
 		// Allow SELECT statements and CTEs (Common Table Expressions)
 		if !strings.HasPrefix(sqlLower, "select") && !strings.HasPrefix(sqlLower, "with") {
 			sqlSnippet := query.SQL
@@ -224,7 +237,7 @@ func (qp *QueryParser) ValidateQuery(query Query) error {
 			}
 			return fmt.Errorf("query type %s requires SELECT statement or CTE, got: %s", query.Type, sqlSnippet)
 		}
-	case QueryTypeExec:
+	case QueryTypeExec, QueryTypeExecRows, QueryTypeBatch:
 		// Exec queries should not be SELECT or CTE
 		if strings.HasPrefix(sqlLower, "select") || strings.HasPrefix(sqlLower, "with") {
 			sqlSnippet := query.SQL
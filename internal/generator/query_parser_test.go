@@ -37,6 +37,16 @@ func TestQueryParser_ParseAnnotation(t *testing.T) {
 			line:     "-- name: CreateUser :exec",
 			expected: &QueryAnnotation{Name: "CreateUser", Type: QueryTypeExec},
 		},
+		{
+			name:     "execrows type",
+			line:     "-- name: DeactivateUsers :execrows",
+			expected: &QueryAnnotation{Name: "DeactivateUsers", Type: QueryTypeExecRows},
+		},
+		{
+			name:     "batch type",
+			line:     "-- name: InsertEvent :batch",
+			expected: &QueryAnnotation{Name: "InsertEvent", Type: QueryTypeBatch},
+		},
 		{
 			name:     "paginated type",
 			line:     "-- name: GetUsersPaginated :paginated",
@@ -108,6 +118,8 @@ func TestQueryParser_ParseQueryType(t *testing.T) {
 		{"one", "one", QueryTypeOne, false},
 		{"many", "many", QueryTypeMany, false},
 		{"exec", "exec", QueryTypeExec, false},
+		{"execrows", "execrows", QueryTypeExecRows, false},
+		{"batch", "batch", QueryTypeBatch, false},
 		{"paginated", "paginated", QueryTypePaginated, false},
 		{"ONE uppercase", "ONE", QueryTypeOne, false},
 		{"Many mixed case", "Many", QueryTypeMany, false},
@@ -173,6 +185,15 @@ func TestQueryParser_ValidateQuery(t *testing.T) {
 			},
 			hasError: false,
 		},
+		{
+			name: "valid execrows query",
+			query: Query{
+				Name: "DeactivateUsers",
+				Type: QueryTypeExecRows,
+				SQL:  "UPDATE users SET is_active = false WHERE last_login < $1",
+			},
+			hasError: false,
+		},
 		{
 			name: "valid paginated query",
 			query: Query{
@@ -245,6 +266,33 @@ func TestQueryParser_ValidateQuery(t *testing.T) {
 			},
 			hasError: true,
 		},
+		{
+			name: "valid batch query",
+			query: Query{
+				Name: "InsertEvent",
+				Type: QueryTypeBatch,
+				SQL:  "INSERT INTO events (kind) VALUES ($1)",
+			},
+			hasError: false,
+		},
+		{
+			name: "select with batch type",
+			query: Query{
+				Name: "GetUser",
+				Type: QueryTypeBatch,
+				SQL:  "SELECT id FROM users WHERE id = $1",
+			},
+			hasError: true,
+		},
+		{
+			name: "select with execrows type",
+			query: Query{
+				Name: "CountUsers",
+				Type: QueryTypeExecRows,
+				SQL:  "SELECT count(*) FROM users",
+			},
+			hasError: true,
+		},
 		{
 			name: "insert with one type",
 			query: Query{
@@ -254,6 +302,42 @@ func TestQueryParser_ValidateQuery(t *testing.T) {
 			},
 			hasError: true,
 		},
+		{
+			name: "insert returning with one type",
+			query: Query{
+				Name: "CreateUser",
+				Type: QueryTypeOne,
+				SQL:  "INSERT INTO users (name) VALUES ($1) RETURNING id",
+			},
+			hasError: false,
+		},
+		{
+			name: "update returning with many type",
+			query: Query{
+				Name: "DeactivateUsers",
+				Type: QueryTypeMany,
+				SQL:  "UPDATE users SET is_active = false WHERE last_login < $1 RETURNING id",
+			},
+			hasError: false,
+		},
+		{
+			name: "update returning with paginated type",
+			query: Query{
+				Name: "DeactivateUsers",
+				Type: QueryTypePaginated,
+				SQL:  "UPDATE users SET is_active = false WHERE last_login < $1 RETURNING id",
+			},
+			hasError: true,
+		},
+		{
+			name: "delete returning with exec type",
+			query: Query{
+				Name: "DeleteInactiveUsers",
+				Type: QueryTypeExec,
+				SQL:  "DELETE FROM users WHERE is_active = false RETURNING id",
+			},
+			hasError: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -11,13 +11,15 @@ import (
 type TemplateManager struct {
 	templates map[string]*template.Template
 	fs        embed.FS
+	funcs     template.FuncMap
 }
 
-// NewTemplateManager creates a new template manager
-func NewTemplateManager(fs embed.FS) *TemplateManager {
+// NewTemplateManager creates a new template manager whose templates may call funcs
+func NewTemplateManager(fs embed.FS, funcs template.FuncMap) *TemplateManager {
 	return &TemplateManager{
 		templates: make(map[string]*template.Template),
 		fs:        fs,
+		funcs:     funcs,
 	}
 }
 
@@ -35,7 +37,7 @@ func (tm *TemplateManager) LoadTemplate(name string) (*template.Template, error)
 	}
 
 	// Parse template
-	tmpl, err := template.New(name).Parse(string(content))
+	tmpl, err := template.New(name).Funcs(tm.funcs).Parse(string(content))
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
 	}
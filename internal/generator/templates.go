@@ -4,17 +4,55 @@ import "embed"
 
 // Embed all template files at build time
 //
-//go:embed templates/crud/* templates/pagination/* templates/repository/* templates/queries/* templates/shared/* templates/tests/*
+//go:embed templates/crud/* templates/pagination/* templates/repository/* templates/queries/* templates/shared/* templates/tests/* templates/mocks/*
 var templateFS embed.FS
 
 // Template file paths (constants for type safety)
 const (
 	// CRUD templates
-	TemplateGetByID = "templates/crud/get_by_id.tmpl"
-	TemplateCreate  = "templates/crud/create.tmpl"
-	TemplateUpdate  = "templates/crud/update.tmpl"
-	TemplateDelete  = "templates/crud/delete.tmpl"
-	TemplateList    = "templates/crud/list.tmpl"
+	TemplateGetByID               = "templates/crud/get_by_id.tmpl"
+	TemplateCreate                = "templates/crud/create.tmpl"
+	TemplateUpdate                = "templates/crud/update.tmpl"
+	TemplateDelete                = "templates/crud/delete.tmpl"
+	TemplateList                  = "templates/crud/list.tmpl"
+	TemplateHead                  = "templates/crud/head.tmpl"
+	TemplateGetRandom             = "templates/crud/get_random.tmpl"
+	TemplateListModifiedSince     = "templates/crud/list_modified_since.tmpl"
+	TemplateGetByUniqueKeys       = "templates/crud/get_by_unique_keys.tmpl"
+	TemplateGetByUniqueConstraint = "templates/crud/get_by_unique_constraint.tmpl"
+	TemplateUpdateFromMap         = "templates/crud/update_params_from_map.tmpl"
+	TemplateBulkCreateIDs         = "templates/crud/bulk_create_ids.tmpl"
+	TemplateCreateBatch           = "templates/crud/create_batch.tmpl"
+	TemplateExportCSV             = "templates/crud/export_csv.tmpl"
+	TemplateCreateConflict        = "templates/crud/create_with_conflict.tmpl"
+	TemplateGetWithDeleted        = "templates/crud/get_with_deleted.tmpl"
+	TemplateListWithDeleted       = "templates/crud/list_with_deleted.tmpl"
+	TemplateCreateBuilder         = "templates/crud/create_params_builder.tmpl"
+	TemplateUpsertWithStatus      = "templates/crud/upsert_with_status.tmpl"
+	TemplateUpsert                = "templates/crud/upsert.tmpl"
+	TemplateGetManyOrdered        = "templates/crud/get_many_ordered.tmpl"
+	TemplateListDescendants       = "templates/crud/list_descendants.tmpl"
+	TemplateListAncestors         = "templates/crud/list_ancestors.tmpl"
+	TemplateFetchAndLock          = "templates/crud/fetch_and_lock.tmpl"
+	TemplateCountBy               = "templates/crud/count_by.tmpl"
+	TemplateCountDistinct         = "templates/crud/count_distinct.tmpl"
+	TemplateListBetween           = "templates/crud/list_between.tmpl"
+	TemplateListUpdatedBy         = "templates/crud/list_updated_by.tmpl"
+	TemplateSearchByPrefix        = "templates/crud/search_by_prefix.tmpl"
+	TemplateBulkUpdate            = "templates/crud/bulk_update.tmpl"
+	TemplateStats                 = "templates/crud/stats.tmpl"
+	TemplateGetNeighbors          = "templates/crud/get_neighbors.tmpl"
+	TemplateFilter                = "templates/crud/filter.tmpl"
+	TemplateListFiltered          = "templates/crud/list_filtered.tmpl"
+	TemplateCountFiltered         = "templates/crud/count_filtered.tmpl"
+	TemplateStreamFiltered        = "templates/crud/stream_filtered.tmpl"
+	TemplateDeleteFiltered        = "templates/crud/delete_filtered.tmpl"
+	TemplateCount                 = "templates/crud/count.tmpl"
+	TemplateExists                = "templates/crud/exists.tmpl"
+	TemplateEnsureByUnique        = "templates/crud/ensure_by_unique.tmpl"
+	TemplateDomainMethods         = "templates/crud/domain_methods.tmpl"
+	TemplateRelationships         = "templates/crud/relationships.tmpl"
+	TemplateListWithChildren      = "templates/crud/list_with_children.tmpl"
 
 	// Pagination templates
 	TemplatePaginationShared              = "templates/pagination/shared_types.tmpl"
@@ -22,27 +60,49 @@ const (
 	TemplatePaginationUtils               = "templates/pagination/pagination_utils.tmpl"
 	TemplatePaginationSharedTypes         = "templates/pagination/shared_pagination_types.tmpl"
 	TemplatePaginationSharedListPaginated = "templates/pagination/shared_list_paginated.tmpl"
+	TemplatePaginationStreamPaginated     = "templates/pagination/shared_stream_paginated.tmpl"
+	TemplateListBetweenPaginated          = "templates/pagination/list_between_paginated.tmpl"
 
 	// Query templates
 	TemplateQueryResultStruct = "templates/queries/result_struct.tmpl"
+	TemplateQueryParamsStruct = "templates/queries/params_struct.tmpl"
 	TemplateQueryRepository   = "templates/queries/repository.tmpl"
 	TemplateQueryOne          = "templates/queries/one_query.tmpl"
 	TemplateQueryMany         = "templates/queries/many_query.tmpl"
 	TemplateQueryExec         = "templates/queries/exec_query.tmpl"
+	TemplateQueryExecRows     = "templates/queries/execrows_query.tmpl"
+	TemplateQueryBatch        = "templates/queries/batch_query.tmpl"
 	TemplateQueryPaginated    = "templates/queries/paginated_query.tmpl"
 
 	// Repository templates
-	TemplateRepositoryStruct = "templates/repository/repository_struct.tmpl"
-	TemplateRepositoryRetry  = "templates/repository/retry_methods.tmpl"
+	TemplateRepositoryStruct    = "templates/repository/repository_struct.tmpl"
+	TemplateRepositoryRetry     = "templates/repository/retry_methods.tmpl"
+	TemplateRepositoryInterface = "templates/repository/repository_interface.tmpl"
+
+	// Mock templates
+	TemplateMockCall       = "templates/mocks/call.tmpl"
+	TemplateMockRepository = "templates/mocks/mock_repository.tmpl"
 
 	// Shared templates
 	TemplateStruct             = "templates/shared/struct.tmpl"
+	TemplateClone              = "templates/shared/clone.tmpl"
+	TemplateDiff               = "templates/shared/diff.tmpl"
+	TemplateIntEnum            = "templates/shared/int_enum.tmpl"
 	TemplateHeader             = "templates/shared/header.tmpl"
 	TemplateErrorHandling      = "templates/shared/error_handling.tmpl"
 	TemplateSharedErrors       = "templates/shared/errors.tmpl"
 	TemplateDatabaseOperations = "templates/shared/database_operations.tmpl"
 	TemplateRetryOperations    = "templates/shared/retry_operations.tmpl"
+	TemplateContextHelpers     = "templates/shared/context_helpers.tmpl"
+	TemplateQueryOptions       = "templates/shared/query_options.tmpl"
+	TemplateSchemaHash         = "templates/shared/schema_hash.tmpl"
+	TemplateEnums              = "templates/shared/enums.tmpl"
+	TemplateGeometry           = "templates/shared/geometry.tmpl"
+	TemplateEncryption         = "templates/shared/encryption.tmpl"
+	TemplateDomainMapping      = "templates/shared/domain_mapping.tmpl"
 
 	// Test templates
 	TemplateRepositoryTest = "templates/tests/repository_test.tmpl"
+	TemplateRoundtripTest  = "templates/tests/roundtrip_test.tmpl"
+	TemplateTruncate       = "templates/tests/truncate_for_testing.tmpl"
 )
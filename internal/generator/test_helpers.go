@@ -30,7 +30,7 @@ func getTestTable() Table {
 				Type:         "uuid",
 				GoType:       "uuid.UUID",
 				IsNullable:   false,
-				DefaultValue: "",
+				DefaultValue: "gen_random_uuid()",
 				IsArray:      false,
 			},
 			{
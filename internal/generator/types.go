@@ -1,7 +1,9 @@
 package generator
 
 import (
+	"regexp"
 	"strings"
+	"unicode"
 )
 
 // Table represents a database table with its columns and metadata
@@ -11,6 +13,15 @@ type Table struct {
 	Columns    []Column `json:"columns"`
 	PrimaryKey []string `json:"primary_key"`
 	Indexes    []Index  `json:"indexes"`
+
+	// UniqueConstraints holds the table's UNIQUE constraints, which unlike unique indexes are never partial
+	UniqueConstraints []UniqueConstraint `json:"unique_constraints"`
+
+	// Checks holds the simple CHECK constraints that generated code can enforce client-side
+	Checks []CheckConstraint `json:"checks"`
+
+	// ForeignKeys holds the table's FOREIGN KEY constraints referencing tables in the same schema
+	ForeignKeys []ForeignKey `json:"foreign_keys"`
 }
 
 // Column represents a database column with its type and constraints
@@ -22,6 +33,15 @@ type Column struct {
 	DefaultValue string `json:"default_value"`
 	IsArray      bool   `json:"is_array"`
 	MaxLength    int    `json:"max_length"`
+
+	// IsAutoGenerated marks columns that draw from a sequence, through a nextval() default (serial)
+	// or as an identity column, which the database assigns and callers never supply
+	IsAutoGenerated bool `json:"is_auto_generated"`
+
+	// EnumName and EnumValues describe columns of a PostgreSQL enum type (or an array of one):
+	// the enum's unqualified type name and its labels in declaration order
+	EnumName   string   `json:"enum_name"`
+	EnumValues []string `json:"enum_values"`
 }
 
 // Index represents a database index
@@ -31,6 +51,29 @@ type Index struct {
 	IsUnique bool     `json:"is_unique"`
 }
 
+// UniqueConstraint is a UNIQUE constraint over one or more columns, listed in constraint order
+type UniqueConstraint struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+}
+
+// ForeignKey is a FOREIGN KEY constraint; RefColumns[i] is the column Columns[i] references in RefTable
+type ForeignKey struct {
+	Name       string   `json:"name"`
+	Columns    []string `json:"columns"`
+	RefTable   string   `json:"ref_table"`
+	RefColumns []string `json:"ref_columns"`
+}
+
+// CheckConstraint is one simple condition from a CHECK constraint, e.g. "age >= 0" or
+// "status IN ('draft', 'published')"; a constraint joined with AND yields one entry per condition
+type CheckConstraint struct {
+	Name     string   `json:"name"`     // Constraint name
+	Column   string   `json:"column"`   // Column the condition applies to
+	Operator string   `json:"operator"` // "IN" or a comparison operator (=, <>, <, <=, >, >=)
+	Values   []string `json:"values"`   // Literal operands, with string quotes removed
+}
+
 // Query represents a parsed SQL query with metadata
 type Query struct {
 	Name       string      `json:"name"`
@@ -39,6 +82,14 @@ type Query struct {
 	Parameters []Parameter `json:"parameters"`
 	Columns    []Column    `json:"columns"` // Result columns (for SELECT queries)
 	SourceFile string      `json:"source_file"`
+
+	// ResultStruct names a generated table struct whose columns match the result exactly;
+	// when set, results are scanned into it instead of a dedicated ...Result struct
+	ResultStruct string `json:"result_struct"`
+
+	// NamedParameters records that the query was written with @name or sqlc.arg(name) parameters,
+	// which the analyzer rewrote to $n; the parameters keep those names in the generated code
+	NamedParameters bool `json:"named_parameters"`
 }
 
 // QueryType represents the type of query operation
@@ -47,7 +98,9 @@ type QueryType string
 const (
 	QueryTypeOne       QueryType = "one"       // Returns single row
 	QueryTypeMany      QueryType = "many"      // Returns multiple rows
-	QueryTypeExec      QueryType = "exec"      // Executes without returning rows
+	QueryTypeExec      QueryType = "exec"      // Executes without returning rows, or returns the rows of a RETURNING clause
+	QueryTypeExecRows  QueryType = "execrows"  // Executes and returns the number of rows affected
+	QueryTypeBatch     QueryType = "batch"     // Executes once per params struct, sent together in a pgx.Batch
 	QueryTypePaginated QueryType = "paginated" // Returns paginated results
 )
 
@@ -77,6 +130,17 @@ func (t *Table) GetPrimaryKeyColumn() *Column {
 	return t.GetColumn(t.PrimaryKey[0])
 }
 
+// HasUUIDPrimaryKey reports whether the table's primary key is a single UUID column
+func (t *Table) HasUUIDPrimaryKey() bool {
+	col := t.GetPrimaryKeyColumn()
+	return col != nil && col.IsUUID()
+}
+
+// HasCompositePrimaryKey reports whether the table's primary key spans more than one column
+func (t *Table) HasCompositePrimaryKey() bool {
+	return len(t.PrimaryKey) > 1
+}
+
 // GoStructName returns the Go struct name for this table
 func (t *Table) GoStructName() string {
 	return toPascalCase(t.Name)
@@ -87,6 +151,16 @@ func (t *Table) GoFileName() string {
 	return toSnakeCase(t.Name) + "_generated.go"
 }
 
+// GoTestFileName returns the Go test file name for this table's generated tests
+func (t *Table) GoTestFileName() string {
+	return toSnakeCase(t.Name) + "_generated_test.go"
+}
+
+// GoDomainFileName returns the Go file name for the scaffolded domain conversion of this table
+func (t *Table) GoDomainFileName() string {
+	return toSnakeCase(t.Name) + "_domain.go"
+}
+
 // IsUUID checks if the column is a UUID type
 func (c *Column) IsUUID() bool {
 	return strings.ToLower(c.Type) == "uuid"
@@ -162,9 +236,11 @@ func toPascalCase(s string) string {
 		return ""
 	}
 
-	// If it contains underscores, split on them
-	if strings.Contains(s, "_") {
-		parts := strings.Split(s, "_")
+	// If it contains underscores or other separators (names that must be quoted in SQL
+	// may hold spaces or dashes), split on them
+	isSeparator := func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }
+	if strings.IndexFunc(s, isSeparator) >= 0 {
+		parts := strings.FieldsFunc(s, isSeparator)
 		result := ""
 		for _, part := range parts {
 			if len(part) > 0 {
@@ -182,6 +258,133 @@ func toPascalCase(s string) string {
 	return s
 }
 
+// toCamelCase converts snake_case to camelCase
+func toCamelCase(s string) string {
+	pascal := toPascalCase(s)
+	if pascal == "" {
+		return ""
+	}
+	return strings.ToLower(pascal[:1]) + pascal[1:]
+}
+
+// reservedWords are the PostgreSQL keywords that cannot be used as bare table or column names
+var reservedWords = map[string]bool{
+	"all": true, "analyse": true, "analyze": true, "and": true, "any": true, "array": true,
+	"as": true, "asc": true, "asymmetric": true, "authorization": true, "binary": true,
+	"both": true, "case": true, "cast": true, "check": true, "collate": true, "collation": true,
+	"column": true, "concurrently": true, "constraint": true, "create": true, "cross": true,
+	"current_catalog": true, "current_date": true, "current_role": true, "current_schema": true,
+	"current_time": true, "current_timestamp": true, "current_user": true, "default": true,
+	"deferrable": true, "desc": true, "distinct": true, "do": true, "else": true, "end": true,
+	"except": true, "false": true, "fetch": true, "for": true, "foreign": true, "freeze": true,
+	"from": true, "full": true, "grant": true, "group": true, "having": true, "ilike": true,
+	"in": true, "initially": true, "inner": true, "intersect": true, "into": true, "is": true,
+	"isnull": true, "join": true, "lateral": true, "leading": true, "left": true, "like": true,
+	"limit": true, "localtime": true, "localtimestamp": true, "natural": true, "not": true,
+	"notnull": true, "null": true, "offset": true, "on": true, "only": true, "or": true,
+	"order": true, "outer": true, "overlaps": true, "placing": true, "primary": true,
+	"references": true, "returning": true, "right": true, "select": true, "session_user": true,
+	"similar": true, "some": true, "symmetric": true, "system_user": true, "table": true,
+	"tablesample": true, "then": true, "to": true, "trailing": true, "true": true, "union": true,
+	"unique": true, "user": true, "using": true, "variadic": true, "verbose": true, "when": true,
+	"where": true, "window": true, "with": true,
+}
+
+// plainIdentifierRegex matches names PostgreSQL reads back unchanged without quotes
+var plainIdentifierRegex = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+// quoteIdentifier returns name as it must appear in generated SQL: double-quoted when it is a
+// reserved word or would otherwise be case-folded or misparsed (e.g. "order", "UserAccounts"),
+// and unchanged otherwise so ordinary queries stay readable
+func quoteIdentifier(name string) string {
+	if plainIdentifierRegex.MatchString(name) && !reservedWords[name] {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteIdentifiers applies quoteIdentifier to each name
+func quoteIdentifiers(names []string) []string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quoteIdentifier(name)
+	}
+	return quoted
+}
+
+// uncountableWords have the same singular and plural form
+var uncountableWords = map[string]bool{
+	"data": true, "metadata": true, "equipment": true, "information": true,
+	"news": true, "series": true, "species": true, "sheep": true, "fish": true,
+}
+
+// irregularPlurals maps singular words to plurals that don't follow the suffix rules
+var irregularPlurals = map[string]string{
+	"person": "people", "child": "children", "man": "men", "woman": "women",
+	"mouse": "mice", "goose": "geese", "foot": "feet", "tooth": "teeth",
+	"datum": "data", "criterion": "criteria", "index": "indexes",
+	// A single final z is doubled in these, unlike in waltz or topaz
+	"quiz": "quizzes", "whiz": "whizzes", "fez": "fezzes",
+	// Singular -is and -us words; any other word ending in s is taken as already plural,
+	// so kpis, taxis and menus are left alone
+	"analysis": "analyses", "axis": "axes", "basis": "bases", "crisis": "crises",
+	"diagnosis": "diagnoses", "hypothesis": "hypotheses", "synopsis": "synopses", "thesis": "theses",
+	"alias": "aliases", "bonus": "bonuses", "bus": "buses", "campus": "campuses",
+	"census": "censuses", "status": "statuses", "virus": "viruses",
+}
+
+// pluralize returns the plural form of a PascalCase, camelCase or lower-case identifier.
+// Only the last word is inflected, and words that already look plural are left alone,
+// since table names are usually plural already. overrides maps lower-case singular words
+// to their plural and takes precedence over the built-in rules.
+func pluralize(name string, overrides map[string]string) string {
+	if name == "" {
+		return ""
+	}
+
+	// Split off the last word of the identifier
+	start := 0
+	for i, r := range name {
+		if i > 0 && r >= 'A' && r <= 'Z' {
+			start = i
+		}
+	}
+	prefix, word := name[:start], name[start:]
+	lower := strings.ToLower(word)
+
+	var plural string
+	if override, ok := overrides[lower]; ok {
+		plural = override
+	} else if irregular, ok := irregularPlurals[lower]; ok {
+		plural = irregular
+	} else {
+		plural = pluralizeWord(lower)
+	}
+
+	// Keep the capitalization of the original word
+	if word[0] >= 'A' && word[0] <= 'Z' {
+		plural = strings.ToUpper(plural[:1]) + plural[1:]
+	}
+	return prefix + plural
+}
+
+// pluralizeWord applies the regular English suffix rules to a lower-case word
+func pluralizeWord(word string) string {
+	switch {
+	case uncountableWords[word]:
+		return word
+	case strings.HasSuffix(word, "ss"), strings.HasSuffix(word, "x"), strings.HasSuffix(word, "z"),
+		strings.HasSuffix(word, "ch"), strings.HasSuffix(word, "sh"):
+		return word + "es"
+	case strings.HasSuffix(word, "s"):
+		return word // already plural, e.g. comments or categories
+	case strings.HasSuffix(word, "y") && len(word) > 1 && !strings.ContainsRune("aeiou", rune(word[len(word)-2])):
+		return word[:len(word)-1] + "ies"
+	default:
+		return word + "s"
+	}
+}
+
 // toSnakeCase converts PascalCase or camelCase to snake_case
 func toSnakeCase(s string) string {
 	if s == "" {
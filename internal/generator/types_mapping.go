@@ -2,12 +2,49 @@ package generator
 
 import (
 	"fmt"
+	"slices"
+	"sort"
 	"strings"
 )
 
 // TypeMapper handles mapping PostgreSQL types to Go types
 type TypeMapper struct {
 	customMappings map[string]string
+
+	// customImports maps package names used in custom Go types to their import paths
+	customImports map[string]string
+
+	// numericMode is the configured numeric_mode; NumericModePgtype maps numeric to pgtype.Numeric
+	numericMode string
+
+	// networkMode is the configured network_mode; NetworkModeNetip maps inet and cidr to net/netip types
+	networkMode string
+
+	// geometryMode is the configured geometry_mode; GeometryModeStruct maps point to the generated Point
+	geometryMode string
+
+	// usesPoint records whether a mapped table column uses the generated Point type
+	usesPoint bool
+
+	// columnMappings maps the "table.column" keys of json and jsonb columns to user-defined Go types
+	columnMappings map[string]string
+
+	// encryptedColumns are the "table.column" keys of text columns mapped to EncryptedString
+	encryptedColumns []string
+
+	// usesEncryption records whether a mapped table column uses the generated EncryptedString type
+	usesEncryption bool
+
+	// enums holds the PostgreSQL enum types seen while mapping table columns, keyed by
+	// their (schema-qualified) type name; each maps to a generated string-based Go type
+	enums map[string]Enum
+}
+
+// Enum is a PostgreSQL enum type and the Go type generated for it
+type Enum struct {
+	PgType string   // PostgreSQL type name as introspected, e.g. "billing.currency"
+	GoType string   // Generated Go type name, e.g. "Currency"
+	Values []string // Enum labels in declaration order
 }
 
 // NewTypeMapper creates a new type mapper with optional custom mappings
@@ -17,14 +54,31 @@ func NewTypeMapper(customMappings map[string]string) *TypeMapper {
 	}
 }
 
+// newConfiguredTypeMapper creates a type mapper with the configured custom mappings and type modes
+func newConfiguredTypeMapper(config *Config) *TypeMapper {
+	tm := NewTypeMapper(config.TypeMappings)
+	tm.customImports = config.CustomImports
+	tm.numericMode = config.NumericMode
+	tm.networkMode = config.NetworkMode
+	tm.geometryMode = config.GeometryMode
+	tm.columnMappings = config.ColumnTypeMappings
+	tm.encryptedColumns = config.EncryptedColumns
+	return tm
+}
+
 // MapType converts a PostgreSQL type to the appropriate Go type
 func (tm *TypeMapper) MapType(pgType string, isNullable bool, isArray bool) (string, error) {
 	// Check custom mappings first
-	if customType, exists := tm.customMappings[pgType]; exists {
+	if customType, exists := tm.lookupCustomMapping(pgType); exists {
 		result := tm.applyNullableAndArray(customType, isNullable, isArray)
 		return result, nil
 	}
 
+	// Enum types map to their generated string-based type
+	if enum, exists := tm.enums[pgType]; exists {
+		return tm.applyNullableAndArray(enum.GoType, isNullable, isArray), nil
+	}
+
 	// Get the base Go type
 	baseType, err := tm.getBaseGoType(pgType)
 	if err != nil {
@@ -35,6 +89,39 @@ func (tm *TypeMapper) MapType(pgType string, isNullable bool, isArray bool) (str
 	return result, nil
 }
 
+// lookupCustomMapping finds the custom mapping for a PostgreSQL type name
+// Schema-qualified types ("billing.currency") match a qualified key first and
+// fall back to the bare type name, so unqualified keys behave like a search_path lookup
+func (tm *TypeMapper) lookupCustomMapping(pgType string) (string, bool) {
+	if customType, exists := tm.customMappings[pgType]; exists {
+		return customType, true
+	}
+
+	if dot := strings.LastIndex(pgType, "."); dot >= 0 {
+		if customType, exists := tm.customMappings[pgType[dot+1:]]; exists {
+			return customType, true
+		}
+	}
+
+	return "", false
+}
+
+// MapParameterType converts a PostgreSQL type to the Go type used for query parameters
+// Intervals are accepted as time.Duration, which pgx encodes as a microsecond interval,
+// while interval result columns stay strings since they may carry months and days
+func (tm *TypeMapper) MapParameterType(pgType string) (string, error) {
+	if _, exists := tm.lookupCustomMapping(pgType); !exists && strings.ToLower(pgType) == "interval" {
+		return "time.Duration", nil
+	}
+
+	// Array parameters (e.g. "uuid[]" for = ANY($1)) bind a slice of the element type
+	if elementType, isArray := strings.CutSuffix(pgType, "[]"); isArray {
+		return tm.MapType(elementType, false, true)
+	}
+
+	return tm.MapType(pgType, false, false)
+}
+
 // getBaseGoType returns the base Go type for a PostgreSQL type
 func (tm *TypeMapper) getBaseGoType(pgType string) (string, error) {
 	switch strings.ToLower(pgType) {
@@ -60,7 +147,10 @@ func (tm *TypeMapper) getBaseGoType(pgType string) (string, error) {
 	case "double precision", "float8":
 		return "float64", nil
 	case "numeric", "decimal":
-		return "float64", nil // Could also use shopspring/decimal for precision
+		if tm.numericMode == NumericModePgtype {
+			return "pgtype.Numeric", nil
+		}
+		return "float64", nil
 
 	// Boolean type
 	case "boolean", "bool":
@@ -87,13 +177,26 @@ func (tm *TypeMapper) getBaseGoType(pgType string) (string, error) {
 		return "json.RawMessage", nil
 
 	// Network types
-	case "inet", "cidr":
-		return "string", nil // Could use net.IP for more type safety
+	case "inet":
+		if tm.networkMode == NetworkModeNetip {
+			return "netip.Addr", nil
+		}
+		return "string", nil
+	case "cidr":
+		if tm.networkMode == NetworkModeNetip {
+			return "netip.Prefix", nil
+		}
+		return "string", nil
 	case "macaddr":
 		return "string", nil
 
-	// Geometric types (simplified to strings for now)
-	case "point", "line", "lseg", "box", "path", "polygon", "circle":
+	// Geometric types (simplified to strings, except point in the struct geometry mode)
+	case "point":
+		if tm.geometryMode == GeometryModeStruct {
+			return "Point", nil
+		}
+		return "string", nil
+	case "line", "lseg", "box", "path", "polygon", "circle":
 		return "string", nil
 
 	// Range types (simplified to strings for now)
@@ -108,6 +211,14 @@ func (tm *TypeMapper) getBaseGoType(pgType string) (string, error) {
 	case "xml":
 		return "string", nil
 
+	// Extension types carried as text
+	case "ltree":
+		return "string", nil
+	case "hstore":
+		// pgtype.Hstore is a map[string]*string, since hstore values may be NULL; it implements
+		// sql.Scanner and driver.Valuer, so it needs no codec registration for the extension's OID
+		return "pgtype.Hstore", nil
+
 	// Array types are handled by the isArray parameter
 	default:
 		return "", fmt.Errorf("unsupported PostgreSQL type: %s", pgType)
@@ -159,6 +270,15 @@ func (tm *TypeMapper) makeNullable(goType string) string {
 	case "json.RawMessage":
 		// In pgx v5, there's no pgtype.JSON, use pointer to json.RawMessage
 		return "*json.RawMessage"
+	case "pgtype.Numeric":
+		// pgtype.Numeric already represents NULL through its Valid field
+		return goType
+	case "pgtype.Hstore":
+		// A nil pgtype.Hstore map is NULL
+		return goType
+	case "netip.Addr", "netip.Prefix":
+		// pgx v5 has no pgtype variant for inet and cidr, so NULL is a nil pointer
+		return "*" + goType
 	}
 
 	// Handle array types
@@ -177,9 +297,13 @@ func (tm *TypeMapper) GetRequiredImports(columns []Column) []string {
 	imports := make(map[string]bool)
 
 	for _, col := range columns {
-		goType, err := tm.MapType(col.Type, col.IsNullable, col.IsArray)
-		if err != nil {
-			continue // Skip unsupported types
+		// Columns already mapped may carry a per-column override, so prefer their Go type
+		goType := col.GoType
+		if goType == "" {
+			var err error
+			if goType, err = tm.MapType(col.Type, col.IsNullable, col.IsArray); err != nil {
+				continue // Skip unsupported types
+			}
 		}
 
 		// Check what imports are needed based on the Go type
@@ -218,12 +342,20 @@ func (tm *TypeMapper) addImportsForType(goType string, imports map[string]bool)
 	switch {
 	case strings.Contains(goType, "uuid.UUID"):
 		imports["github.com/google/uuid"] = true
-	case strings.Contains(goType, "time.Time"):
+	case strings.HasPrefix(goType, "time."):
 		imports["time"] = true
 	case strings.Contains(goType, "json.RawMessage"):
 		imports["encoding/json"] = true
 	case strings.Contains(goType, "pgtype."):
 		imports["github.com/jackc/pgx/v5/pgtype"] = true
+	case strings.HasPrefix(goType, "netip."):
+		imports["net/netip"] = true
+	default:
+		if dot := strings.Index(goType, "."); dot > 0 {
+			if path, exists := tm.customImports[goType[:dot]]; exists {
+				imports[path] = true
+			}
+		}
 	}
 }
 
@@ -232,17 +364,133 @@ func (tm *TypeMapper) MapTableColumns(table *Table) error {
 	if table == nil {
 		return fmt.Errorf("table cannot be nil")
 	}
+	if err := tm.checkColumnMappings(*table); err != nil {
+		return err
+	}
 
 	for i := range table.Columns {
-		goType, err := tm.MapType(table.Columns[i].Type, table.Columns[i].IsNullable, table.Columns[i].IsArray)
+		col := &table.Columns[i]
+		tm.registerEnum(*col)
+
+		// Encrypted columns hold ciphertext, so they map to the type that encrypts and decrypts it
+		if slices.Contains(tm.encryptedColumns, table.Name+"."+col.Name) {
+			if err := checkEncryptedColumn(table.Name, *col); err != nil {
+				return err
+			}
+			col.GoType = tm.applyNullableAndArray("EncryptedString", col.IsNullable, false)
+			tm.usesEncryption = true
+			continue
+		}
+
+		// A json or jsonb column may map to a user-defined type by its "table.column" key
+		if customType, exists := tm.jsonColumnMapping(table.Name, *col); exists {
+			col.GoType = tm.applyJSONNullable(customType, col.IsNullable, col.IsArray)
+			continue
+		}
+
+		goType, err := tm.MapType(col.Type, col.IsNullable, col.IsArray)
 		if err != nil {
-			return fmt.Errorf("failed to map type for column %s: %w", table.Columns[i].Name, err)
+			return fmt.Errorf("failed to map type for column %s: %w", col.Name, err)
+		}
+		col.GoType = goType
+		if strings.TrimLeft(goType, "*[]") == "Point" && tm.geometryMode == GeometryModeStruct {
+			tm.usesPoint = true
+		}
+	}
+	return nil
+}
+
+// UsesPoint reports whether any table column mapped so far uses the generated Point type
+func (tm *TypeMapper) UsesPoint() bool {
+	return tm.usesPoint
+}
+
+// UsesEncryption reports whether any table column mapped so far uses the generated EncryptedString type
+func (tm *TypeMapper) UsesEncryption() bool {
+	return tm.usesEncryption
+}
+
+// checkEncryptedColumn verifies an encrypted column can hold the base64 ciphertext EncryptedString writes
+func checkEncryptedColumn(tableName string, col Column) error {
+	switch strings.ToLower(col.Type) {
+	case "text", "varchar", "character varying":
+		if col.IsArray {
+			return fmt.Errorf("encrypted column %s.%s must not be an array", tableName, col.Name)
+		}
+		return nil
+	}
+	return fmt.Errorf("encrypted column %s.%s must be text or varchar to hold the ciphertext, got %s", tableName, col.Name, col.Type)
+}
+
+// jsonColumnMapping finds the column mapping of a json or jsonb column, keyed by "table.column"
+// (e.g. "posts.metadata": "PostMetadata"). pgx encodes and decodes such columns with encoding/json,
+// so the mapped type is any type json.Marshal and json.Unmarshal accept, defined by the user
+func (tm *TypeMapper) jsonColumnMapping(tableName string, col Column) (string, bool) {
+	if col.Type != "json" && col.Type != "jsonb" {
+		return "", false
+	}
+	customType, exists := tm.columnMappings[tableName+"."+col.Name]
+	return customType, exists
+}
+
+// checkColumnMappings verifies every column mapping of the table names one of its json or jsonb columns
+func (tm *TypeMapper) checkColumnMappings(table Table) error {
+	for key := range tm.columnMappings {
+		tableName, name, _ := strings.Cut(key, ".")
+		if tableName != table.Name {
+			continue
+		}
+		col := table.GetColumn(name)
+		if col == nil {
+			return fmt.Errorf("types.column_mappings references unknown column %s in table %s", name, table.Name)
+		}
+		if col.Type != "json" && col.Type != "jsonb" {
+			return fmt.Errorf("types.column_mappings column %s in table %s must be json or jsonb, got %s", name, table.Name, col.Type)
 		}
-		table.Columns[i].GoType = goType
 	}
 	return nil
 }
 
+// applyJSONNullable returns the Go type of a column mapped to a user-defined JSON type. A nullable
+// column holds a pointer to the type so SQL NULL scans as nil rather than a zero value; slices,
+// maps and pointers already represent NULL as nil and are left as they are
+func (tm *TypeMapper) applyJSONNullable(customType string, isNullable bool, isArray bool) string {
+	if isArray || !isNullable {
+		return tm.applyNullableAndArray(customType, isNullable, isArray)
+	}
+	for _, prefix := range []string{"*", "[]", "map["} {
+		if strings.HasPrefix(customType, prefix) {
+			return customType
+		}
+	}
+	return "*" + customType
+}
+
+// registerEnum records the enum type of an enum column so MapType can map it; enums with a
+// custom mapping keep that mapping and get no generated type
+func (tm *TypeMapper) registerEnum(col Column) {
+	if len(col.EnumValues) == 0 {
+		return
+	}
+	if _, exists := tm.lookupCustomMapping(col.Type); exists {
+		return
+	}
+	if tm.enums == nil {
+		tm.enums = make(map[string]Enum)
+	}
+	tm.enums[col.Type] = Enum{PgType: col.Type, GoType: toPascalCase(col.EnumName), Values: col.EnumValues}
+}
+
+// Enums returns the enum types registered while mapping table columns, ordered by Go type name
+func (tm *TypeMapper) Enums() []Enum {
+	enums := make([]Enum, 0, len(tm.enums))
+	for _, enum := range tm.enums {
+		enums = append(enums, enum)
+	}
+	sort.Slice(enums, func(i, j int) bool { return enums[i].GoType < enums[j].GoType })
+	return enums
+}
+
 // MapQueryColumns maps all columns in a query and sets their GoType field
 func (tm *TypeMapper) MapQueryColumns(query *Query) error {
 	if query == nil {
@@ -259,7 +507,7 @@ func (tm *TypeMapper) MapQueryColumns(query *Query) error {
 
 	// Also map parameter types
 	for i := range query.Parameters {
-		goType, err := tm.MapType(query.Parameters[i].Type, false, false) // Parameters are typically not nullable
+		goType, err := tm.MapParameterType(query.Parameters[i].Type) // Parameters are typically not nullable
 		if err != nil {
 			return fmt.Errorf("failed to map type for parameter %d in query %s: %w", query.Parameters[i].Index, query.Name, err)
 		}
@@ -49,6 +49,8 @@ func TestTypeMapper_MapType(t *testing.T) {
 	testTypeMapping(t, tm, "boolean", "bool", "pgtype.Bool")
 	testTypeMapping(t, tm, "timestamptz", "time.Time", "pgtype.Timestamptz")
 	testTypeMapping(t, tm, "jsonb", "json.RawMessage", "*json.RawMessage")
+	testTypeMapping(t, tm, "ltree", "string", "pgtype.Text")
+	testTypeMapping(t, tm, "hstore", "pgtype.Hstore", "pgtype.Hstore")
 
 	// Test type aliases
 	aliasTests := []struct {
@@ -124,6 +126,22 @@ func TestTypeMapper_MapType_NullableArrays(t *testing.T) {
 			expectedType: "[]pgtype.UUID",
 			expectError:  false,
 		},
+		{
+			name:         "nullable_bool_array",
+			pgType:       "bool",
+			isNullable:   true,
+			isArray:      true,
+			expectedType: "[]pgtype.Bool",
+			expectError:  false,
+		},
+		{
+			name:         "non_nullable_bool_array",
+			pgType:       "bool",
+			isNullable:   false,
+			isArray:      true,
+			expectedType: "[]bool",
+			expectError:  false,
+		},
 		{
 			name:         "non_nullable_text_array",
 			pgType:       "text",
@@ -197,6 +215,287 @@ func TestTypeMapper_MapType_WithCustomMappings(t *testing.T) {
 	}
 }
 
+func TestTypeMapper_MapType_NumericMode(t *testing.T) {
+	// float64 stays the default so existing code keeps compiling
+	testTypeMapping(t, NewTypeMapper(nil), "numeric", "float64", "pgtype.Float8")
+
+	tm := NewTypeMapper(nil)
+	tm.numericMode = NumericModePgtype
+	testTypeMapping(t, tm, "numeric", "pgtype.Numeric", "pgtype.Numeric")
+	testTypeMapping(t, tm, "decimal", "pgtype.Numeric", "pgtype.Numeric")
+
+	imports := tm.GetRequiredImports([]Column{{Name: "balance", Type: "numeric", IsNullable: true}})
+	if !reflect.DeepEqual(imports, []string{"github.com/jackc/pgx/v5/pgtype"}) {
+		t.Errorf("GetRequiredImports() = %v, want the pgtype import", imports)
+	}
+
+	// A custom mapping still takes precedence over the numeric mode
+	tm = NewTypeMapper(map[string]string{"numeric": "decimal.Decimal"})
+	tm.numericMode = NumericModePgtype
+	testTypeMapping(t, tm, "numeric", "decimal.Decimal", "*decimal.Decimal")
+}
+
+func TestTypeMapper_MapType_NetworkMode(t *testing.T) {
+	// string stays the default so existing code keeps compiling
+	testTypeMapping(t, NewTypeMapper(nil), "inet", "string", "pgtype.Text")
+	testTypeMapping(t, NewTypeMapper(nil), "cidr", "string", "pgtype.Text")
+
+	tm := NewTypeMapper(nil)
+	tm.networkMode = NetworkModeNetip
+	testTypeMapping(t, tm, "inet", "netip.Addr", "*netip.Addr")
+	testTypeMapping(t, tm, "cidr", "netip.Prefix", "*netip.Prefix")
+
+	imports := tm.GetRequiredImports([]Column{
+		{Name: "allowed_ips", Type: "inet", IsArray: true},
+		{Name: "subnet", Type: "cidr", IsNullable: true},
+	})
+	if !reflect.DeepEqual(imports, []string{"net/netip"}) {
+		t.Errorf("GetRequiredImports() = %v, want only the net/netip import", imports)
+	}
+}
+
+func TestTypeMapper_MapType_GeometryMode(t *testing.T) {
+	tm := NewTypeMapper(nil)
+	testTypeMapping(t, tm, "point", "string", "pgtype.Text")
+	if err := tm.MapTableColumns(&Table{Name: "places", Columns: []Column{{Name: "location", Type: "point"}}}); err != nil {
+		t.Fatalf("MapTableColumns() error = %v", err)
+	}
+	if tm.UsesPoint() {
+		t.Error("UsesPoint() = true, want false in the default geometry mode")
+	}
+
+	tm = NewTypeMapper(nil)
+	tm.geometryMode = GeometryModeStruct
+	testTypeMapping(t, tm, "point", "Point", "*Point")
+	// Only point gets a structured type
+	testTypeMapping(t, tm, "polygon", "string", "pgtype.Text")
+
+	table := &Table{Name: "places", Columns: []Column{
+		{Name: "location", Type: "point"},
+		{Name: "entrance", Type: "point", IsNullable: true},
+		{Name: "route", Type: "point", IsArray: true},
+	}}
+	if err := tm.MapTableColumns(table); err != nil {
+		t.Fatalf("MapTableColumns() error = %v", err)
+	}
+	want := []string{"Point", "*Point", "[]Point"}
+	for i, col := range table.Columns {
+		if col.GoType != want[i] {
+			t.Errorf("column %s GoType = %q, want %q", col.Name, col.GoType, want[i])
+		}
+	}
+	if !tm.UsesPoint() {
+		t.Error("UsesPoint() = false, want true after mapping a point column")
+	}
+	// Point is declared in the generated package, so it needs no import
+	if imports := tm.GetRequiredImports(table.Columns); len(imports) != 0 {
+		t.Errorf("GetRequiredImports() = %v, want no imports", imports)
+	}
+}
+
+func TestTypeMapper_MapTableColumns_Enums(t *testing.T) {
+	values := []string{"usd", "eur", "gbp"}
+	enumColumn := func(name string, isNullable, isArray bool) Column {
+		return Column{Name: name, Type: "billing.currency", IsNullable: isNullable, IsArray: isArray, EnumName: "currency", EnumValues: values}
+	}
+	table := &Table{Name: "invoices", Columns: []Column{
+		enumColumn("currency", false, false),
+		enumColumn("fallback_currency", true, false),
+		enumColumn("accepted_currencies", false, true),
+		enumColumn("blocked_currencies", true, true),
+	}}
+
+	tm := NewTypeMapper(nil)
+	if err := tm.MapTableColumns(table); err != nil {
+		t.Fatalf("MapTableColumns() error = %v", err)
+	}
+	want := []string{"Currency", "*Currency", "[]Currency", "[]*Currency"}
+	for i, col := range table.Columns {
+		if col.GoType != want[i] {
+			t.Errorf("column %s GoType = %q, want %q", col.Name, col.GoType, want[i])
+		}
+	}
+
+	enums := tm.Enums()
+	if !reflect.DeepEqual(enums, []Enum{{PgType: "billing.currency", GoType: "Currency", Values: values}}) {
+		t.Errorf("Enums() = %+v, want the billing.currency enum", enums)
+	}
+
+	// The generated type is string-based and local to the package, so it needs no imports
+	if imports := tm.GetRequiredImports(table.Columns); len(imports) != 0 {
+		t.Errorf("GetRequiredImports() = %v, want no imports", imports)
+	}
+
+	// A custom mapping takes precedence over the generated type
+	tm = NewTypeMapper(map[string]string{"billing.currency": "string"})
+	table.Columns = []Column{enumColumn("currency", true, false)}
+	if err := tm.MapTableColumns(table); err != nil {
+		t.Fatalf("MapTableColumns() error = %v", err)
+	}
+	if got := table.Columns[0].GoType; got != "pgtype.Text" {
+		t.Errorf("custom-mapped enum GoType = %q, want %q", got, "pgtype.Text")
+	}
+	if enums := tm.Enums(); len(enums) != 0 {
+		t.Errorf("Enums() = %+v, want none for a custom-mapped enum", enums)
+	}
+}
+
+func TestTypeMapper_MapTableColumns_JSONColumnMappings(t *testing.T) {
+	tm := NewTypeMapper(nil)
+	tm.columnMappings = map[string]string{
+		"posts.metadata": "PostMetadata",
+		"posts.settings": "PostSettings",
+		"posts.tags":     "[]PostTag",
+		"posts.labels":   "map[string]string",
+		"users.profile":  "UserProfile",
+	}
+	table := &Table{Name: "posts", Columns: []Column{
+		{Name: "metadata", Type: "jsonb"},
+		{Name: "settings", Type: "json", IsNullable: true},
+		{Name: "extra", Type: "jsonb", IsNullable: true},
+		{Name: "title", Type: "text"},
+		{Name: "tags", Type: "jsonb", IsNullable: true},
+		{Name: "labels", Type: "jsonb", IsNullable: true},
+	}}
+
+	if err := tm.MapTableColumns(table); err != nil {
+		t.Fatalf("MapTableColumns() error = %v", err)
+	}
+	// Only the mapped columns of this table change. Nullable structs become pointers, while
+	// nullable slices and maps stay as they are since nil already means NULL
+	want := []string{"PostMetadata", "*PostSettings", "*json.RawMessage", "string", "[]PostTag", "map[string]string"}
+	for i, col := range table.Columns {
+		if col.GoType != want[i] {
+			t.Errorf("column %s GoType = %q, want %q", col.Name, col.GoType, want[i])
+		}
+	}
+
+	imports := tm.GetRequiredImports(table.Columns[:2])
+	if len(imports) != 0 {
+		t.Errorf("GetRequiredImports() = %v, want no imports for user-defined JSON types", imports)
+	}
+}
+
+func TestTypeMapper_MapTableColumns_InvalidColumnMappings(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		wantErr string
+	}{
+		{"unknown column", "posts.body", "unknown column body in table posts"},
+		{"non-json column", "posts.title", "column title in table posts must be json or jsonb, got text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm := NewTypeMapper(nil)
+			tm.columnMappings = map[string]string{tt.key: "PostField"}
+			table := &Table{Name: "posts", Columns: []Column{
+				{Name: "title", Type: "text"},
+				{Name: "metadata", Type: "jsonb"},
+			}}
+			err := tm.MapTableColumns(table)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("MapTableColumns() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTypeMapper_MapTableColumns_EncryptedColumns(t *testing.T) {
+	tm := NewTypeMapper(nil)
+	tm.encryptedColumns = []string{"users.ssn", "users.note", "posts.ssn"}
+	table := &Table{Name: "users", Columns: []Column{
+		{Name: "ssn", Type: "text"},
+		{Name: "note", Type: "character varying", IsNullable: true},
+		{Name: "name", Type: "text"},
+	}}
+
+	if tm.UsesEncryption() {
+		t.Fatal("UsesEncryption() = true before mapping any encrypted column")
+	}
+	if err := tm.MapTableColumns(table); err != nil {
+		t.Fatalf("MapTableColumns() error = %v", err)
+	}
+	// Only the listed columns of this table are encrypted
+	want := []string{"EncryptedString", "*EncryptedString", "string"}
+	for i, col := range table.Columns {
+		if col.GoType != want[i] {
+			t.Errorf("column %s GoType = %q, want %q", col.Name, col.GoType, want[i])
+		}
+	}
+	if !tm.UsesEncryption() {
+		t.Error("UsesEncryption() = false after mapping an encrypted column")
+	}
+
+	// The base64 ciphertext needs a text column
+	table = &Table{Name: "posts", Columns: []Column{{Name: "ssn", Type: "integer"}}}
+	if err := tm.MapTableColumns(table); err == nil || !strings.Contains(err.Error(), "must be text or varchar") {
+		t.Errorf("Expected column type error, got %v", err)
+	}
+}
+
+func TestTypeMapper_MapType_SchemaQualifiedMappings(t *testing.T) {
+	tm := NewTypeMapper(map[string]string{
+		"billing.currency": "billing.Currency",
+		"mood":             "Mood",
+	})
+
+	tests := []struct {
+		name       string
+		pgType     string
+		isNullable bool
+		isArray    bool
+		want       string
+	}{
+		{"qualified key matches qualified type", "billing.currency", false, false, "billing.Currency"},
+		{"qualified key with nullable array", "billing.currency", true, true, "[]*billing.Currency"},
+		{"bare key matches type in any schema", "audit.mood", false, false, "Mood"},
+		{"bare key matches bare type", "mood", false, false, "Mood"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tm.MapType(tt.pgType, tt.isNullable, tt.isArray)
+			if err != nil {
+				t.Fatalf("MapType() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("MapType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// A qualified key only applies to its own schema
+	if _, err := tm.MapType("reporting.currency", false, false); err == nil {
+		t.Error("Expected error for currency type in a schema without a mapping")
+	}
+}
+
+func TestTypeMapper_MapParameterType(t *testing.T) {
+	tm := NewTypeMapper(nil)
+
+	if got, err := tm.MapParameterType("interval"); err != nil || got != "time.Duration" {
+		t.Errorf("MapParameterType(interval) = %q, %v; want time.Duration", got, err)
+	}
+	if got, err := tm.MapType("interval", false, false); err != nil || got != "string" {
+		t.Errorf("MapType(interval) = %q, %v; want string for result columns", got, err)
+	}
+	if got, err := tm.MapParameterType("uuid"); err != nil || got != "uuid.UUID" {
+		t.Errorf("MapParameterType(uuid) = %q, %v; want uuid.UUID", got, err)
+	}
+
+	// Array parameters bind a slice of the element type
+	if got, err := tm.MapParameterType("uuid[]"); err != nil || got != "[]uuid.UUID" {
+		t.Errorf("MapParameterType(uuid[]) = %q, %v; want []uuid.UUID", got, err)
+	}
+
+	// Custom mappings take precedence over the duration default
+	custom := NewTypeMapper(map[string]string{"interval": "pgtype.Interval"})
+	if got, err := custom.MapParameterType("interval"); err != nil || got != "pgtype.Interval" {
+		t.Errorf("MapParameterType(interval) with custom mapping = %q, %v; want pgtype.Interval", got, err)
+	}
+}
+
 // TestTypeMapper_MapType_CustomMappingsEdgeCases - test custom type mapping edge cases
 func TestTypeMapper_MapType_CustomMappingsEdgeCases(t *testing.T) {
 	customMappings := map[string]string{
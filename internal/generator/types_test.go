@@ -49,6 +49,9 @@ func TestTable_GetPrimaryKeyColumn(t *testing.T) {
 	if table.GetPrimaryKeyColumn() != nil {
 		t.Errorf("GetPrimaryKeyColumn() should return nil for composite primary key")
 	}
+	if !table.HasCompositePrimaryKey() {
+		t.Errorf("HasCompositePrimaryKey() should be true for composite primary key")
+	}
 
 	// Test no primary key
 	table.PrimaryKey = []string{}
@@ -128,6 +131,11 @@ func TestTable_GoStructName_SpecialCharacters(t *testing.T) {
 			input:    "userId",
 			expected: "UserId",
 		},
+		{
+			name:     "dashes_and_spaces",
+			input:    "line-items list",
+			expected: "LineItemsList",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -361,6 +369,29 @@ func TestToPascalCase(t *testing.T) {
 	}
 }
 
+func TestQuoteIdentifier(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"users", "users"},
+		{"created_at", "created_at"},
+		{"order", `"order"`},
+		{"user", `"user"`},
+		{"group", `"group"`},
+		{"UserAccounts", `"UserAccounts"`},
+		{"line-items", `"line-items"`},
+		{"2fa_codes", `"2fa_codes"`},
+		{`odd"name`, `"odd""name"`},
+	}
+
+	for _, tt := range tests {
+		if got := quoteIdentifier(tt.input); got != tt.want {
+			t.Errorf("quoteIdentifier(%s) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
 // TestToSnakeCase - keep essential string conversion tests
 func TestToSnakeCase(t *testing.T) {
 	tests := []struct {
@@ -381,6 +412,60 @@ func TestToSnakeCase(t *testing.T) {
 	}
 }
 
+func TestPluralize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		// Regular and already-plural words
+		{"Comment", "Comments"},
+		{"Comments", "Comments"},
+		{"Email", "Emails"},
+		{"", ""},
+		// -y words
+		{"Category", "Categories"},
+		{"Categories", "Categories"},
+		{"Key", "Keys"},
+		// -s, -x, -ch words
+		{"Address", "Addresses"},
+		{"Status", "Statuses"},
+		{"Box", "Boxes"},
+		{"Batch", "Batches"},
+		{"Analysis", "Analyses"},
+		// Words ending in -is or -us other than the listed singulars are already plural
+		{"Kpis", "Kpis"},
+		{"Apis", "Apis"},
+		{"Taxis", "Taxis"},
+		{"Emojis", "Emojis"},
+		{"Menus", "Menus"},
+		{"Statuses", "Statuses"},
+		// Irregular and uncountable words
+		{"Person", "People"},
+		{"Child", "Children"},
+		{"Metadata", "Metadata"},
+		{"Quiz", "Quizzes"},
+		{"Quizzes", "Quizzes"},
+		{"Waltz", "Waltzes"},
+		// Only the last word of an identifier is inflected
+		{"PostCategory", "PostCategories"},
+		{"ExternalId", "ExternalIds"},
+		{"userAddress", "userAddresses"},
+		{"id", "ids"},
+	}
+
+	for _, tt := range tests {
+		if got := pluralize(tt.input, nil); got != tt.want {
+			t.Errorf("pluralize(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	// Configured plurals take precedence over the built-in rules
+	overrides := map[string]string{"cactus": "cacti"}
+	if got := pluralize("GardenCactus", overrides); got != "GardenCacti" {
+		t.Errorf("pluralize with override = %q, want %q", got, "GardenCacti")
+	}
+}
+
 // TestQueryType_Constants - keep essential constant tests
 func TestQueryType_Constants(t *testing.T) {
 	tests := []struct {
@@ -391,6 +476,8 @@ func TestQueryType_Constants(t *testing.T) {
 		{"QueryTypeOne", QueryTypeOne, "one"},
 		{"QueryTypeMany", QueryTypeMany, "many"},
 		{"QueryTypeExec", QueryTypeExec, "exec"},
+		{"QueryTypeExecRows", QueryTypeExecRows, "execrows"},
+		{"QueryTypeBatch", QueryTypeBatch, "batch"},
 		{"QueryTypePaginated", QueryTypePaginated, "paginated"},
 	}
 
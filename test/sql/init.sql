@@ -154,6 +154,8 @@ CREATE TABLE data_types_test (
     text_array_field TEXT[],
     integer_array_field INTEGER[],
     uuid_array_field UUID[],
+    boolean_array_field BOOLEAN[],
+    required_boolean_array_field BOOLEAN[] NOT NULL DEFAULT '{}',
     
     -- Network types
     inet_field INET,
@@ -182,6 +184,12 @@ CREATE TABLE invalid_pk_table (
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
+-- Test table with an identity primary key, which has no column default
+CREATE TABLE identity_pk_table (
+    id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    name VARCHAR(255) NOT NULL
+);
+
 -- Test table with composite primary key (should be rejected by generator)
 CREATE TABLE composite_pk_table (
     tenant_id UUID NOT NULL,
@@ -191,6 +199,37 @@ CREATE TABLE composite_pk_table (
     PRIMARY KEY (tenant_id, user_id)
 );
 
+-- Partitioned table - Only the parent should be generated, never its partitions
+CREATE TABLE events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v7(),
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+) PARTITION BY HASH (id);
+
+CREATE TABLE events_p0 PARTITION OF events FOR VALUES WITH (MODULUS 2, REMAINDER 0);
+CREATE TABLE events_p1 PARTITION OF events FOR VALUES WITH (MODULUS 2, REMAINDER 1);
+
+-- Custom type in a non-public schema - Mapped through schema-qualified type_mappings
+CREATE SCHEMA billing;
+CREATE TYPE billing.currency AS ENUM ('usd', 'eur', 'gbp');
+
+CREATE TABLE invoices (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v7(),
+    amount DECIMAL(10,2) NOT NULL,
+    currency billing.currency NOT NULL,
+    accepted_currencies billing.currency[],
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Extension type - hstore columns map to pgtype.Hstore
+CREATE EXTENSION IF NOT EXISTS hstore;
+
+CREATE TABLE feature_flags (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v7(),
+    flags hstore NOT NULL,
+    overrides hstore
+);
+
 -- Indexes for performance testing
 CREATE INDEX idx_users_email ON users(email);
 CREATE INDEX idx_users_active_created ON users(is_active, created_at);